@@ -8,6 +8,7 @@ import (
 	"os/signal"
 	"strings"
 	"syscall"
+	"time"
 
 	"log/slog"
 
@@ -17,6 +18,7 @@ import (
 
 	"github.com/wasilak/go-hello-world/utils"
 	"github.com/wasilak/go-hello-world/web"
+	"github.com/wasilak/go-hello-world/web/admin"
 	"github.com/wasilak/go-hello-world/web/common"
 	"github.com/wasilak/loggergo"
 	"github.com/wasilak/profilego"
@@ -40,18 +42,117 @@ func main() {
 
 	listenAddr := flag.String("listen-addr", "127.0.0.1:3000", "server listen address")
 	logLevel := flag.String("log-level", slog.LevelInfo.String(), fmt.Sprintf("log level %s", loggergo.Types.AllLogLevels()))
+	logLevelOverrides := flag.String("log-levels", "", "Comma separated logger=level overrides for components (server, router, handlers, errors, access) and routes (route:/path)")
 	logFormat := flag.String("log-format", loggergo.Types.LogFormatText.String(), fmt.Sprintf("log format %s", loggergo.Types.AllLogFormats()))
 	otelEnabled := flag.Bool("otel-enabled", false, "OpenTelemetry traces enabled")
 	otelHostMetricsEnabled := flag.Bool("otel-host-metrics", false, "OpenTelemetry host metrics enabled")
 	otelRuntimeMetricsEnabled := flag.Bool("otel-runtime-metrics", false, "OpenTelemetry runtime metrics enabled")
+	otelPropagators := flag.String("otel-propagators", "tracecontext,baggage", fmt.Sprintf("Comma separated trace context propagation formats %s", common.AllPropagators()))
+	otelFilteredPaths := flag.String("otel-filtered-paths", "/health", "Comma separated path prefixes excluded from tracing")
+	otelCaptureRequestHeaders := flag.String("otel-capture-request-headers", "", "Comma separated request headers recorded as span attributes")
+	otelCaptureResponseHeaders := flag.String("otel-capture-response-headers", "", "Comma separated response headers recorded as span attributes")
+	otelMetricsExporters := flag.String("otel-metrics-exporters", "", fmt.Sprintf("OpenTelemetry HTTP server metrics exporters, comma separated (%s, %s)", common.MetricsExporterOTLP, common.MetricsExporterPrometheus))
+	chainHops := flag.String("chain-hops", "", "Comma separated downstream URLs called by /chain when the request has no hop parameters")
+	chainMode := flag.String("chain-mode", common.ChainModeSequential, fmt.Sprintf("Default /chain mode (%s, %s)", common.ChainModeSequential, common.ChainModeParallel))
+	chainTimeout := flag.Duration("chain-timeout", 5*time.Second, "Timeout of each downstream /chain call")
+	chainAllowedHops := flag.String("chain-allowed-hops", "", "Comma separated hosts (host or host:port) or URL prefixes the /chain hop parameters may call, besides -chain-hops")
+	chainMaxDepth := flag.Int("chain-max-depth", common.DefaultChainMaxDepth, fmt.Sprintf("Highest %s accepted by /chain, deeper requests are rejected to stop cycles", common.ChainDepthHeader))
+	proxyUpstream := flag.String("proxy-upstream", "", "Comma separated prefix=url reverse proxy routes, e.g. /api=http://backend:8080")
+	proxyStripPrefix := flag.Bool("proxy-strip-prefix", true, "Strip the route prefix from the path forwarded upstream")
+	proxySetHeaders := flag.String("proxy-set-headers", "", "Comma separated Name=value headers injected into proxied requests")
+	proxyStripHeaders := flag.String("proxy-strip-headers", "", "Comma separated headers removed from proxied requests")
+	proxyTimeout := flag.Duration("proxy-timeout", 30*time.Second, "Upstream dial and response header timeout")
+	proxyRetries := flag.Int("proxy-retries", 0, "Retries of idempotent proxied requests on connection errors and 502/503/504")
+	problemTypePrefix := flag.String("problem-type-prefix", common.DefaultProblemTypeURIPrefix, "Prefix of the problem type URI in problem+json error responses")
+	problemContextAllowlist := flag.String("problem-context-allowlist", strings.Join(utils.PublicContextKeys(), ","), "Comma separated error context fields exposed in problem+json responses, others are redacted")
+	errorStacks := flag.Bool("error-stacks", false, "Capture the call stack when application errors are created")
+	errorLabelLimit := flag.Int("error-metrics-max-label-values", utils.DefaultMaxErrorLabelValues, "Distinct error codes and routes kept as app_errors_total labels before folding into \"other\"")
+	recentErrorsSize := flag.Int("recent-errors-size", utils.DefaultRecentErrorsSize, "Number of recent errors kept for /admin/errors")
+	adminAddr := flag.String("admin-addr", "", "Admin API listen address, disabled when empty")
+	adminToken := flag.String("admin-token", "", "Admin API bearer token, $ADMIN_TOKEN when empty")
+	adminTLSCert := flag.String("admin-tls-cert", "", "Admin API TLS certificate file")
+	adminTLSKey := flag.String("admin-tls-key", "", "Admin API TLS key file")
+	adminClientCA := flag.String("admin-client-ca", "", "CA file verifying admin API client certificates (mTLS)")
+	accessLogFields := flag.String("access-log-fields", strings.Join(common.DefaultAccessLogFields(), ","), fmt.Sprintf("Comma separated access log fields %s", common.AccessLogFields()))
+	accessLogHeaders := flag.String("access-log-headers", "", "Comma separated request headers logged with the headers access log field")
+	accessLogExclude := flag.String("access-log-exclude", "/health,/ready,/metrics", "Comma separated paths, and the paths below them, not access logged")
+	accessLogSampleRate := flag.Float64("access-log-sample-rate", 1, "Fraction of requests access logged, server errors are always logged")
+	rateLimitKey := flag.String("rate-limit-key", common.RateLimitKeyIP, "What per-client rate limits apply to: ip, route or header:<name>")
+	rateLimitGlobal := flag.String("rate-limit-global", "", "Global rate limit shared by all requests as rate:burst, requests per second, disabled when empty")
+	rateLimitDefault := flag.String("rate-limit", "", "Per-client rate limit as rate:burst, requests per second, disabled when empty")
+	rateLimitRoutes := flag.String("rate-limit-routes", "", "Comma separated per-client path=rate:burst limits of path prefixes, overriding -rate-limit")
+	rateLimitExclude := flag.String("rate-limit-exclude", "/health,/ready,/metrics", "Comma separated paths, and the paths below them, never rate limited")
+	concurrencyLimit := flag.Int("concurrency-limit", 0, "Maximum requests served concurrently, 0 for no limit")
+	concurrencyQueueSize := flag.Int("concurrency-queue-size", 0, "Requests waiting for a slot when the concurrency limit is reached, others are shed with 503")
+	concurrencyQueueTimeout := flag.Duration("concurrency-queue-timeout", time.Second, "How long a queued request waits for a slot before it is shed")
+	concurrencyAdaptive := flag.Bool("concurrency-adaptive", false, "Adapt the concurrency limit to the latency with AIMD, between -concurrency-min-limit and -concurrency-limit")
+	concurrencyMinLimit := flag.Int("concurrency-min-limit", 1, "Lowest adaptive concurrency limit")
+	concurrencyTargetLatency := flag.Duration("concurrency-target-latency", 100*time.Millisecond, "Latency above which the adaptive concurrency limit decreases")
+	concurrencyRetryAfter := flag.Duration("concurrency-retry-after", time.Second, "Retry-After returned with shed requests")
+	concurrencyExclude := flag.String("concurrency-exclude", "/health,/ready,/metrics", "Comma separated paths, and the paths below them, never concurrency limited")
+	serverReadTimeout := flag.Duration("server-read-timeout", 30*time.Second, "Maximum duration for reading a whole request, including the body, 0 for no timeout")
+	serverReadHeaderTimeout := flag.Duration("server-read-header-timeout", 10*time.Second, "Maximum duration for reading the request headers, 0 for no timeout (not supported by fiber)")
+	serverWriteTimeout := flag.Duration("server-write-timeout", 2*time.Minute, "Maximum duration for writing the response, 0 for no timeout")
+	serverIdleTimeout := flag.Duration("server-idle-timeout", 2*time.Minute, "Maximum duration a keep-alive connection waits for the next request, 0 for no timeout")
+	serverMaxHeaderBytes := flag.Int("server-max-header-bytes", 32<<10, "Maximum size of the request headers, 0 for the framework default")
+	serverMaxBodyBytes := flag.Int64("server-max-body-bytes", 4<<20, "Maximum size of the request body, larger bodies get 413, 0 for no limit")
+	serverKeepAlive := flag.Bool("server-keep-alive", true, "Keep connections open between requests")
+	accessLogRateLimit := flag.Int("access-log-rate-limit", 0, "Maximum access log entries per second, 0 for no limit")
+	accessLogBodyLimit := flag.Int("access-log-body-limit", 4096, "Body bytes captured by the request_body and response_body access log fields")
+	redactHeaders := flag.String("redact-headers", "authorization,proxy-authorization,cookie,set-cookie,x-api-key", "Comma separated headers redacted in responses, logs, error context and trace attributes")
+	redactHeaderPatterns := flag.String("redact-header-patterns", "(token|secret|password|api-?key)", "Comma separated regular expressions matching lowercase header names to redact")
+	redactQueryParams := flag.String("redact-query-params", "token,access_token,api_key,password,secret,signature", "Comma separated query parameters redacted in responses, logs and error context")
+	redactFields := flag.String("redact-fields", "password,token,secret,authorization,api_key", "Comma separated JSON body and error context fields redacted in logs and responses")
+	redactionMode := flag.String("redaction-mode", string(utils.RedactionMask), fmt.Sprintf("Redaction mode %s, hash keeps values correlatable", utils.RedactionModes()))
+	redactionHashKey := flag.String("redaction-hash-key", "", "HMAC key of the hash redaction mode, $REDACTION_HASH_KEY when empty")
+	requestIDHeader := flag.String("request-id-header", common.DefaultRequestIDHeader, "Header request IDs are accepted from, returned in and propagated with")
+	requestIDFormat := flag.String("request-id-format", common.RequestIDFormatUUIDv7, fmt.Sprintf("Format of generated request IDs %s", common.RequestIDFormats()))
+	instanceInfo := flag.Bool("instance-info", false, "Include the pod, node, labels, annotations, image and cgroup limits of the instance in the main response")
+	podInfoPath := flag.String("podinfo-path", common.DefaultPodInfoPath, "Directory of the downward API volume with the labels and annotations files")
+	cgroupRoot := flag.String("cgroup-root", common.DefaultCgroupRoot, "cgroup filesystem the CPU and memory limits are read from")
+	containerImage := flag.String("container-image", os.Getenv("CONTAINER_IMAGE"), "Container image reported in the instance info (default $CONTAINER_IMAGE)")
+	middlewareOrder := flag.String("middleware-order", strings.Join(common.DefaultMiddlewareOrder(), ","), "Comma separated middleware from the outermost, unlisted middleware follow in the default order")
+	middlewareDisabled := flag.String("middleware-disabled", "", fmt.Sprintf("Comma separated middleware left out of the chain %s", common.DefaultMiddlewareOrder()))
+	compressionEncodings := flag.String("compression-encodings", strings.Join(common.CompressionEncodings(), ","), fmt.Sprintf("Comma separated response encodings in order of preference %s, empty disables compression", common.CompressionEncodings()))
+	compressionLevels := flag.String("compression-levels", "gzip=5,deflate=5,br=4,zstd=3", "Comma separated compression levels as encoding=level")
+	compressionMinSize := flag.Int("compression-min-size", 1024, "Smallest response body compressed, in bytes")
+	compressionContentTypes := flag.String("compression-content-types", strings.Join(common.DefaultCompressionContentTypes(), ","), "Comma separated media types compressed, type/* matches all subtypes")
+	corsAllowedOrigins := flag.String("cors-allowed-origins", "", "Comma separated origins allowed by the CORS policy, * for any, wildcards like https://*.example.com or ~regexp, empty disables CORS")
+	corsAllowedMethods := flag.String("cors-allowed-methods", strings.Join(common.DefaultCORSMethods(), ","), "Comma separated methods allowed in cross-origin requests, * for any")
+	corsAllowedHeaders := flag.String("cors-allowed-headers", strings.Join(common.DefaultCORSHeaders(), ","), "Comma separated request headers allowed in cross-origin requests, * for any")
+	corsExposedHeaders := flag.String("cors-exposed-headers", strings.Join(common.DefaultCORSExposedHeaders(), ","), "Comma separated response headers exposed to cross-origin scripts")
+	corsAllowCredentials := flag.Bool("cors-allow-credentials", false, "Allow cookies and authorization in cross-origin requests")
+	corsMaxAge := flag.Duration("cors-max-age", 10*time.Minute, "How long browsers cache preflight responses, 0 leaves it to the browser")
+	securityHeadersPreset := flag.String("security-headers", common.SecurityHeadersOff, fmt.Sprintf("Security headers preset %s", common.SecurityHeadersPresets()))
+	securityHeadersSet := flag.String("security-headers-set", "", "Comma separated Name=value headers overriding the preset, an empty value removes the header")
 	statsvizEnabled := flag.Bool("statsviz-enabled", false, "statsviz enabled")
 	profilingEnabled := flag.Bool("profiling-enabled", false, "Profiling enabled")
 	profilingAddress := flag.String("profiling-address", "http://localhost:4040", "Profiling address")
 	webFramework := flag.String("web-framework", "gorilla", "Web framework (gorilla, echo, gin, chi, fiber)")
+	serverStartRetries := flag.Int("server-start-retries", 3, "Retries of a failed web server start before rolling back to the previous framework")
+	serverStartBackoff := flag.Duration("server-start-backoff", 500*time.Millisecond, "Delay before the first web server start retry, doubled after every attempt")
+	serverStartMaxBackoff := flag.Duration("server-start-max-backoff", 10*time.Second, "Maximum delay between web server start retries")
 	devFlavor := flag.String("dev-flavor", loggergo.Types.DevFlavorTint.String(), fmt.Sprintf("Dev flavor %s", loggergo.Types.AllDevFlavors()))
 	outPutType := flag.String("output-type", loggergo.Types.OutputConsole.String(), fmt.Sprintf("Output type %s", loggergo.Types.AllOutputTypes()))
+	version := flag.Bool("version", false, "Print the version, build information and dependencies, then exit")
 	flag.Parse()
 
+	// secrets are read after parsing so they never appear as flag defaults in the usage output
+	if *adminToken == "" {
+		*adminToken = os.Getenv("ADMIN_TOKEN")
+	}
+	if *redactionHashKey == "" {
+		*redactionHashKey = os.Getenv("REDACTION_HASH_KEY")
+	}
+
+	if *version {
+		utils.GetBuildInfo().WriteVersion(os.Stdout, utils.GetAppName())
+		os.Exit(0)
+	}
+
+	utils.SetStackCapture(*errorStacks)
+	utils.ConfigureErrorTracking(*errorLabelLimit, *recentErrorsSize)
+
 	if *profilingEnabled {
 		profileGoConfig := config.Config{
 			ApplicationName: utils.GetAppName(),
@@ -106,47 +207,296 @@ func main() {
 		}()
 	}
 
+	propagator, err := common.NewPropagator(utils.SplitList(*otelPropagators))
+	if err != nil {
+		slog.ErrorContext(ctx, err.Error())
+		os.Exit(1)
+	}
+	otel.SetTextMapPropagator(propagator)
+
+	var httpMetrics *common.HTTPServerMetrics
+
+	meterProvider, err := common.InitMeterProvider(ctx, utils.SplitList(*otelMetricsExporters))
+	if err != nil {
+		slog.ErrorContext(ctx, err.Error())
+		os.Exit(1)
+	}
+
+	if meterProvider != nil {
+		httpMetrics, err = common.NewHTTPServerMetrics(meterProvider)
+		if err != nil {
+			slog.ErrorContext(ctx, err.Error())
+			os.Exit(1)
+		}
+
+		defer func() {
+			if err := meterProvider.Shutdown(ctx); err != nil {
+				slog.ErrorContext(ctx, "Failed to shut down meter provider", "error", err)
+			}
+		}()
+	}
+
 	ctx, span := tracer.Start(ctx, "main")
 	defer span.End()
 
-	ctx, _, err = loggergo.Init(ctx, loggerConfig)
+	ctx, logger, err := loggergo.Init(ctx, loggerConfig)
 	if err != nil {
 		slog.ErrorContext(ctx, err.Error())
 		os.Exit(1)
 	}
 
-	logLevelConfig := loggergo.GetLogLevelAccessor()
+	// The registry filters records by component and route, so loggergo lets every level through
+	logLevelConfig := new(slog.LevelVar)
+	logLevelConfig.Set(loggergo.GetLogLevelAccessor().Level())
+	loggergo.GetLogLevelAccessor().Set(utils.MinLogLevel)
+
+	logLevels := utils.NewLevelRegistry(logLevelConfig)
+	slog.SetDefault(slog.New(utils.RequestIDHandler(logLevels.Handler(logger.Handler()))))
+
+	if err := logLevels.Apply(utils.SplitList(*logLevelOverrides)); err != nil {
+		slog.ErrorContext(ctx, err.Error())
+		os.Exit(1)
+	}
 
 	slog.DebugContext(ctx, "flags",
 		"listen-addr", *listenAddr,
 		"log-level", *logLevel,
+		"log-levels", *logLevelOverrides,
 		"log-format", *logFormat,
 		"otel-enabled", *otelEnabled,
+		"otel-propagators", *otelPropagators,
+		"otel-filtered-paths", *otelFilteredPaths,
+		"otel-metrics-exporters", *otelMetricsExporters,
 		"profiling-enabled", *profilingEnabled,
 		"profiling-address", *profilingAddress,
 		"web-framework", *webFramework,
 		"statsviz-enabled", *statsvizEnabled,
+		"chain-hops", *chainHops,
+		"chain-mode", *chainMode,
+		"chain-allowed-hops", *chainAllowedHops,
+		"chain-max-depth", *chainMaxDepth,
+		"proxy-upstream", *proxyUpstream,
+		"admin-addr", *adminAddr,
+		"middleware-order", *middlewareOrder,
+		"middleware-disabled", *middlewareDisabled,
+		"compression-encodings", *compressionEncodings,
+		"compression-min-size", *compressionMinSize,
+		"cors-allowed-origins", *corsAllowedOrigins,
+		"security-headers", *securityHeadersPreset,
 	)
 
 	if strings.EqualFold(logLevelConfig.Level().String(), "debug") {
 		slog.DebugContext(ctx, "Debug mode enabled")
 	}
 
+	proxyRoutes, err := common.ParseProxyRoutes(utils.SplitList(*proxyUpstream))
+	if err != nil {
+		slog.ErrorContext(ctx, err.Error())
+		os.Exit(1)
+	}
+
+	proxyHeaders, err := common.ParseHeaderAssignments(utils.SplitList(*proxySetHeaders))
+	if err != nil {
+		slog.ErrorContext(ctx, err.Error())
+		os.Exit(1)
+	}
+
+	redactor, appErr := utils.NewRedactor(utils.RedactionConfig{
+		Headers:        utils.SplitList(*redactHeaders),
+		HeaderPatterns: utils.SplitList(*redactHeaderPatterns),
+		QueryParams:    utils.SplitList(*redactQueryParams),
+		Fields:         utils.SplitList(*redactFields),
+		Mode:           utils.RedactionMode(*redactionMode),
+		HashKey:        *redactionHashKey,
+	})
+	if appErr != nil {
+		slog.ErrorContext(ctx, appErr.Error())
+		os.Exit(1)
+	}
+	utils.SetDefaultRedactor(redactor)
+
+	requestIDConfig := common.RequestIDConfig{
+		Header: *requestIDHeader,
+		Format: *requestIDFormat,
+	}
+	if err := requestIDConfig.Validate(); err != nil {
+		slog.ErrorContext(ctx, err.Error())
+		os.Exit(1)
+	}
+
+	accessLogConfig := common.AccessLogConfig{
+		Fields:       utils.SplitList(*accessLogFields),
+		Headers:      utils.SplitList(*accessLogHeaders),
+		ExcludePaths: utils.SplitList(*accessLogExclude),
+		SampleRate:   *accessLogSampleRate,
+		RateLimit:    *accessLogRateLimit,
+		BodyLimit:    *accessLogBodyLimit,
+		Redaction:    redactor,
+	}
+	if err := accessLogConfig.Validate(); err != nil {
+		slog.ErrorContext(ctx, err.Error())
+		os.Exit(1)
+	}
+
+	concurrencyConfig := common.ConcurrencyConfig{
+		Limit:         *concurrencyLimit,
+		QueueSize:     *concurrencyQueueSize,
+		QueueTimeout:  *concurrencyQueueTimeout,
+		Adaptive:      *concurrencyAdaptive,
+		MinLimit:      *concurrencyMinLimit,
+		TargetLatency: *concurrencyTargetLatency,
+		RetryAfter:    *concurrencyRetryAfter,
+		ExcludePaths:  utils.SplitList(*concurrencyExclude),
+	}
+	if err := concurrencyConfig.Validate(); err != nil {
+		slog.ErrorContext(ctx, err.Error())
+		os.Exit(1)
+	}
+
+	serverConfig := common.ServerConfig{
+		ReadTimeout:       *serverReadTimeout,
+		ReadHeaderTimeout: *serverReadHeaderTimeout,
+		WriteTimeout:      *serverWriteTimeout,
+		IdleTimeout:       *serverIdleTimeout,
+		MaxHeaderBytes:    *serverMaxHeaderBytes,
+		MaxBodyBytes:      *serverMaxBodyBytes,
+		DisableKeepAlive:  !*serverKeepAlive,
+	}
+	if err := serverConfig.Validate(); err != nil {
+		slog.ErrorContext(ctx, err.Error())
+		os.Exit(1)
+	}
+
+	supervisorConfig := web.SupervisorConfig{
+		Retries:    *serverStartRetries,
+		Backoff:    *serverStartBackoff,
+		MaxBackoff: *serverStartMaxBackoff,
+	}
+	if err := supervisorConfig.Validate(); err != nil {
+		slog.ErrorContext(ctx, err.Error())
+		os.Exit(1)
+	}
+
+	rateLimitConfig, appErr := common.ParseRateLimitConfig(*rateLimitKey, *rateLimitGlobal, *rateLimitDefault, utils.SplitList(*rateLimitRoutes), utils.SplitList(*rateLimitExclude))
+	if appErr != nil {
+		slog.ErrorContext(ctx, appErr.Error())
+		os.Exit(1)
+	}
+
+	middlewareConfig, appErr := common.ParseMiddlewareConfig(utils.SplitList(*middlewareOrder), utils.SplitList(*middlewareDisabled))
+	if appErr != nil {
+		slog.ErrorContext(ctx, appErr.Error())
+		os.Exit(1)
+	}
+
+	compressionConfig, appErr := common.ParseCompressionConfig(utils.SplitList(*compressionEncodings), utils.SplitList(*compressionLevels), *compressionMinSize, utils.SplitList(*compressionContentTypes))
+	if appErr != nil {
+		slog.ErrorContext(ctx, appErr.Error())
+		os.Exit(1)
+	}
+
+	corsConfig, appErr := common.ParseCORSConfig(utils.SplitList(*corsAllowedOrigins), utils.SplitList(*corsAllowedMethods), utils.SplitList(*corsAllowedHeaders), utils.SplitList(*corsExposedHeaders), *corsAllowCredentials, *corsMaxAge)
+	if appErr != nil {
+		slog.ErrorContext(ctx, appErr.Error())
+		os.Exit(1)
+	}
+
+	securityHeadersConfig, appErr := common.ParseSecurityHeadersConfig(*securityHeadersPreset, utils.SplitList(*securityHeadersSet))
+	if appErr != nil {
+		slog.ErrorContext(ctx, appErr.Error())
+		os.Exit(1)
+	}
+
+	tracingConfig := common.TracingConfig{
+		Propagators:             propagator,
+		FilteredPaths:           utils.SplitList(*otelFilteredPaths),
+		CapturedRequestHeaders:  utils.SplitList(*otelCaptureRequestHeaders),
+		CapturedResponseHeaders: utils.SplitList(*otelCaptureResponseHeaders),
+		Redaction:               redactor,
+	}
+
+	// a nil *trace.TracerProvider would be a non-nil interface, so it is only set with OpenTelemetry enabled
+	if traceProvider != nil {
+		tracingConfig.TracerProvider = traceProvider
+	}
+
 	frameworkOptions := common.FrameworkOptions{
 		ListenAddr:      *listenAddr,
 		OtelEnabled:     *otelEnabled,
 		StatsvizEnabled: *statsvizEnabled,
 		Tracer:          tracer,
 		LogLevelConfig:  logLevelConfig,
-		TraceProvider:   traceProvider,
+		LogLevels:       logLevels,
+		Tracing:         tracingConfig,
+		HTTPMetrics:     httpMetrics,
+		Chain: common.ChainConfig{
+			Hops:        utils.SplitList(*chainHops),
+			Mode:        *chainMode,
+			Timeout:     *chainTimeout,
+			Client:      common.NewChainClient(tracingConfig, *chainTimeout),
+			MaxDepth:    *chainMaxDepth,
+			AllowedHops: utils.SplitList(*chainAllowedHops),
+		},
+		Proxy: common.ProxyConfig{
+			Routes:       proxyRoutes,
+			StripPrefix:  *proxyStripPrefix,
+			SetHeaders:   proxyHeaders,
+			StripHeaders: utils.SplitList(*proxyStripHeaders),
+			Timeout:      *proxyTimeout,
+			Retries:      *proxyRetries,
+		},
+		Problems: common.ProblemConfig{
+			TypeURIPrefix:    *problemTypePrefix,
+			ContextAllowlist: utils.SplitList(*problemContextAllowlist),
+			Redaction:        redactor,
+		},
+		Admin: common.AdminConfig{
+			ListenAddr:   *adminAddr,
+			Token:        *adminToken,
+			TLSCertFile:  *adminTLSCert,
+			TLSKeyFile:   *adminTLSKey,
+			ClientCAFile: *adminClientCA,
+		},
+		Runtime:         common.NewRuntimeState(),
+		AccessLog:       common.NewAccessLogger(accessLogConfig),
+		Redaction:       redactor,
+		RequestID:       requestIDConfig,
+		RateLimit:       common.NewRateLimiter(rateLimitConfig),
+		Concurrency:     common.NewConcurrencyLimiter(concurrencyConfig),
+		Server:          serverConfig,
+		Middleware:      common.NewMiddlewareRegistry(middlewareConfig),
+		Compression:     common.NewCompressor(compressionConfig),
+		CORS:            common.NewCORSPolicy(corsConfig),
+		SecurityHeaders: common.NewSecurityHeaders(securityHeadersConfig),
+	}
+
+	if *instanceInfo {
+		frameworkOptions.Instance = common.LoadInstanceInfo(common.InstanceConfig{
+			PodInfoPath:    *podInfoPath,
+			CgroupRoot:     *cgroupRoot,
+			ContainerImage: *containerImage,
+		}, redactor)
 	}
 
 	// Create a channel to signal framework changes
-	common.FrameworkChannel = make(chan string)
+	common.FrameworkChannel = make(chan common.FrameworkSwitch)
 
-	go web.RunWebServer(ctx, frameworkOptions)
+	// The first server is started before serving switches, so a failure is fatal
+	supervisor := web.NewSupervisor(frameworkOptions, supervisorConfig)
+	if err := supervisor.Switch(ctx, *webFramework); err != nil {
+		// the failure is already logged by the supervisor
+		os.Exit(1)
+	}
+	go supervisor.Run(ctx)
 
-	common.FrameworkChannel <- *webFramework
+	if frameworkOptions.Admin.Enabled() {
+		adminServer := admin.NewServer(frameworkOptions)
+		if err := adminServer.Start(ctx); err != nil {
+			slog.ErrorContext(ctx, err.Error())
+			os.Exit(1)
+		}
+		defer adminServer.Stop(context.WithoutCancel(ctx))
+	}
 
 	// Wait for the context to be canceled
 	<-ctx.Done()
@@ -2,13 +2,19 @@ package utils
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"log/slog"
+	"maps"
+	"runtime"
+	"sync"
+	"sync/atomic"
 	"time"
 
-	"github.com/prometheus/client_golang/prometheus"
-	"github.com/prometheus/client_golang/prometheus/promauto"
+	"go.opentelemetry.io/otel/attribute"
+	otelcodes "go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
 )
 
 // ErrorType represents the category of an error
@@ -26,15 +32,111 @@ const (
 
 	// ValidationError represents validation errors
 	ValidationError ErrorType = "validation"
+
+	// RateLimitError represents requests rejected by the rate limiter
+	RateLimitError ErrorType = "rate_limit"
+
+	// OverloadError represents requests shed because the server is overloaded
+	OverloadError ErrorType = "overload"
+
+	// ForbiddenError represents requests not accepted on the listener they were sent to
+	ForbiddenError ErrorType = "forbidden"
+
+	// UnauthorizedError represents requests without valid credentials
+	UnauthorizedError ErrorType = "unauthorized"
+)
+
+// ErrorCode is a stable, machine-readable identifier of an error, e.g. "validation.chain_mode"
+type ErrorCode string
+
+// Severity represents how serious an error is, it selects the log level
+type Severity string
+
+const (
+	// SeverityInfo is used for expected errors, e.g. client mistakes
+	SeverityInfo Severity = "info"
+
+	// SeverityWarning is used for degraded but handled conditions
+	SeverityWarning Severity = "warning"
+
+	// SeverityError is the default severity
+	SeverityError Severity = "error"
+
+	// SeverityCritical is used for errors the application cannot recover from
+	SeverityCritical Severity = "critical"
+)
+
+// Sentinel errors matching any AppError of the given type with errors.Is
+var (
+	ErrConfig       = &AppError{Type: ConfigError}
+	ErrRuntime      = &AppError{Type: RuntimeError}
+	ErrFramework    = &AppError{Type: FrameworkError}
+	ErrValidation   = &AppError{Type: ValidationError}
+	ErrRateLimit    = &AppError{Type: RateLimitError}
+	ErrOverload     = &AppError{Type: OverloadError}
+	ErrForbidden    = &AppError{Type: ForbiddenError}
+	ErrUnauthorized = &AppError{Type: UnauthorizedError}
 )
 
+// Context keys returned to clients in problem responses by default. Errors should add
+// context with these keys when the value is safe to expose, other keys are redacted
+// unless they are added to -problem-context-allowlist.
+const (
+	ContextPath             = "path"
+	ContextMode             = "mode"
+	ContextHops             = "hops"
+	ContextDepth            = "depth"
+	ContextFramework        = "framework"
+	ContextRunningFramework = "running_framework"
+	ContextLogLevel         = "log_level"
+	ContextLogger           = "logger"
+	ContextTTL              = "ttl"
+	ContextScope            = "scope"
+	ContextRule             = "rule"
+	ContextReason           = "reason"
+	ContextFormat           = "format"
+	ContextMiddleware       = "middleware"
+	ContextEncoding         = "encoding"
+	ContextLevel            = "level"
+	ContextOrigin           = "origin"
+	ContextPreset           = "preset"
+)
+
+// PublicContextKeys lists the context keys returned to clients by default, the default
+// of -problem-context-allowlist
+func PublicContextKeys() []string {
+	return []string{
+		ContextPath, ContextMode, ContextHops, ContextDepth, ContextFramework, ContextRunningFramework,
+		ContextLogLevel, ContextLogger, ContextTTL, RequestIDKey, ContextScope, ContextRule,
+		ContextReason, ContextFormat, ContextMiddleware, ContextEncoding, ContextLevel,
+		ContextOrigin, ContextPreset,
+	}
+}
+
+// maxStackDepth bounds the number of frames captured for an AppError
+const maxStackDepth = 32
+
+// stackCapture enables capturing the stack when AppErrors are created
+var stackCapture atomic.Bool
+
+// SetStackCapture enables or disables capturing the call stack in NewAppError and WrapError
+func SetStackCapture(enabled bool) {
+	stackCapture.Store(enabled)
+}
+
 // AppError represents a standardized application error with type and context
 type AppError struct {
 	Type       ErrorType
+	Code       ErrorCode
 	Message    string
 	Err        error
+	Severity   Severity
+	Retryable  bool
 	OccurredAt time.Time
-	Context    map[string]interface{}
+	Stack      []string
+
+	mu      sync.RWMutex
+	context map[string]interface{}
 }
 
 // Error returns the error message
@@ -50,100 +152,267 @@ func (e *AppError) Unwrap() error {
 	return e.Err
 }
 
+// Is reports whether the error matches target, an AppError whose non-empty type and code
+// must both match, so errors.Is(err, ErrValidation) matches any validation error
+func (e *AppError) Is(target error) bool {
+	t, ok := target.(*AppError)
+	if !ok || (t.Type == "" && t.Code == "") {
+		return false
+	}
+	if t.Type != "" && t.Type != e.Type {
+		return false
+	}
+	return t.Code == "" || t.Code == e.Code
+}
+
 // NewAppError creates a new AppError instance
 func NewAppError(errType ErrorType, message string, err error) *AppError {
-	return &AppError{
-		Type:       errType,
-		Message:    message,
-		Err:        err,
-		OccurredAt: time.Now(),
-		Context:    make(map[string]interface{}),
-	}
+	return newAppError(errType, message, err)
 }
 
 // WrapError wraps an existing error with additional context
 func WrapError(err error, errType ErrorType, message string) *AppError {
-	return &AppError{
+	return newAppError(errType, message, err)
+}
+
+// newAppError is shared by the constructors so the stack starts at their caller
+func newAppError(errType ErrorType, message string, err error) *AppError {
+	appErr := &AppError{
 		Type:       errType,
+		Code:       ErrorCode(errType),
 		Message:    message,
 		Err:        err,
+		Severity:   SeverityError,
 		OccurredAt: time.Now(),
-		Context:    make(map[string]interface{}),
+		context:    make(map[string]interface{}),
+	}
+
+	if stackCapture.Load() {
+		appErr.Stack = captureStack(3)
+	}
+
+	return appErr
+}
+
+// captureStack formats the call stack as "function file:line" frames
+func captureStack(skip int) []string {
+	pcs := make([]uintptr, maxStackDepth)
+	n := runtime.Callers(skip+1, pcs)
+	frames := runtime.CallersFrames(pcs[:n])
+
+	stack := make([]string, 0, n)
+	for {
+		frame, more := frames.Next()
+		stack = append(stack, fmt.Sprintf("%s %s:%d", frame.Function, frame.File, frame.Line))
+		if !more {
+			break
+		}
 	}
+	return stack
 }
 
-// AddContext adds context information to an AppError
+// AddContext adds context information to an AppError, it is safe for concurrent use
 func (e *AppError) AddContext(key string, value interface{}) *AppError {
-	e.Context[key] = value
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if e.context == nil {
+		e.context = make(map[string]interface{})
+	}
+	e.context[key] = value
 	return e
 }
 
-// ErrorCounterVec is a Prometheus counter vector for tracking errors by type
-var ErrorCounterVec = promauto.NewCounterVec(
-	prometheus.CounterOpts{
-		Name: "app_errors_total",
-		Help: "Total number of application errors by type",
-	},
-	[]string{"error_type", "error_message"},
-)
+// Context returns a copy of the context information
+func (e *AppError) Context() map[string]interface{} {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
 
-// LogError logs the AppError using slog and updates metrics
-func (e *AppError) LogError(ctx interface{}) {
-	// Update error metrics
-	ErrorCounterVec.WithLabelValues(string(e.Type), e.Message).Inc()
+	return maps.Clone(e.context)
+}
+
+// WithCode sets the machine-readable error code
+func (e *AppError) WithCode(code ErrorCode) *AppError {
+	e.Code = code
+	return e
+}
+
+// WithSeverity sets the severity, which selects the level LogError logs at
+func (e *AppError) WithSeverity(severity Severity) *AppError {
+	e.Severity = severity
+	return e
+}
 
-	// Log error with context
-	loggerAttrs := []interface{}{
-		"error_type", e.Type,
-		"message", e.Message,
-		"occurred_at", e.OccurredAt,
+// WithRetryable marks whether the failed operation can be retried
+func (e *AppError) WithRetryable(retryable bool) *AppError {
+	e.Retryable = retryable
+	return e
+}
+
+// LogLevel maps the severity to a slog level
+func (e *AppError) LogLevel() slog.Level {
+	switch e.Severity {
+	case SeverityInfo:
+		return slog.LevelInfo
+	case SeverityWarning:
+		return slog.LevelWarn
+	case SeverityCritical:
+		return slog.LevelError + 4
+	default:
+		return slog.LevelError
 	}
+}
 
-	// Add context fields to log
-	for key, value := range e.Context {
-		loggerAttrs = append(loggerAttrs, key, value)
+// appErrorJSON is the serialized form of an AppError
+type appErrorJSON struct {
+	Type       ErrorType              `json:"type"`
+	Code       ErrorCode              `json:"code"`
+	Message    string                 `json:"message"`
+	Error      string                 `json:"error,omitempty"`
+	Severity   Severity               `json:"severity"`
+	Retryable  bool                   `json:"retryable"`
+	OccurredAt time.Time              `json:"occurred_at"`
+	Context    map[string]interface{} `json:"context,omitempty"`
+	Stack      []string               `json:"stack,omitempty"`
+}
+
+// MarshalJSON serializes the error including its context and wrapped error message
+func (e *AppError) MarshalJSON() ([]byte, error) {
+	out := appErrorJSON{
+		Type:       e.Type,
+		Code:       e.Code,
+		Message:    e.Message,
+		Severity:   e.Severity,
+		Retryable:  e.Retryable,
+		OccurredAt: e.OccurredAt,
+		Context:    DefaultRedactor().Fields(e.Context()),
+		Stack:      e.Stack,
+	}
+	if e.Err != nil {
+		out.Error = e.Err.Error()
+	}
+	return json.Marshal(out)
+}
+
+// LogValue implements slog.LogValuer, logging the error as a group
+func (e *AppError) LogValue() slog.Value {
+	attrs := []slog.Attr{
+		slog.String("type", string(e.Type)),
+		slog.String("code", string(e.Code)),
+		slog.String("message", e.Message),
+		slog.String("severity", string(e.Severity)),
+		slog.Bool("retryable", e.Retryable),
+		slog.Time("occurred_at", e.OccurredAt),
 	}
 
 	if e.Err != nil {
-		loggerAttrs = append(loggerAttrs, "original_error", e.Err)
+		attrs = append(attrs, slog.String("original_error", e.Err.Error()))
+	}
+
+	if fields := DefaultRedactor().Fields(e.Context()); len(fields) > 0 {
+		contextAttrs := make([]any, 0, len(fields))
+		for key, value := range fields {
+			contextAttrs = append(contextAttrs, slog.Any(key, value))
+		}
+		attrs = append(attrs, slog.Group("context", contextAttrs...))
+	}
+
+	if len(e.Stack) > 0 {
+		attrs = append(attrs, slog.Any("stack", e.Stack))
+	}
+
+	return slog.GroupValue(attrs...)
+}
+
+// LogError logs the AppError using slog, updates the error metrics and recent errors
+// and records it on the span active in ctx, if any. The request ID of ctx is added to
+// the error context. A nil ctx is allowed.
+func (e *AppError) LogError(ctx context.Context) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	if id := RequestIDFromContext(ctx); id != "" {
+		e.AddContext(RequestIDKey, id)
+	}
+
+	scope := ErrorScopeFromContext(ctx)
+	framework, route := scope.labels()
+
+	// Update error metrics, with bounded label values
+	ErrorCounterVec.WithLabelValues(
+		errorTypeLabel(e.Type),
+		errorLabels.codes.fold(string(e.Code)),
+		framework,
+		errorLabels.routes.fold(route),
+	).Inc()
+
+	record := ErrorRecord{Error: e, Framework: framework, Route: route}
+
+	// Record the error on the span started by the tracing middleware
+	span := trace.SpanFromContext(ctx)
+	if span.SpanContext().HasTraceID() {
+		record.TraceID = span.SpanContext().TraceID().String()
 	}
+	if span.IsRecording() {
+		span.RecordError(e, trace.WithAttributes(
+			attribute.String("error.type", string(e.Type)),
+			attribute.String("error.code", string(e.Code)),
+			attribute.Bool("error.retryable", e.Retryable),
+		))
+		span.SetStatus(otelcodes.Error, e.Message)
+	}
+
+	RecentErrors().Add(record)
 
 	// Use slog to log the error with context
-	slog.ErrorContext(ctx.(context.Context), e.Error(), loggerAttrs...)
+	ComponentLogger(ComponentErrors).Log(ctx, e.LogLevel(), e.Error(), "error", e)
 }
 
 // IsConfigError checks if an error is of configuration type
 func IsConfigError(err error) bool {
-	var appErr *AppError
-	if errors.As(err, &appErr) {
-		return appErr.Type == ConfigError
-	}
-	return false
+	return errors.Is(err, ErrConfig)
 }
 
 // IsRuntimeError checks if an error is of runtime type
 func IsRuntimeError(err error) bool {
-	var appErr *AppError
-	if errors.As(err, &appErr) {
-		return appErr.Type == RuntimeError
-	}
-	return false
+	return errors.Is(err, ErrRuntime)
 }
 
 // IsFrameworkError checks if an error is of framework type
 func IsFrameworkError(err error) bool {
-	var appErr *AppError
-	if errors.As(err, &appErr) {
-		return appErr.Type == FrameworkError
-	}
-	return false
+	return errors.Is(err, ErrFramework)
 }
 
 // IsValidationError checks if an error is of validation type
 func IsValidationError(err error) bool {
-	var appErr *AppError
-	if errors.As(err, &appErr) {
-		return appErr.Type == ValidationError
+	return errors.Is(err, ErrValidation)
+}
+
+// IsRateLimitError checks if an error is of rate limit type
+func IsRateLimitError(err error) bool {
+	return errors.Is(err, ErrRateLimit)
+}
+
+// IsOverloadError checks if an error is of overload type
+func IsOverloadError(err error) bool {
+	return errors.Is(err, ErrOverload)
+}
+
+// IsForbiddenError checks if an error is of forbidden type
+func IsForbiddenError(err error) bool {
+	return errors.Is(err, ErrForbidden)
+}
+
+// IsUnauthorizedError checks if an error is of unauthorized type
+func IsUnauthorizedError(err error) bool {
+	return errors.Is(err, ErrUnauthorized)
+}
+
+// IsRetryable checks if an error is an AppError marked as retryable
+func IsRetryable(err error) bool {
+	if appErr, ok := AsAppError(err); ok {
+		return appErr.Retryable
 	}
 	return false
 }
@@ -0,0 +1,130 @@
+package utils
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	otelcodes "go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestAppErrorIs(t *testing.T) {
+	err := fmt.Errorf("handler: %w", NewAppError(ValidationError, "invalid chain mode", nil).WithCode("validation.chain_mode"))
+
+	assert.True(t, errors.Is(err, ErrValidation))
+	assert.False(t, errors.Is(err, ErrRuntime))
+	assert.True(t, errors.Is(err, &AppError{Code: "validation.chain_mode"}))
+	assert.False(t, errors.Is(err, &AppError{Type: ValidationError, Code: "validation.other"}))
+	assert.True(t, IsValidationError(err))
+}
+
+func TestAppErrorDefaults(t *testing.T) {
+	appErr := WrapError(errors.New("refused"), RuntimeError, "upstream failed")
+
+	assert.Equal(t, ErrorCode(RuntimeError), appErr.Code)
+	assert.Equal(t, SeverityError, appErr.Severity)
+	assert.False(t, IsRetryable(appErr))
+	assert.Empty(t, appErr.Stack)
+
+	assert.True(t, IsRetryable(appErr.WithRetryable(true)))
+	assert.Equal(t, slog.LevelWarn, appErr.WithSeverity(SeverityWarning).LogLevel())
+}
+
+func TestAppErrorStackCapture(t *testing.T) {
+	SetStackCapture(true)
+	defer SetStackCapture(false)
+
+	appErr := NewAppError(ConfigError, "bad flag", nil)
+
+	require.NotEmpty(t, appErr.Stack)
+	assert.True(t, strings.HasPrefix(appErr.Stack[0], "github.com/wasilak/go-hello-world/utils.TestAppErrorStackCapture "), appErr.Stack[0])
+}
+
+func TestAppErrorConcurrentContext(t *testing.T) {
+	appErr := NewAppError(RuntimeError, "concurrent", nil)
+
+	var wg sync.WaitGroup
+	for i := range 50 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			appErr.AddContext(fmt.Sprintf("key_%d", i), i)
+			_ = appErr.Context()
+		}()
+	}
+	wg.Wait()
+
+	assert.Len(t, appErr.Context(), 50)
+}
+
+func TestAppErrorMarshalJSON(t *testing.T) {
+	appErr := WrapError(errors.New("refused"), RuntimeError, "upstream failed").
+		WithCode("runtime.proxy_upstream").
+		AddContext("path", "/api")
+
+	data, err := json.Marshal(appErr)
+	require.NoError(t, err)
+
+	var body map[string]interface{}
+	require.NoError(t, json.Unmarshal(data, &body))
+	assert.Equal(t, "runtime.proxy_upstream", body["code"])
+	assert.Equal(t, "refused", body["error"])
+	assert.Equal(t, map[string]interface{}{"path": "/api"}, body["context"])
+}
+
+func TestAppErrorLogError(t *testing.T) {
+	var buf bytes.Buffer
+	previous := slog.Default()
+	slog.SetDefault(slog.New(slog.NewJSONHandler(&buf, nil)))
+	defer slog.SetDefault(previous)
+
+	exporter := tracetest.NewInMemoryExporter()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+	ctx, span := provider.Tracer("test").Start(context.Background(), "request")
+
+	NewAppError(ValidationError, "invalid", nil).
+		WithSeverity(SeverityWarning).
+		AddContext("mode", "random").
+		LogError(ctx)
+	span.End()
+
+	t.Run("Log record", func(t *testing.T) {
+		var record map[string]interface{}
+		require.NoError(t, json.Unmarshal(buf.Bytes(), &record))
+		assert.Equal(t, "WARN", record["level"])
+
+		logged := record["error"].(map[string]interface{})
+		assert.Equal(t, "validation", logged["code"])
+		assert.Equal(t, map[string]interface{}{"mode": "random"}, logged["context"])
+	})
+
+	t.Run("Span", func(t *testing.T) {
+		spans := exporter.GetSpans()
+		require.Len(t, spans, 1)
+		assert.Equal(t, otelcodes.Error, spans[0].Status.Code)
+		require.Len(t, spans[0].Events, 1)
+		assert.Equal(t, "exception", spans[0].Events[0].Name)
+	})
+}
+
+func TestPublicContextKeys(t *testing.T) {
+	keys := PublicContextKeys()
+	assert.Contains(t, keys, RequestIDKey)
+	assert.Contains(t, keys, ContextPath)
+
+	seen := make(map[string]bool, len(keys))
+	for _, key := range keys {
+		assert.False(t, seen[key], "duplicate key %s", key)
+		seen[key] = true
+	}
+}
@@ -0,0 +1,192 @@
+package utils
+
+import (
+	"context"
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+const (
+	// OtherLabelValue replaces error metric label values beyond the cardinality limit
+	OtherLabelValue = "other"
+
+	// DefaultMaxErrorLabelValues is the default number of distinct codes and routes per label
+	DefaultMaxErrorLabelValues = 50
+
+	// DefaultRecentErrorsSize is the default capacity of the recent errors buffer
+	DefaultRecentErrorsSize = 100
+)
+
+// ErrorCounterVec is a Prometheus counter vector for tracking errors by type, code,
+// framework and route, label values are bounded by the cardinality guard
+var ErrorCounterVec = promauto.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: "app_errors_total",
+		Help: "Total number of application errors by type, code, framework and route",
+	},
+	[]string{"error_type", "error_code", "framework", "route"},
+)
+
+// ErrorScope describes the request an error occurred in, it is attached to the request
+// context by the frameworks so errors are labelled with the framework and route
+type ErrorScope struct {
+	Framework string
+
+	// Route returns the route template, it is resolved when the error is logged
+	// because some frameworks only know the route once routing completed
+	Route func() string
+}
+
+type errorScopeKey struct{}
+
+// WithErrorScope returns a copy of ctx carrying the error scope
+func WithErrorScope(ctx context.Context, scope ErrorScope) context.Context {
+	return context.WithValue(ctx, errorScopeKey{}, scope)
+}
+
+// ErrorScopeFromContext returns the error scope of ctx, or an empty scope
+func ErrorScopeFromContext(ctx context.Context) ErrorScope {
+	scope, _ := ctx.Value(errorScopeKey{}).(ErrorScope)
+	return scope
+}
+
+// labels returns the framework and route of the scope
+func (s ErrorScope) labels() (string, string) {
+	route := ""
+	if s.Route != nil {
+		route = s.Route()
+	}
+	return s.Framework, route
+}
+
+// cardinalityGuard admits the first max distinct values of a label and folds the rest into "other"
+type cardinalityGuard struct {
+	mu    sync.Mutex
+	limit int
+	seen  map[string]struct{}
+}
+
+func newCardinalityGuard(limit int) *cardinalityGuard {
+	return &cardinalityGuard{limit: limit, seen: make(map[string]struct{})}
+}
+
+// reset forgets the admitted values and sets a new limit
+func (g *cardinalityGuard) reset(limit int) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	g.limit = limit
+	g.seen = make(map[string]struct{})
+}
+
+// fold returns value if it is known or there is room for it, "other" otherwise
+func (g *cardinalityGuard) fold(value string) string {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if _, ok := g.seen[value]; ok {
+		return value
+	}
+	if len(g.seen) >= g.limit {
+		return OtherLabelValue
+	}
+	g.seen[value] = struct{}{}
+	return value
+}
+
+// errorTypeLabel returns the type label, folding types outside the known set
+func errorTypeLabel(errType ErrorType) string {
+	switch errType {
+	case ConfigError, RuntimeError, FrameworkError, ValidationError, RateLimitError, OverloadError, ForbiddenError, UnauthorizedError:
+		return string(errType)
+	default:
+		return OtherLabelValue
+	}
+}
+
+// errorLabels holds the cardinality guards of the code and route labels
+var errorLabels = struct {
+	codes  *cardinalityGuard
+	routes *cardinalityGuard
+}{
+	codes:  newCardinalityGuard(DefaultMaxErrorLabelValues),
+	routes: newCardinalityGuard(DefaultMaxErrorLabelValues),
+}
+
+// ErrorRecord is an entry of the recent errors buffer
+type ErrorRecord struct {
+	Error     *AppError `json:"error"`
+	Framework string    `json:"framework,omitempty"`
+	Route     string    `json:"route,omitempty"`
+	TraceID   string    `json:"trace_id,omitempty"`
+}
+
+// ErrorRing is a fixed size ring buffer of the most recent errors, safe for concurrent use
+type ErrorRing struct {
+	mu      sync.Mutex
+	records []ErrorRecord
+	next    int
+	full    bool
+}
+
+// NewErrorRing creates a ring buffer keeping the last size errors
+func NewErrorRing(size int) *ErrorRing {
+	return &ErrorRing{records: make([]ErrorRecord, max(size, 1))}
+}
+
+// Add stores the record, overwriting the oldest one when the buffer is full
+func (r *ErrorRing) Add(record ErrorRecord) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.records[r.next] = record
+	r.next = (r.next + 1) % len(r.records)
+	if r.next == 0 {
+		r.full = true
+	}
+}
+
+// Snapshot returns up to limit records, newest first; limit <= 0 returns all of them
+func (r *ErrorRing) Snapshot(limit int) []ErrorRecord {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	count := r.next
+	if r.full {
+		count = len(r.records)
+	}
+	if limit > 0 && limit < count {
+		count = limit
+	}
+
+	snapshot := make([]ErrorRecord, 0, count)
+	for i := 1; i <= count; i++ {
+		snapshot = append(snapshot, r.records[(r.next-i+len(r.records))%len(r.records)])
+	}
+	return snapshot
+}
+
+var (
+	recentErrorsMu sync.RWMutex
+	recentErrors   = NewErrorRing(DefaultRecentErrorsSize)
+)
+
+// RecentErrors returns the buffer of the most recently logged errors
+func RecentErrors() *ErrorRing {
+	recentErrorsMu.RLock()
+	defer recentErrorsMu.RUnlock()
+	return recentErrors
+}
+
+// ConfigureErrorTracking sets the number of distinct code and route label values kept
+// before folding into "other", and the size of the recent errors buffer. It resets both.
+func ConfigureErrorTracking(maxLabelValues, recentErrorsSize int) {
+	errorLabels.codes.reset(maxLabelValues)
+	errorLabels.routes.reset(maxLabelValues)
+
+	recentErrorsMu.Lock()
+	defer recentErrorsMu.Unlock()
+	recentErrors = NewErrorRing(recentErrorsSize)
+}
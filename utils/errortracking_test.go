@@ -0,0 +1,69 @@
+package utils
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCardinalityGuard(t *testing.T) {
+	guard := newCardinalityGuard(2)
+
+	assert.Equal(t, "a", guard.fold("a"))
+	assert.Equal(t, "b", guard.fold("b"))
+	assert.Equal(t, OtherLabelValue, guard.fold("c"))
+	assert.Equal(t, "a", guard.fold("a"))
+
+	guard.reset(1)
+	assert.Equal(t, "c", guard.fold("c"))
+	assert.Equal(t, OtherLabelValue, guard.fold("a"))
+}
+
+func TestErrorRing(t *testing.T) {
+	ring := NewErrorRing(3)
+	assert.Empty(t, ring.Snapshot(0))
+
+	for i := range 5 {
+		ring.Add(ErrorRecord{Route: fmt.Sprintf("/%d", i)})
+	}
+
+	routes := func(records []ErrorRecord) []string {
+		var out []string
+		for _, record := range records {
+			out = append(out, record.Route)
+		}
+		return out
+	}
+
+	assert.Equal(t, []string{"/4", "/3", "/2"}, routes(ring.Snapshot(0)))
+	assert.Equal(t, []string{"/4"}, routes(ring.Snapshot(1)))
+}
+
+func TestLogErrorLabels(t *testing.T) {
+	ConfigureErrorTracking(1, 10)
+	defer ConfigureErrorTracking(DefaultMaxErrorLabelValues, DefaultRecentErrorsSize)
+
+	ctx := WithErrorScope(context.Background(), ErrorScope{Framework: "chi", Route: func() string { return "/chain" }})
+
+	NewAppError(ValidationError, "first", nil).WithCode("validation.first").LogError(ctx)
+	NewAppError(ValidationError, "second", nil).WithCode("validation.second").LogError(ctx)
+
+	assert.Equal(t, 1.0, testutil.ToFloat64(ErrorCounterVec.WithLabelValues("validation", "validation.first", "chi", "/chain")))
+	assert.Equal(t, 1.0, testutil.ToFloat64(ErrorCounterVec.WithLabelValues("validation", OtherLabelValue, "chi", "/chain")))
+
+	records := RecentErrors().Snapshot(0)
+	require.Len(t, records, 2)
+	assert.Equal(t, "second", records[0].Error.Message)
+	assert.Equal(t, "chi", records[0].Framework)
+	assert.Equal(t, "/chain", records[0].Route)
+}
+
+func TestLogErrorNilContext(t *testing.T) {
+	assert.NotPanics(t, func() {
+		NewAppError(RuntimeError, "no context", nil).LogError(nil)
+	})
+}
@@ -0,0 +1,352 @@
+package utils
+
+import (
+	"context"
+	"log/slog"
+	"maps"
+	"math"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultLogger names the default level, used when no component or route level applies
+	DefaultLogger = "default"
+
+	// ComponentKey is the logger attribute selecting the component level
+	ComponentKey = "component"
+
+	// RouteLoggerPrefix prefixes route templates to name per-route levels, e.g. "route:/chain"
+	RouteLoggerPrefix = "route:"
+
+	// MinLogLevel lets every record through a handler whose filtering is done by the registry
+	MinLogLevel = slog.Level(math.MinInt32)
+)
+
+// Log components with adjustable levels
+const (
+	ComponentServer   = "server"
+	ComponentRouter   = "router"
+	ComponentHandlers = "handlers"
+	ComponentErrors   = "errors"
+	ComponentAccess   = "access"
+)
+
+// Components lists the log components with adjustable levels
+func Components() []string {
+	return []string{ComponentServer, ComponentRouter, ComponentHandlers, ComponentErrors, ComponentAccess}
+}
+
+// ComponentLogger returns the default logger tagged with the component, so the
+// component's level applies to it
+func ComponentLogger(component string) *slog.Logger {
+	return slog.Default().With(ComponentKey, component)
+}
+
+// RouteLogger returns the logger name of a route template
+func RouteLogger(route string) string {
+	return RouteLoggerPrefix + route
+}
+
+// ParseLevel parses a level name (debug, info, warn, error, optionally with an offset
+// such as "debug-4"), returning a validation error for unknown levels
+func ParseLevel(value string) (slog.Level, *AppError) {
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(strings.TrimSpace(value))); err != nil {
+		return 0, WrapError(err, ValidationError, "unknown log level").
+			WithCode("validation.log_level").
+			AddContext(ContextLogLevel, value)
+	}
+	return level, nil
+}
+
+// LoggerLevel describes the level of a named logger
+type LoggerLevel struct {
+	Logger    string     `json:"logger"`
+	Level     string     `json:"level"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+}
+
+// levelEntry is the level of a logger, with the temporary change reverted on expiry
+type levelEntry struct {
+	level     slog.Level
+	expiresAt time.Time
+	timer     *time.Timer
+
+	// previous is restored on expiry, nil removes the override
+	previous *slog.Level
+}
+
+// LevelRegistry holds the default log level and per component and per route overrides,
+// optionally reverted after a TTL. Its handler filters records, the route is taken from
+// the request scope attached to the context.
+type LevelRegistry struct {
+	defaultLevel *slog.LevelVar
+
+	mu        sync.RWMutex
+	overrides map[string]*levelEntry
+	defaults  *levelEntry
+}
+
+// NewLevelRegistry creates a registry using defaultLevel as the default level
+func NewLevelRegistry(defaultLevel *slog.LevelVar) *LevelRegistry {
+	return &LevelRegistry{
+		defaultLevel: defaultLevel,
+		overrides:    make(map[string]*levelEntry),
+	}
+}
+
+// DefaultLevel returns the default level variable
+func (r *LevelRegistry) DefaultLevel() *slog.LevelVar {
+	return r.defaultLevel
+}
+
+// validateLogger checks a logger name is the default, a component or a route
+func validateLogger(name string) *AppError {
+	if name == DefaultLogger || slices.Contains(Components(), name) {
+		return nil
+	}
+	if route, ok := strings.CutPrefix(name, RouteLoggerPrefix); ok && strings.HasPrefix(route, "/") {
+		return nil
+	}
+	return NewAppError(ValidationError, "unknown logger, expected default, a component or route:/path", nil).
+		WithCode("validation.logger").
+		AddContext(ContextLogger, name)
+}
+
+// Set changes the level of a logger and returns its previous level. With a positive ttl
+// the change is temporary and the previous level is restored once it expires.
+func (r *LevelRegistry) Set(name string, level slog.Level, ttl time.Duration) (slog.Level, *AppError) {
+	if name == "" {
+		name = DefaultLogger
+	}
+	if appErr := validateLogger(name); appErr != nil {
+		return 0, appErr
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	current := r.entry(name)
+	previousLevel := r.levelLocked(name)
+
+	var previous *slog.Level
+	switch {
+	case current != nil && current.timer != nil:
+		// keep the level from before the first temporary change
+		current.timer.Stop()
+		previous = current.previous
+	case current != nil || name == DefaultLogger:
+		previous = &previousLevel
+	}
+
+	entry := &levelEntry{level: level}
+	if ttl > 0 {
+		entry.previous = previous
+		entry.expiresAt = time.Now().Add(ttl)
+		entry.timer = time.AfterFunc(ttl, func() { r.expire(name, entry) })
+	}
+
+	r.store(name, entry)
+	return previousLevel, nil
+}
+
+// Apply sets the levels of "logger=level" assignments, such as "access=warn" or
+// "route:/health=error", without a TTL
+func (r *LevelRegistry) Apply(assignments []string) *AppError {
+	for _, assignment := range assignments {
+		name, value, found := strings.Cut(assignment, "=")
+		if !found {
+			return NewAppError(ValidationError, "invalid log level assignment, expected logger=level", nil).
+				WithCode("validation.log_level").
+				AddContext(ContextLogLevel, assignment)
+		}
+
+		level, appErr := ParseLevel(value)
+		if appErr != nil {
+			return appErr
+		}
+		if _, appErr := r.Set(strings.TrimSpace(name), level, 0); appErr != nil {
+			return appErr
+		}
+	}
+	return nil
+}
+
+// Reset removes the override of a logger, or restores the default level set at start
+// for the default logger when a temporary change is active
+func (r *LevelRegistry) Reset(name string) *AppError {
+	if appErr := validateLogger(name); appErr != nil {
+		return appErr
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	entry := r.entry(name)
+	if entry == nil {
+		return nil
+	}
+	if entry.timer != nil {
+		entry.timer.Stop()
+	}
+
+	if name == DefaultLogger {
+		if entry.previous != nil {
+			r.defaultLevel.Set(*entry.previous)
+		}
+		r.defaults = nil
+		return nil
+	}
+
+	delete(r.overrides, name)
+	return nil
+}
+
+// expire reverts a temporary level, unless it was replaced in the meantime
+func (r *LevelRegistry) expire(name string, expired *levelEntry) {
+	r.mu.Lock()
+
+	if r.entry(name) != expired {
+		r.mu.Unlock()
+		return
+	}
+
+	if name == DefaultLogger {
+		if expired.previous != nil {
+			r.defaultLevel.Set(*expired.previous)
+		}
+		r.defaults = nil
+	} else if expired.previous != nil {
+		r.overrides[name] = &levelEntry{level: *expired.previous}
+	} else {
+		delete(r.overrides, name)
+	}
+	level := r.levelLocked(name)
+
+	// logging checks the registry levels, so it happens once the lock is released
+	r.mu.Unlock()
+	ComponentLogger(ComponentServer).Info("log_level_reverted", "logger", name, "level", level.String())
+}
+
+// entry returns the current entry of a logger, the caller must hold the lock
+func (r *LevelRegistry) entry(name string) *levelEntry {
+	if name == DefaultLogger {
+		return r.defaults
+	}
+	return r.overrides[name]
+}
+
+// store saves the entry of a logger, the caller must hold the lock
+func (r *LevelRegistry) store(name string, entry *levelEntry) {
+	if name == DefaultLogger {
+		r.defaultLevel.Set(entry.level)
+		if entry.timer != nil {
+			r.defaults = entry
+		} else {
+			r.defaults = nil
+		}
+		return
+	}
+	r.overrides[name] = entry
+}
+
+// levelLocked returns the configured level of a logger, the caller must hold the lock
+func (r *LevelRegistry) levelLocked(name string) slog.Level {
+	if entry, ok := r.overrides[name]; ok && name != DefaultLogger {
+		return entry.level
+	}
+	return r.defaultLevel.Level()
+}
+
+// Level returns the effective level for a component and route, a route level takes
+// precedence over a component level, which takes precedence over the default
+func (r *LevelRegistry) Level(component, route string) slog.Level {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if len(r.overrides) > 0 {
+		if route != "" {
+			if entry, ok := r.overrides[RouteLoggerPrefix+route]; ok {
+				return entry.level
+			}
+		}
+		if component != "" {
+			if entry, ok := r.overrides[component]; ok {
+				return entry.level
+			}
+		}
+	}
+	return r.defaultLevel.Level()
+}
+
+// Levels lists the default level and all overrides
+func (r *LevelRegistry) Levels() []LoggerLevel {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	levels := []LoggerLevel{newLoggerLevel(DefaultLogger, r.defaultLevel.Level(), r.defaults)}
+	for _, name := range slices.Sorted(maps.Keys(r.overrides)) {
+		entry := r.overrides[name]
+		levels = append(levels, newLoggerLevel(name, entry.level, entry))
+	}
+	return levels
+}
+
+// LoggerLevel returns the configured level of a logger, the default level for loggers without an override
+func (r *LevelRegistry) LoggerLevel(name string) LoggerLevel {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return newLoggerLevel(name, r.levelLocked(name), r.entry(name))
+}
+
+func newLoggerLevel(name string, level slog.Level, entry *levelEntry) LoggerLevel {
+	loggerLevel := LoggerLevel{Logger: name, Level: level.String()}
+	if entry != nil && entry.timer != nil {
+		expiresAt := entry.expiresAt
+		loggerLevel.ExpiresAt = &expiresAt
+	}
+	return loggerLevel
+}
+
+// Handler wraps inner so records are filtered by the registry levels. The inner handler
+// should let all levels through, see MinLogLevel.
+func (r *LevelRegistry) Handler(inner slog.Handler) slog.Handler {
+	return &levelHandler{inner: inner, registry: r}
+}
+
+// levelHandler filters records by the level of the logger's component and the request route
+type levelHandler struct {
+	inner     slog.Handler
+	registry  *LevelRegistry
+	component string
+}
+
+func (h *levelHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	route := ""
+	if ctx != nil {
+		_, route = ErrorScopeFromContext(ctx).labels()
+	}
+	return level >= h.registry.Level(h.component, route) && h.inner.Enabled(ctx, level)
+}
+
+func (h *levelHandler) Handle(ctx context.Context, record slog.Record) error {
+	return h.inner.Handle(ctx, record)
+}
+
+func (h *levelHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	component := h.component
+	for _, attr := range attrs {
+		if attr.Key == ComponentKey {
+			component = attr.Value.String()
+		}
+	}
+	return &levelHandler{inner: h.inner.WithAttrs(attrs), registry: h.registry, component: component}
+}
+
+func (h *levelHandler) WithGroup(name string) slog.Handler {
+	return &levelHandler{inner: h.inner.WithGroup(name), registry: h.registry, component: h.component}
+}
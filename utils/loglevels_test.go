@@ -0,0 +1,126 @@
+package utils
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestRegistry(level slog.Level) *LevelRegistry {
+	defaultLevel := new(slog.LevelVar)
+	defaultLevel.Set(level)
+	return NewLevelRegistry(defaultLevel)
+}
+
+func TestParseLevel(t *testing.T) {
+	level, appErr := ParseLevel("debug")
+	require.Nil(t, appErr)
+	assert.Equal(t, slog.LevelDebug, level)
+
+	level, appErr = ParseLevel(" WARN ")
+	require.Nil(t, appErr)
+	assert.Equal(t, slog.LevelWarn, level)
+
+	_, appErr = ParseLevel("verbose")
+	require.NotNil(t, appErr)
+	assert.True(t, IsValidationError(appErr))
+	assert.Equal(t, ErrorCode("validation.log_level"), appErr.Code)
+}
+
+func TestLevelRegistryPrecedence(t *testing.T) {
+	registry := newTestRegistry(slog.LevelInfo)
+
+	previous, appErr := registry.Set(ComponentAccess, slog.LevelWarn, 0)
+	require.Nil(t, appErr)
+	assert.Equal(t, slog.LevelInfo, previous)
+
+	_, appErr = registry.Set(RouteLogger("/health"), slog.LevelError, 0)
+	require.Nil(t, appErr)
+
+	assert.Equal(t, slog.LevelInfo, registry.Level("", ""))
+	assert.Equal(t, slog.LevelInfo, registry.Level(ComponentServer, "/"))
+	assert.Equal(t, slog.LevelWarn, registry.Level(ComponentAccess, "/"))
+	assert.Equal(t, slog.LevelError, registry.Level(ComponentAccess, "/health"))
+
+	_, appErr = registry.Set("database", slog.LevelDebug, 0)
+	assert.NotNil(t, appErr, "Expected an error for an unknown logger")
+
+	require.Nil(t, registry.Reset(ComponentAccess))
+	assert.Equal(t, slog.LevelInfo, registry.Level(ComponentAccess, "/"))
+
+	levels := registry.Levels()
+	require.Len(t, levels, 2)
+	assert.Equal(t, LoggerLevel{Logger: DefaultLogger, Level: "INFO"}, levels[0])
+	assert.Equal(t, LoggerLevel{Logger: "route:/health", Level: "ERROR"}, levels[1])
+}
+
+func TestLevelRegistryTTL(t *testing.T) {
+	registry := newTestRegistry(slog.LevelInfo)
+
+	_, appErr := registry.Set(DefaultLogger, slog.LevelDebug, 20*time.Millisecond)
+	require.Nil(t, appErr)
+	_, appErr = registry.Set(ComponentHandlers, slog.LevelDebug, 20*time.Millisecond)
+	require.Nil(t, appErr)
+
+	assert.Equal(t, slog.LevelDebug, registry.DefaultLevel().Level())
+	require.NotNil(t, registry.LoggerLevel(ComponentHandlers).ExpiresAt)
+
+	assert.Eventually(t, func() bool {
+		return registry.DefaultLevel().Level() == slog.LevelInfo && len(registry.Levels()) == 1
+	}, time.Second, 5*time.Millisecond)
+
+	t.Run("Temporary change of an override", func(t *testing.T) {
+		require.Nil(t, registry.Apply([]string{"errors=warn"}))
+
+		_, appErr := registry.Set(ComponentErrors, slog.LevelDebug, 10*time.Millisecond)
+		require.Nil(t, appErr)
+		_, appErr = registry.Set(ComponentErrors, slog.LevelInfo, 10*time.Millisecond)
+		require.Nil(t, appErr)
+
+		assert.Eventually(t, func() bool {
+			return registry.Level(ComponentErrors, "") == slog.LevelWarn && registry.LoggerLevel(ComponentErrors).ExpiresAt == nil
+		}, time.Second, 5*time.Millisecond)
+	})
+}
+
+func TestLevelRegistryApply(t *testing.T) {
+	registry := newTestRegistry(slog.LevelInfo)
+
+	require.Nil(t, registry.Apply([]string{"access=warn", "route:/health=error", "default=debug"}))
+	assert.Equal(t, slog.LevelWarn, registry.Level(ComponentAccess, ""))
+	assert.Equal(t, slog.LevelError, registry.Level("", "/health"))
+	assert.Equal(t, slog.LevelDebug, registry.Level("", ""))
+
+	assert.NotNil(t, registry.Apply([]string{"access"}))
+	assert.NotNil(t, registry.Apply([]string{"access=loud"}))
+	assert.NotNil(t, registry.Apply([]string{"route:health=info"}))
+}
+
+func TestLevelHandler(t *testing.T) {
+	registry := newTestRegistry(slog.LevelInfo)
+	require.Nil(t, registry.Apply([]string{"access=warn", "route:/debug=debug"}))
+
+	var buf bytes.Buffer
+	logger := slog.New(registry.Handler(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: MinLogLevel})))
+	access := logger.With(ComponentKey, ComponentAccess)
+
+	logger.Debug("default debug")
+	logger.Info("default info")
+	access.Info("access info")
+	access.Warn("access warn")
+
+	ctx := WithErrorScope(context.Background(), ErrorScope{Route: func() string { return "/debug" }})
+	access.DebugContext(ctx, "route debug")
+
+	output := buf.String()
+	assert.NotContains(t, output, "default debug")
+	assert.Contains(t, output, "default info")
+	assert.NotContains(t, output, "access info")
+	assert.Contains(t, output, "access warn")
+	assert.Contains(t, output, "route debug")
+}
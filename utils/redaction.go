@@ -0,0 +1,299 @@
+package utils
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"regexp"
+	"slices"
+	"strings"
+	"sync/atomic"
+)
+
+// RedactedValue replaces redacted values in mask mode
+const RedactedValue = "[REDACTED]"
+
+// RedactionMode selects how sensitive values are redacted
+type RedactionMode string
+
+const (
+	// RedactionMask replaces values with RedactedValue
+	RedactionMask RedactionMode = "mask"
+
+	// RedactionHash replaces values with a keyed hash, so equal values can be correlated
+	RedactionHash RedactionMode = "hash"
+
+	// RedactionDrop removes sensitive headers, query parameters and fields
+	RedactionDrop RedactionMode = "drop"
+)
+
+// RedactionModes lists the supported redaction modes
+func RedactionModes() []RedactionMode {
+	return []RedactionMode{RedactionMask, RedactionHash, RedactionDrop}
+}
+
+// RedactionConfig describes the sensitive values redacted from responses, logs,
+// error context and trace attributes
+type RedactionConfig struct {
+	// Headers are sensitive header names, matched case-insensitively
+	Headers []string
+
+	// HeaderPatterns are regular expressions matched against lowercase header names
+	HeaderPatterns []string
+
+	// QueryParams are sensitive query parameter names, matched case-insensitively
+	QueryParams []string
+
+	// Fields are sensitive JSON body and error context keys, matched case-insensitively
+	Fields []string
+
+	Mode RedactionMode
+
+	// HashKey keys the HMAC of the hash mode and is required by it, so hashes can't be
+	// reversed by guessing values; instances sharing the key produce the same hashes
+	HashKey string
+}
+
+// Redactor applies a redaction policy. A nil redactor redacts nothing.
+type Redactor struct {
+	headers  map[string]bool
+	patterns []*regexp.Regexp
+	query    map[string]bool
+	fields   map[string]bool
+	mode     RedactionMode
+	key      []byte
+}
+
+// NewRedactor validates the configuration and creates the redactor
+func NewRedactor(config RedactionConfig) (*Redactor, *AppError) {
+	if config.Mode == "" {
+		config.Mode = RedactionMask
+	}
+	if !slices.Contains(RedactionModes(), config.Mode) {
+		return nil, NewAppError(ValidationError, "unknown redaction mode", nil).
+			WithCode("validation.redaction_mode").
+			AddContext(ContextMode, string(config.Mode))
+	}
+	if config.Mode == RedactionHash && config.HashKey == "" {
+		return nil, NewAppError(ValidationError, "the hash redaction mode requires a hash key", nil).
+			WithCode("validation.redaction_hash_key").
+			AddContext(ContextMode, string(config.Mode))
+	}
+
+	r := &Redactor{
+		headers: lowerSet(config.Headers),
+		query:   lowerSet(config.QueryParams),
+		fields:  lowerSet(config.Fields),
+		mode:    config.Mode,
+		key:     []byte(config.HashKey),
+	}
+
+	for _, pattern := range config.HeaderPatterns {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, WrapError(err, ValidationError, "invalid redaction header pattern").
+				WithCode("validation.redaction_pattern").
+				AddContext("pattern", pattern)
+		}
+		r.patterns = append(r.patterns, re)
+	}
+
+	return r, nil
+}
+
+func lowerSet(values []string) map[string]bool {
+	set := make(map[string]bool, len(values))
+	for _, value := range values {
+		set[strings.ToLower(value)] = true
+	}
+	return set
+}
+
+// Mode returns the redaction mode
+func (r *Redactor) Mode() RedactionMode {
+	if r == nil {
+		return RedactionMask
+	}
+	return r.mode
+}
+
+// SensitiveHeader reports whether the header is redacted
+func (r *Redactor) SensitiveHeader(name string) bool {
+	if r == nil {
+		return false
+	}
+
+	name = strings.ToLower(name)
+	if r.headers[name] {
+		return true
+	}
+	return slices.ContainsFunc(r.patterns, func(re *regexp.Regexp) bool {
+		return re.MatchString(name)
+	})
+}
+
+// SensitiveQueryParam reports whether the query parameter is redacted
+func (r *Redactor) SensitiveQueryParam(name string) bool {
+	return r != nil && r.query[strings.ToLower(name)]
+}
+
+// SensitiveField reports whether a JSON body or error context key is redacted. Sensitive
+// headers and query parameters are sensitive fields too, as they are often copied into
+// the error context.
+func (r *Redactor) SensitiveField(name string) bool {
+	return r != nil && (r.fields[strings.ToLower(name)] || r.SensitiveHeader(name) || r.SensitiveQueryParam(name))
+}
+
+// Value redacts a sensitive value, in drop mode it returns an empty string and callers
+// omit the value
+func (r *Redactor) Value(value string) string {
+	switch r.Mode() {
+	case RedactionHash:
+		mac := hmac.New(sha256.New, r.key)
+		mac.Write([]byte(value))
+		return "sha256:" + hex.EncodeToString(mac.Sum(nil))[:16]
+	case RedactionDrop:
+		return ""
+	default:
+		return RedactedValue
+	}
+}
+
+// Headers returns a copy of headers with the sensitive values redacted
+func (r *Redactor) Headers(headers http.Header) http.Header {
+	if r == nil || headers == nil {
+		return headers
+	}
+
+	redacted := headers.Clone()
+	for name, values := range redacted {
+		if !r.SensitiveHeader(name) {
+			continue
+		}
+		if r.mode == RedactionDrop {
+			delete(redacted, name)
+			continue
+		}
+		for i, value := range values {
+			values[i] = r.Value(value)
+		}
+	}
+	return redacted
+}
+
+// Query returns the raw query with the sensitive parameter values redacted
+func (r *Redactor) Query(rawQuery string) string {
+	if r == nil || len(r.query) == 0 || rawQuery == "" {
+		return rawQuery
+	}
+
+	values, err := url.ParseQuery(rawQuery)
+	if err != nil {
+		// a query which can't be parsed is redacted as a whole
+		return r.Value(rawQuery)
+	}
+
+	changed := false
+	for name, params := range values {
+		if !r.SensitiveQueryParam(name) {
+			continue
+		}
+		changed = true
+		if r.mode == RedactionDrop {
+			delete(values, name)
+			continue
+		}
+		for i, value := range params {
+			params[i] = r.Value(value)
+		}
+	}
+	if !changed {
+		return rawQuery
+	}
+	return values.Encode()
+}
+
+// URL returns a copy of u with the sensitive query parameter values redacted
+func (r *Redactor) URL(u *url.URL) *url.URL {
+	if r == nil || u == nil {
+		return u
+	}
+
+	redacted := *u
+	redacted.RawQuery = r.Query(u.RawQuery)
+	if redacted.User != nil {
+		redacted.User = url.User(redacted.User.Username())
+	}
+	return &redacted
+}
+
+// RequestURI redacts the query of a request URI
+func (r *Redactor) RequestURI(requestURI string) string {
+	path, rawQuery, found := strings.Cut(requestURI, "?")
+	if !found {
+		return requestURI
+	}
+	return path + "?" + r.Query(rawQuery)
+}
+
+// Fields returns a copy of fields with the values of sensitive keys redacted, nested
+// maps and slices included
+func (r *Redactor) Fields(fields map[string]interface{}) map[string]interface{} {
+	if r == nil || fields == nil {
+		return fields
+	}
+
+	redacted := make(map[string]interface{}, len(fields))
+	for key, value := range fields {
+		if !r.SensitiveField(key) {
+			redacted[key] = r.JSONValue(value)
+		} else if r.mode != RedactionDrop {
+			redacted[key] = r.Value(stringValue(value))
+		}
+	}
+	return redacted
+}
+
+// JSONValue redacts the sensitive keys of decoded JSON
+func (r *Redactor) JSONValue(value any) any {
+	switch v := value.(type) {
+	case map[string]any:
+		return r.Fields(v)
+	case []any:
+		redacted := make([]any, len(v))
+		for i, nested := range v {
+			redacted[i] = r.JSONValue(nested)
+		}
+		return redacted
+	}
+	return value
+}
+
+// stringValue returns the text hashed for a redacted value
+func stringValue(value any) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case error:
+		return v.Error()
+	}
+	b, _ := json.Marshal(value)
+	return string(b)
+}
+
+// defaultRedactor redacts the error context in logs and JSON
+var defaultRedactor atomic.Pointer[Redactor]
+
+// SetDefaultRedactor sets the redactor applied to the AppError context when errors are
+// logged or marshaled
+func SetDefaultRedactor(r *Redactor) {
+	defaultRedactor.Store(r)
+}
+
+// DefaultRedactor returns the redactor set with SetDefaultRedactor, nil redacts nothing
+func DefaultRedactor() *Redactor {
+	return defaultRedactor.Load()
+}
@@ -0,0 +1,55 @@
+package utils
+
+import (
+	"context"
+	"log/slog"
+)
+
+// RequestIDKey is the log attribute and error context key of the request ID
+const RequestIDKey = "request_id"
+
+type requestIDKey struct{}
+
+// WithRequestID returns a copy of ctx carrying the request ID
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, id)
+}
+
+// RequestIDFromContext returns the request ID of ctx, or an empty string
+func RequestIDFromContext(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+// RequestIDHandler returns a slog handler adding the request ID of the context to the
+// records logged with one
+func RequestIDHandler(inner slog.Handler) slog.Handler {
+	return &requestIDHandler{inner: inner}
+}
+
+type requestIDHandler struct {
+	inner slog.Handler
+}
+
+func (h *requestIDHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.inner.Enabled(ctx, level)
+}
+
+func (h *requestIDHandler) Handle(ctx context.Context, record slog.Record) error {
+	if id := RequestIDFromContext(ctx); id != "" {
+		record = record.Clone()
+		record.AddAttrs(slog.String(RequestIDKey, id))
+	}
+	return h.inner.Handle(ctx, record)
+}
+
+func (h *requestIDHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &requestIDHandler{inner: h.inner.WithAttrs(attrs)}
+}
+
+func (h *requestIDHandler) WithGroup(name string) slog.Handler {
+	return &requestIDHandler{inner: h.inner.WithGroup(name)}
+}
@@ -0,0 +1,29 @@
+package utils
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRequestIDHandler(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(RequestIDHandler(slog.NewTextHandler(&buf, nil)))
+
+	logger.InfoContext(WithRequestID(context.Background(), "abc-123"), "with id")
+	assert.Contains(t, buf.String(), "request_id=abc-123")
+
+	buf.Reset()
+	logger.With("component", "access").InfoContext(context.Background(), "without id")
+	assert.NotContains(t, buf.String(), RequestIDKey)
+}
+
+func TestLogErrorRequestID(t *testing.T) {
+	appErr := NewAppError(ValidationError, "invalid", nil)
+	appErr.LogError(WithRequestID(context.Background(), "abc-123"))
+
+	assert.Equal(t, "abc-123", appErr.Context()[RequestIDKey])
+}
@@ -4,6 +4,7 @@ import (
 	"crypto/rand"
 	"encoding/hex"
 	"os"
+	"strings"
 )
 
 func GetAppName() string {
@@ -26,3 +27,14 @@ func GenerateKey() (string, error) {
 	return hex.EncodeToString(bytes), nil //encode key in bytes to string for saving
 
 }
+
+// SplitList splits a comma separated flag value, trimming spaces and dropping empty items
+func SplitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
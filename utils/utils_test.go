@@ -35,3 +35,8 @@ func TestGenerateKey(t *testing.T) {
 		assert.Len(t, decoded, 32, "Expected key length of 32 bytes")
 	})
 }
+
+func TestSplitList(t *testing.T) {
+	assert.Nil(t, SplitList(""), "Expected no items for an empty value")
+	assert.Equal(t, []string{"a", "b"}, SplitList(" a, ,b ,"), "Expected trimmed, non-empty items")
+}
@@ -0,0 +1,119 @@
+package utils
+
+import (
+	"fmt"
+	"io"
+	"runtime"
+	"runtime/debug"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// Build metadata, set at build time with
+// -ldflags "-X github.com/wasilak/go-hello-world/utils.Version=v1.9.0 -X ...utils.Commit=... -X ...utils.BuildDate=..."
+// The commit and build date fall back to the VCS information Go embeds in the binary.
+var (
+	Version   = "dev"
+	Commit    = ""
+	BuildDate = ""
+)
+
+// BuildInfo describes the running binary
+type BuildInfo struct {
+	Version      string            `json:"version"`
+	Commit       string            `json:"commit,omitempty"`
+	BuildDate    string            `json:"build_date,omitempty"`
+	Modified     bool              `json:"modified,omitempty"`
+	GoVersion    string            `json:"go_version"`
+	Platform     string            `json:"platform"`
+	Dependencies map[string]string `json:"dependencies,omitempty"`
+}
+
+// GetBuildInfo returns the build metadata, read once from the linker variables and
+// runtime/debug.ReadBuildInfo
+var GetBuildInfo = sync.OnceValue(func() BuildInfo {
+	info := BuildInfo{
+		Version:   Version,
+		Commit:    Commit,
+		BuildDate: BuildDate,
+		GoVersion: runtime.Version(),
+		Platform:  runtime.GOOS + "/" + runtime.GOARCH,
+	}
+
+	build, ok := debug.ReadBuildInfo()
+	if !ok {
+		return info
+	}
+
+	if info.Version == "dev" && build.Main.Version != "" && build.Main.Version != "(devel)" {
+		info.Version = build.Main.Version
+	}
+	// the modified flag describes the VCS revision, not a commit set at build time
+	vcsCommit := info.Commit == ""
+	for _, setting := range build.Settings {
+		switch setting.Key {
+		case "vcs.revision":
+			if vcsCommit {
+				info.Commit = setting.Value
+			}
+		case "vcs.time":
+			if info.BuildDate == "" {
+				info.BuildDate = setting.Value
+			}
+		case "vcs.modified":
+			info.Modified = vcsCommit && setting.Value == "true"
+		}
+	}
+
+	if len(build.Deps) > 0 {
+		info.Dependencies = make(map[string]string, len(build.Deps))
+		for _, dep := range build.Deps {
+			if dep.Replace != nil {
+				dep = dep.Replace
+			}
+			info.Dependencies[dep.Path] = dep.Version
+		}
+	}
+	return info
+})
+
+// ShortCommit returns the first 12 characters of the commit
+func (b BuildInfo) ShortCommit() string {
+	if len(b.Commit) > 12 {
+		return b.Commit[:12]
+	}
+	return b.Commit
+}
+
+// String returns a one line summary, e.g. "v1.9.0 (commit 0123456789ab, built 2026-01-02T03:04:05Z, go1.25.0 linux/amd64)"
+func (b BuildInfo) String() string {
+	details := []string{}
+	if b.Commit != "" {
+		commit := "commit " + b.ShortCommit()
+		if b.Modified {
+			commit += "-dirty"
+		}
+		details = append(details, commit)
+	}
+	if b.BuildDate != "" {
+		details = append(details, "built "+b.BuildDate)
+	}
+	details = append(details, b.GoVersion+" "+b.Platform)
+
+	return fmt.Sprintf("%s (%s)", b.Version, strings.Join(details, ", "))
+}
+
+// WriteVersion writes the summary of the binary followed by its dependencies, one per line
+func (b BuildInfo) WriteVersion(w io.Writer, name string) {
+	fmt.Fprintf(w, "%s %s\n", name, b)
+
+	paths := make([]string, 0, len(b.Dependencies))
+	for path := range b.Dependencies {
+		paths = append(paths, path)
+	}
+	slices.Sort(paths)
+	for _, path := range paths {
+		fmt.Fprintf(w, "\tdep\t%s\t%s\n", path, b.Dependencies[path])
+	}
+}
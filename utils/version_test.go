@@ -0,0 +1,49 @@
+package utils
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBuildInfo(t *testing.T) {
+	t.Run("String", func(t *testing.T) {
+		info := BuildInfo{
+			Version:   "v1.9.0",
+			Commit:    "0123456789abcdef0123",
+			BuildDate: "2026-01-02T03:04:05Z",
+			Modified:  true,
+			GoVersion: "go1.26.0",
+			Platform:  "linux/amd64",
+		}
+		assert.Equal(t, "v1.9.0 (commit 0123456789ab-dirty, built 2026-01-02T03:04:05Z, go1.26.0 linux/amd64)", info.String())
+	})
+
+	t.Run("Without VCS information", func(t *testing.T) {
+		info := BuildInfo{Version: "dev", GoVersion: "go1.26.0", Platform: "darwin/arm64"}
+		assert.Equal(t, "dev (go1.26.0 darwin/arm64)", info.String())
+	})
+
+	t.Run("WriteVersion lists sorted dependencies", func(t *testing.T) {
+		info := BuildInfo{
+			Version:      "dev",
+			GoVersion:    "go1.26.0",
+			Platform:     "linux/amd64",
+			Dependencies: map[string]string{"github.com/gorilla/mux": "v1.8.1", "github.com/go-chi/chi/v5": "v5.2.0"},
+		}
+
+		var out bytes.Buffer
+		info.WriteVersion(&out, "go-hello-world")
+		assert.Equal(t, "go-hello-world dev (go1.26.0 linux/amd64)\n"+
+			"\tdep\tgithub.com/go-chi/chi/v5\tv5.2.0\n"+
+			"\tdep\tgithub.com/gorilla/mux\tv1.8.1\n", out.String())
+	})
+
+	t.Run("GetBuildInfo", func(t *testing.T) {
+		info := GetBuildInfo()
+		assert.NotEmpty(t, info.Version)
+		assert.NotEmpty(t, info.GoVersion)
+		assert.NotEmpty(t, info.Platform)
+	})
+}
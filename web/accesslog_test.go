@@ -0,0 +1,84 @@
+package web
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/wasilak/go-hello-world/web/common"
+)
+
+// syncBuffer is a bytes.Buffer safe for the server goroutines writing logs
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+// accessEntries returns the decoded access log entries written so far
+func (b *syncBuffer) accessEntries(t *testing.T) []map[string]any {
+	t.Helper()
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	var entries []map[string]any
+	for _, line := range strings.Split(b.buf.String(), "\n") {
+		var entry map[string]any
+		if json.Unmarshal([]byte(line), &entry) == nil && entry["component"] == "access" {
+			entries = append(entries, entry)
+		}
+	}
+	return entries
+}
+
+func TestAccessLogPerFramework(t *testing.T) {
+	for _, framework := range testFrameworks {
+		t.Run(framework, func(t *testing.T) {
+			buf := &syncBuffer{}
+			previous := slog.Default()
+			slog.SetDefault(slog.New(slog.NewJSONHandler(buf, nil)))
+			t.Cleanup(func() { slog.SetDefault(previous) })
+
+			options := testOptions(t)
+			options.AccessLog = common.NewAccessLogger(common.AccessLogConfig{
+				Fields:       []string{common.AccessFieldMethod, common.AccessFieldPath, common.AccessFieldRoute, common.AccessFieldStatus, common.AccessFieldResponseBody},
+				ExcludePaths: []string{"/health"},
+				SampleRate:   1,
+				BodyLimit:    1024,
+			})
+			baseURL := startTestServer(t, context.Background(), framework, options)
+
+			resp, err := http.Get(baseURL + "/chain?mode=random")
+			require.NoError(t, err)
+			resp.Body.Close()
+
+			var entries []map[string]any
+			require.Eventually(t, func() bool {
+				entries = buf.accessEntries(t)
+				return len(entries) > 0
+			}, time.Second, 10*time.Millisecond)
+
+			require.Len(t, entries, 1, "Expected /health requests to be excluded")
+			entry := entries[0]
+			assert.Equal(t, "GET", entry["method"])
+			assert.Equal(t, "/chain", entry["path"])
+			assert.Equal(t, "/chain", entry["route"])
+			assert.Equal(t, float64(http.StatusBadRequest), entry["status"])
+			assert.Contains(t, entry["response_body"], "validation.chain_mode")
+		})
+	}
+}
@@ -0,0 +1,114 @@
+package admin
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/wasilak/go-hello-world/utils"
+)
+
+type principalKey struct{}
+
+// authenticate requires a valid bearer token when one is configured; with mTLS the
+// client certificate is already verified during the handshake
+func (s *Server) authenticate(next http.Handler) http.Handler {
+	token := s.Options.Admin.Token
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var principals []string
+
+		if r.TLS != nil && len(r.TLS.PeerCertificates) > 0 {
+			principals = append(principals, "cert:"+r.TLS.PeerCertificates[0].Subject.CommonName)
+		}
+
+		if token != "" {
+			if !validToken(r.Header.Get("Authorization"), token) {
+				appErr := utils.NewAppError(utils.UnauthorizedError, "missing or invalid bearer token", nil).
+					WithCode("auth.invalid_token").
+					WithSeverity(utils.SeverityWarning)
+				appErr.AddContext(utils.ContextPath, r.URL.Path)
+				appErr.AddContext("remote_addr", r.RemoteAddr)
+				appErr.LogError(r.Context())
+
+				w.Header().Set("WWW-Authenticate", `Bearer realm="admin"`)
+				s.Options.Problems.Write(w, r, appErr)
+				return
+			}
+			principals = append(principals, "bearer")
+		}
+
+		ctx := context.WithValue(r.Context(), principalKey{}, strings.Join(principals, ","))
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// dashboardCORS lets the dashboard served on the main listener call the admin API. Only
+// origins on the host the admin API is reached on are allowed, the port differs. Preflight
+// requests carry no credentials and are answered before authentication.
+func dashboardCORS(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		origin := r.Header.Get("Origin")
+		if origin == "" || !sameHost(origin, r.Host) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		w.Header().Set("Access-Control-Allow-Origin", origin)
+		w.Header().Add("Vary", "Origin")
+
+		if r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != "" {
+			w.Header().Set("Access-Control-Allow-Methods", "GET, PUT, DELETE")
+			w.Header().Set("Access-Control-Allow-Headers", "Authorization, Content-Type")
+			w.Header().Set("Access-Control-Max-Age", "600")
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// sameHost reports whether origin is on the host of hostport, ignoring the ports
+func sameHost(origin, hostport string) bool {
+	originURL, err := url.Parse(origin)
+	if err != nil || originURL.Hostname() == "" {
+		return false
+	}
+
+	host := hostport
+	if h, _, err := net.SplitHostPort(hostport); err == nil {
+		host = h
+	}
+	return strings.EqualFold(originURL.Hostname(), strings.Trim(host, "[]"))
+}
+
+// validToken compares the bearer token in constant time
+func validToken(header, token string) bool {
+	scheme, value, found := strings.Cut(header, " ")
+	if !found || !strings.EqualFold(scheme, "Bearer") {
+		return false
+	}
+
+	// hash both sides so the comparison does not leak the token length
+	got := sha256.Sum256([]byte(strings.TrimSpace(value)))
+	want := sha256.Sum256([]byte(token))
+	return subtle.ConstantTimeCompare(got[:], want[:]) == 1
+}
+
+// audit logs a state change made through the admin API
+func audit(r *http.Request, action string, attrs ...any) {
+	principal, _ := r.Context().Value(principalKey{}).(string)
+
+	attrs = append([]any{
+		"action", action,
+		"principal", principal,
+		"remote_addr", r.RemoteAddr,
+	}, attrs...)
+
+	slog.InfoContext(r.Context(), "admin_audit", attrs...)
+}
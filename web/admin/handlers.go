@@ -0,0 +1,286 @@
+package admin
+
+import (
+	"encoding/json"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/wasilak/go-hello-world/utils"
+	"github.com/wasilak/go-hello-world/web/common"
+)
+
+// maxRequestBytes bounds admin request bodies
+const maxRequestBytes = 64 << 10
+
+// StatusResponse type
+type StatusResponse struct {
+	Framework string              `json:"framework"`
+	LogLevel  string              `json:"log_level"`
+	Ready     bool                `json:"ready"`
+	Faults    *common.FaultConfig `json:"faults"`
+}
+
+// ConfigResponse is the effective server configuration of the running framework
+type ConfigResponse struct {
+	Framework  string                    `json:"framework"`
+	ListenAddr string                    `json:"listen_addr"`
+	Server     common.ServerConfigReport `json:"server"`
+}
+
+// ReadyResponse type
+type ReadyResponse struct {
+	Ready bool `json:"ready"`
+}
+
+func (s *Server) statusHandler(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, StatusResponse{
+		Framework: s.Options.Runtime.Framework(),
+		LogLevel:  s.Options.LogLevelConfig.Level().String(),
+		Ready:     s.Options.Runtime.Ready(),
+		Faults:    s.Options.Runtime.Faults(),
+	})
+}
+
+func (s *Server) configHandler(w http.ResponseWriter, r *http.Request) {
+	framework := s.Options.Runtime.Framework()
+
+	writeJSON(w, http.StatusOK, ConfigResponse{
+		Framework:  framework,
+		ListenAddr: s.Options.ListenAddr,
+		Server:     s.Options.Server.Report(framework),
+	})
+}
+
+func (s *Server) logLevelHandler(w http.ResponseWriter, r *http.Request) {
+	var req common.LogLevelRequest
+	if !s.decode(w, r, &req) {
+		return
+	}
+
+	if strings.TrimSpace(req.Level) == "" {
+		s.fail(w, r, utils.NewAppError(utils.ValidationError, "level is required", nil).WithCode("validation.log_level"))
+		return
+	}
+
+	response, appErr := common.SetLogLevel(s.Options.LevelRegistry(), req)
+	if appErr != nil {
+		s.fail(w, r, appErr)
+		return
+	}
+
+	audit(r, "log_level", "logger", response.Logger, "from", response.LogLevelPrevious, "to", response.LogLevelCurrent, "ttl", req.TTL)
+	writeJSON(w, http.StatusOK, response)
+}
+
+func (s *Server) logLevelsHandler(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, s.Options.LevelRegistry().Levels())
+}
+
+func (s *Server) resetLogLevelHandler(w http.ResponseWriter, r *http.Request) {
+	logger := r.URL.Query().Get("logger")
+
+	if appErr := s.Options.LevelRegistry().Reset(logger); appErr != nil {
+		s.fail(w, r, appErr)
+		return
+	}
+
+	audit(r, "log_level_reset", "logger", logger)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) frameworkHandler(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Framework string `json:"framework"`
+	}
+	if !s.decode(w, r, &req) {
+		return
+	}
+
+	framework := strings.ToLower(strings.TrimSpace(req.Framework))
+	if !slices.Contains(common.SupportedFrameworks(), framework) {
+		s.fail(w, r, utils.NewAppError(utils.ValidationError, "unsupported framework", nil).WithCode("validation.framework").AddContext(utils.ContextFramework, req.Framework))
+		return
+	}
+
+	s.switchMU.Lock()
+	defer s.switchMU.Unlock()
+
+	response := common.FrameworkResponse{
+		FrameworkPrevious: s.Options.Runtime.Framework(),
+	}
+	if response.FrameworkPrevious != framework {
+		audit(r, "framework", "from", response.FrameworkPrevious, "to", framework)
+		if err := s.SwitchFramework(r.Context(), framework); err != nil {
+			s.fail(w, r, switchError(err, framework, s.Options.Runtime.Framework()))
+			return
+		}
+	}
+	response.FrameworkCurrent = s.Options.Runtime.Framework()
+
+	writeJSON(w, http.StatusOK, response)
+}
+
+// switchError describes a failed switch to framework with the framework running after it,
+// the previous one when the switch was rolled back or none
+func switchError(err error, framework, running string) *utils.AppError {
+	return utils.WrapError(err, utils.FrameworkError, "framework switch failed").
+		WithCode("framework.switch_failed").
+		AddContext(utils.ContextFramework, framework).
+		AddContext(utils.ContextRunningFramework, running)
+}
+
+func (s *Server) setFaultsHandler(w http.ResponseWriter, r *http.Request) {
+	var faults common.FaultConfig
+	if !s.decode(w, r, &faults) {
+		return
+	}
+
+	if appErr := faults.Validate(); appErr != nil {
+		s.fail(w, r, appErr)
+		return
+	}
+
+	s.Options.Runtime.SetFaults(&faults)
+
+	audit(r, "faults", "latency_ms", faults.LatencyMS, "error_rate", faults.ErrorRate, "status_code", faults.StatusCode, "paths", faults.Paths)
+	writeJSON(w, http.StatusOK, faults)
+}
+
+func (s *Server) clearFaultsHandler(w http.ResponseWriter, r *http.Request) {
+	s.Options.Runtime.SetFaults(nil)
+
+	audit(r, "faults_cleared")
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) rateLimitsHandler(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, s.Options.RateLimit.Config())
+}
+
+func (s *Server) setRateLimitsHandler(w http.ResponseWriter, r *http.Request) {
+	if s.Options.RateLimit == nil {
+		s.fail(w, r, utils.NewAppError(utils.ConfigError, "rate limiter is not configured", nil).WithCode("config.rate_limit"))
+		return
+	}
+
+	var config common.RateLimitConfig
+	if !s.decode(w, r, &config) {
+		return
+	}
+
+	if appErr := s.Options.RateLimit.SetConfig(config); appErr != nil {
+		s.fail(w, r, appErr)
+		return
+	}
+
+	audit(r, "rate_limits", "key", config.Key, "global", config.Global, "default", config.Default, "routes", config.Routes, "exclude_paths", config.ExcludePaths)
+	writeJSON(w, http.StatusOK, config)
+}
+
+func (s *Server) clearRateLimitsHandler(w http.ResponseWriter, r *http.Request) {
+	if s.Options.RateLimit != nil {
+		_ = s.Options.RateLimit.SetConfig(common.RateLimitConfig{})
+	}
+
+	audit(r, "rate_limits_cleared")
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) middlewareHandler(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, common.MiddlewareResponse{
+		Config: s.Options.Middleware.Config(),
+		Chain:  s.Options.Middleware.Chain(),
+	})
+}
+
+func (s *Server) setMiddlewareHandler(w http.ResponseWriter, r *http.Request) {
+	if s.Options.Middleware == nil {
+		s.fail(w, r, utils.NewAppError(utils.ConfigError, "middleware registry is not configured", nil).WithCode("config.middleware"))
+		return
+	}
+
+	var config common.MiddlewareConfig
+	if !s.decode(w, r, &config) {
+		return
+	}
+
+	if appErr := s.Options.Middleware.SetConfig(config); appErr != nil {
+		s.fail(w, r, appErr)
+		return
+	}
+
+	audit(r, "middleware", "order", config.Order, "disabled", config.Disabled)
+	if appErr := s.rebuild(r); appErr != nil {
+		s.fail(w, r, appErr)
+		return
+	}
+	writeJSON(w, http.StatusAccepted, config)
+}
+
+func (s *Server) resetMiddlewareHandler(w http.ResponseWriter, r *http.Request) {
+	if s.Options.Middleware != nil {
+		config := s.Options.Middleware.Reset()
+		audit(r, "middleware_reset", "order", config.Order, "disabled", config.Disabled)
+		if appErr := s.rebuild(r); appErr != nil {
+			s.fail(w, r, appErr)
+			return
+		}
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// rebuild restarts the running framework, so its router is built with the current
+// middleware configuration
+func (s *Server) rebuild(r *http.Request) *utils.AppError {
+	s.switchMU.Lock()
+	defer s.switchMU.Unlock()
+
+	if framework := s.Options.Runtime.Framework(); framework != "" {
+		audit(r, "router_rebuild", "framework", framework)
+		if err := s.SwitchFramework(r.Context(), framework); err != nil {
+			return switchError(err, framework, s.Options.Runtime.Framework())
+		}
+	}
+	return nil
+}
+
+func (s *Server) readyHandler(w http.ResponseWriter, r *http.Request) {
+	var req ReadyResponse
+	if !s.decode(w, r, &req) {
+		return
+	}
+
+	previous := s.Options.Runtime.Ready()
+	s.Options.Runtime.SetReady(req.Ready)
+
+	audit(r, "ready", "from", previous, "to", req.Ready)
+	writeJSON(w, http.StatusOK, req)
+}
+
+// decode reads a JSON request body into v, rendering a problem and returning false on failure
+func (s *Server) decode(w http.ResponseWriter, r *http.Request, v any) bool {
+	decoder := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxRequestBytes))
+	decoder.DisallowUnknownFields()
+
+	if err := decoder.Decode(v); err != nil {
+		s.fail(w, r, utils.WrapError(err, utils.ValidationError, "invalid JSON request body").WithCode("validation.json"))
+		return false
+	}
+	return true
+}
+
+// fail logs the error and renders it as problem details
+func (s *Server) fail(w http.ResponseWriter, r *http.Request, appErr *utils.AppError) {
+	appErr.WithSeverity(utils.SeverityInfo).AddContext(utils.ContextPath, r.URL.Path)
+	appErr.LogError(r.Context())
+	s.Options.Problems.Write(w, r, appErr)
+}
+
+// writeJSON writes v as a JSON response
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,163 @@
+package admin
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/wasilak/go-hello-world/utils"
+	"github.com/wasilak/go-hello-world/web/common"
+)
+
+// Server is the admin API, served on its own listener so runtime control is separated
+// from user traffic. It outlives framework switches.
+type Server struct {
+	Options common.FrameworkOptions
+
+	// SwitchFramework switches the framework and returns the error of a failed or rolled
+	// back switch, it defaults to common.SwitchFramework
+	SwitchFramework func(ctx context.Context, framework string) error
+
+	server *http.Server
+
+	// switchMU serializes framework switches
+	switchMU sync.Mutex
+}
+
+// NewServer creates the admin API server
+func NewServer(options common.FrameworkOptions) *Server {
+	return &Server{
+		Options:         options,
+		SwitchFramework: common.SwitchFramework,
+	}
+}
+
+// Validate checks that the admin API is protected by a bearer token or mTLS
+func (s *Server) Validate() error {
+	config := s.Options.Admin
+
+	if config.Token == "" && config.ClientCAFile == "" {
+		return utils.NewAppError(utils.ConfigError, "admin API requires a bearer token or a client CA for mTLS", nil).WithCode("config.admin_auth")
+	}
+	if (config.TLSCertFile == "") != (config.TLSKeyFile == "") {
+		return utils.NewAppError(utils.ConfigError, "admin TLS certificate and key must be set together", nil).WithCode("config.admin_tls")
+	}
+	if config.ClientCAFile != "" && config.TLSCertFile == "" {
+		return utils.NewAppError(utils.ConfigError, "admin mTLS requires a server TLS certificate and key", nil).WithCode("config.admin_tls")
+	}
+	if s.Options.Runtime == nil {
+		return utils.NewAppError(utils.ConfigError, "admin API requires the runtime state", nil).WithCode("config.admin_runtime")
+	}
+	return nil
+}
+
+// tlsConfig returns the listener TLS configuration, requiring client certificates when a client CA is set
+func (s *Server) tlsConfig() (*tls.Config, error) {
+	config := s.Options.Admin
+	if config.ClientCAFile == "" {
+		return nil, nil
+	}
+
+	caPEM, err := os.ReadFile(config.ClientCAFile)
+	if err != nil {
+		return nil, utils.WrapError(err, utils.ConfigError, "failed to read admin client CA").AddContext("file", config.ClientCAFile)
+	}
+
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(caPEM) {
+		return nil, utils.NewAppError(utils.ConfigError, "admin client CA contains no certificates", nil).AddContext("file", config.ClientCAFile)
+	}
+
+	return &tls.Config{
+		ClientCAs:  pool,
+		ClientAuth: tls.RequireAndVerifyClientCert,
+		MinVersion: tls.VersionTLS12,
+	}, nil
+}
+
+// Start validates the configuration, binds the admin listener and serves in the background
+func (s *Server) Start(ctx context.Context) error {
+	if err := s.Validate(); err != nil {
+		return err
+	}
+
+	tlsConfig, err := s.tlsConfig()
+	if err != nil {
+		return err
+	}
+
+	listener, err := net.Listen("tcp", s.Options.Admin.ListenAddr)
+	if err != nil {
+		return utils.WrapError(err, utils.ConfigError, "failed to listen on admin address").AddContext("address", s.Options.Admin.ListenAddr)
+	}
+
+	s.server = &http.Server{
+		Handler:           s.Handler(),
+		TLSConfig:         tlsConfig,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	go func() {
+		slog.InfoContext(ctx, "Starting admin server", "address", listener.Addr().String(), "tls", s.Options.Admin.TLSCertFile != "", "mtls", tlsConfig != nil)
+
+		var err error
+		if s.Options.Admin.TLSCertFile != "" {
+			err = s.server.ServeTLS(listener, s.Options.Admin.TLSCertFile, s.Options.Admin.TLSKeyFile)
+		} else {
+			err = s.server.Serve(listener)
+		}
+		if err != nil && !errors.Is(err, http.ErrServerClosed) {
+			utils.WrapError(err, utils.RuntimeError, "admin server failed").LogError(ctx)
+		}
+	}()
+
+	return nil
+}
+
+// Stop gracefully stops the admin server
+func (s *Server) Stop(ctx context.Context) {
+	if s.server == nil {
+		return
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	if err := s.server.Shutdown(shutdownCtx); err != nil {
+		slog.ErrorContext(ctx, "Error stopping admin server", "error", err)
+	}
+}
+
+// Handler returns the authenticated admin API routes, callable from the dashboard
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+
+	// Read-only status endpoints
+	mux.HandleFunc("GET /admin/status", s.statusHandler)
+	mux.HandleFunc("GET /admin/config", s.configHandler)
+	mux.Handle("GET /admin/errors", common.RecentErrorsHandler(s.Options.Problems))
+	mux.HandleFunc("GET /admin/log-levels", s.logLevelsHandler)
+	mux.HandleFunc("GET /admin/rate-limits", s.rateLimitsHandler)
+	mux.HandleFunc("GET /admin/middleware", s.middlewareHandler)
+
+	// Mutating endpoints, each change is audited
+	mux.HandleFunc("PUT /admin/log-level", s.logLevelHandler)
+	mux.HandleFunc("DELETE /admin/log-level", s.resetLogLevelHandler)
+	mux.HandleFunc("PUT /admin/framework", s.frameworkHandler)
+	mux.HandleFunc("PUT /admin/faults", s.setFaultsHandler)
+	mux.HandleFunc("DELETE /admin/faults", s.clearFaultsHandler)
+	mux.HandleFunc("PUT /admin/ready", s.readyHandler)
+	mux.HandleFunc("PUT /admin/rate-limits", s.setRateLimitsHandler)
+	mux.HandleFunc("DELETE /admin/rate-limits", s.clearRateLimitsHandler)
+	mux.HandleFunc("PUT /admin/middleware", s.setMiddlewareHandler)
+	mux.HandleFunc("DELETE /admin/middleware", s.resetMiddlewareHandler)
+
+	return dashboardCORS(s.authenticate(mux))
+}
@@ -0,0 +1,400 @@
+package admin
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"encoding/pem"
+	"log/slog"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/wasilak/go-hello-world/utils"
+	"github.com/wasilak/go-hello-world/web/common"
+)
+
+const testToken = "s3cret"
+
+func newTestServer(t *testing.T) (*Server, *[]string) {
+	t.Helper()
+
+	runtime := common.NewRuntimeState()
+	runtime.SetFramework("gorilla")
+
+	switched := &[]string{}
+	logLevel := new(slog.LevelVar)
+	server := NewServer(common.FrameworkOptions{
+		LogLevelConfig: logLevel,
+		LogLevels:      utils.NewLevelRegistry(logLevel),
+		Admin:          common.AdminConfig{ListenAddr: "127.0.0.1:0", Token: testToken},
+		Runtime:        runtime,
+		RateLimit:      common.NewRateLimiter(common.RateLimitConfig{}),
+		Middleware:     common.NewMiddlewareRegistry(common.MiddlewareConfig{}),
+	})
+	server.SwitchFramework = func(_ context.Context, framework string) error {
+		*switched = append(*switched, framework)
+		runtime.SetFramework(framework)
+		return nil
+	}
+	return server, switched
+}
+
+func do(t *testing.T, handler http.Handler, method, path, body, token string) *httptest.ResponseRecorder {
+	t.Helper()
+
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestValidate(t *testing.T) {
+	server, _ := newTestServer(t)
+	assert.NoError(t, server.Validate())
+
+	server.Options.Admin.Token = ""
+	assert.Error(t, server.Validate(), "Expected an error without authentication")
+
+	server.Options.Admin.ClientCAFile = "ca.pem"
+	assert.Error(t, server.Validate(), "Expected an error for mTLS without a server certificate")
+}
+
+func TestAuthentication(t *testing.T) {
+	server, _ := newTestServer(t)
+	handler := server.Handler()
+
+	t.Run("Missing token", func(t *testing.T) {
+		rec := do(t, handler, http.MethodGet, "/admin/status", "", "")
+		assert.Equal(t, http.StatusUnauthorized, rec.Code)
+		assert.Equal(t, common.ProblemContentType, rec.Header().Get("Content-Type"))
+		assert.NotEmpty(t, rec.Header().Get("WWW-Authenticate"))
+
+		var problem common.Problem
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &problem))
+		assert.Equal(t, common.DefaultProblemTypeURIPrefix+"unauthorized", problem.Type)
+		assert.Equal(t, "auth.invalid_token", problem.Code)
+	})
+
+	t.Run("Invalid token", func(t *testing.T) {
+		rec := do(t, handler, http.MethodGet, "/admin/status", "", "wrong")
+		assert.Equal(t, http.StatusUnauthorized, rec.Code)
+	})
+
+	t.Run("Valid token", func(t *testing.T) {
+		rec := do(t, handler, http.MethodGet, "/admin/status", "", testToken)
+		require.Equal(t, http.StatusOK, rec.Code)
+
+		var status StatusResponse
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &status))
+		assert.Equal(t, "gorilla", status.Framework)
+		assert.True(t, status.Ready)
+		assert.Nil(t, status.Faults)
+	})
+}
+
+func TestMutatingEndpoints(t *testing.T) {
+	server, switched := newTestServer(t)
+	handler := server.Handler()
+
+	t.Run("Method", func(t *testing.T) {
+		rec := do(t, handler, http.MethodGet, "/admin/log-level?level=debug", "", testToken)
+		assert.Equal(t, http.StatusMethodNotAllowed, rec.Code)
+	})
+
+	t.Run("Log level", func(t *testing.T) {
+		rec := do(t, handler, http.MethodPut, "/admin/log-level", `{"level":"debug"}`, testToken)
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, slog.LevelDebug, server.Options.LogLevelConfig.Level())
+
+		rec = do(t, handler, http.MethodPut, "/admin/log-level", `{"level":"verbose"}`, testToken)
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+		assert.Equal(t, slog.LevelDebug, server.Options.LogLevelConfig.Level())
+	})
+
+	t.Run("Component log level", func(t *testing.T) {
+		rec := do(t, handler, http.MethodPut, "/admin/log-level", `{"logger":"access","level":"warn","ttl":"10m"}`, testToken)
+		require.Equal(t, http.StatusOK, rec.Code)
+
+		var response common.LoggerResponse
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &response))
+		assert.Equal(t, "access", response.Logger)
+		assert.Equal(t, "WARN", response.LogLevelCurrent)
+		assert.NotNil(t, response.ExpiresAt)
+		assert.Equal(t, slog.LevelWarn, server.Options.LogLevels.Level(utils.ComponentAccess, ""))
+
+		rec = do(t, handler, http.MethodGet, "/admin/log-levels", "", testToken)
+		require.Equal(t, http.StatusOK, rec.Code)
+		var levels []utils.LoggerLevel
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &levels))
+		require.Len(t, levels, 2)
+		assert.Equal(t, "access", levels[1].Logger)
+
+		rec = do(t, handler, http.MethodPut, "/admin/log-level", `{"logger":"access","level":"warn","ttl":"soon"}`, testToken)
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+		rec = do(t, handler, http.MethodPut, "/admin/log-level", `{"logger":"database","level":"warn"}`, testToken)
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+		rec = do(t, handler, http.MethodDelete, "/admin/log-level?logger=access", "", testToken)
+		assert.Equal(t, http.StatusNoContent, rec.Code)
+		assert.Equal(t, slog.LevelDebug, server.Options.LogLevels.Level(utils.ComponentAccess, ""))
+	})
+
+	t.Run("Framework", func(t *testing.T) {
+		rec := do(t, handler, http.MethodPut, "/admin/framework", `{"framework":"gin"}`, testToken)
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, []string{"gin"}, *switched)
+
+		var response common.FrameworkResponse
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &response))
+		assert.Equal(t, common.FrameworkResponse{FrameworkPrevious: "gorilla", FrameworkCurrent: "gin"}, response)
+
+		rec = do(t, handler, http.MethodPut, "/admin/framework", `{"framework":"django"}`, testToken)
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+		assert.Len(t, *switched, 1)
+
+		rec = do(t, handler, http.MethodPut, "/admin/framework", `{"framework":"gorilla"}`, testToken)
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, []string{"gin", "gorilla"}, *switched)
+	})
+
+	t.Run("Failed framework switch", func(t *testing.T) {
+		switchFramework := server.SwitchFramework
+		defer func() { server.SwitchFramework = switchFramework }()
+		server.SwitchFramework = func(context.Context, string) error {
+			return utils.NewAppError(utils.FrameworkError, "failed to listen", nil)
+		}
+		server.Options.Problems = common.ProblemConfig{ContextAllowlist: utils.PublicContextKeys()}
+		defer func() { server.Options.Problems = common.ProblemConfig{} }()
+
+		rec := do(t, handler, http.MethodPut, "/admin/framework", `{"framework":"fiber"}`, testToken)
+		assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+		assert.Contains(t, rec.Body.String(), `"code":"framework.switch_failed"`)
+		assert.Contains(t, rec.Body.String(), `"running_framework":"gorilla"`)
+		assert.Equal(t, "gorilla", server.Options.Runtime.Framework())
+	})
+
+	t.Run("Faults", func(t *testing.T) {
+		rec := do(t, handler, http.MethodPut, "/admin/faults", `{"error_rate":2}`, testToken)
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+		assert.Nil(t, server.Options.Runtime.Faults())
+
+		rec = do(t, handler, http.MethodPut, "/admin/faults", `{"latency_ms":10,"error_rate":0.5,"status_code":503}`, testToken)
+		assert.Equal(t, http.StatusOK, rec.Code)
+		require.NotNil(t, server.Options.Runtime.Faults())
+		assert.Equal(t, 503, server.Options.Runtime.Faults().StatusCode)
+
+		rec = do(t, handler, http.MethodDelete, "/admin/faults", "", testToken)
+		assert.Equal(t, http.StatusNoContent, rec.Code)
+		assert.Nil(t, server.Options.Runtime.Faults())
+	})
+
+	t.Run("Config", func(t *testing.T) {
+		server.Options.Server = common.ServerConfig{ReadHeaderTimeout: 5 * time.Second, MaxBodyBytes: 1024, DisableKeepAlive: true}
+
+		rec := do(t, handler, http.MethodGet, "/admin/config", "", testToken)
+		assert.Equal(t, http.StatusOK, rec.Code)
+
+		var config ConfigResponse
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &config))
+		assert.Equal(t, "gorilla", config.Framework)
+		assert.Equal(t, "5s", config.Server.ReadHeaderTimeout)
+		assert.Equal(t, int64(1024), config.Server.MaxBodyBytes)
+		assert.False(t, config.Server.KeepAlive)
+		assert.Empty(t, config.Server.Unsupported)
+	})
+
+	t.Run("Rate limits", func(t *testing.T) {
+		rec := do(t, handler, http.MethodPut, "/admin/rate-limits", `{"key":"cookie"}`, testToken)
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+		rec = do(t, handler, http.MethodPut, "/admin/rate-limits", `{"key":"ip","default":{"rate":5,"burst":10},"routes":[{"path":"/chain","rate":1,"burst":1}]}`, testToken)
+		assert.Equal(t, http.StatusOK, rec.Code)
+		config := server.Options.RateLimit.Config()
+		require.NotNil(t, config.Default)
+		assert.Equal(t, 10, config.Default.Burst)
+		assert.Equal(t, []common.RouteRateLimit{{Path: "/chain", RateLimit: common.RateLimit{Rate: 1, Burst: 1}}}, config.Routes)
+
+		rec = do(t, handler, http.MethodGet, "/admin/rate-limits", "", testToken)
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Contains(t, rec.Body.String(), `"path":"/chain"`)
+
+		rec = do(t, handler, http.MethodDelete, "/admin/rate-limits", "", testToken)
+		assert.Equal(t, http.StatusNoContent, rec.Code)
+		assert.False(t, server.Options.RateLimit.Config().Enabled())
+	})
+
+	t.Run("Middleware", func(t *testing.T) {
+		*switched = nil
+
+		rec := do(t, handler, http.MethodPut, "/admin/middleware", `{"disabled":["csrf"]}`, testToken)
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+		assert.Empty(t, *switched)
+
+		rec = do(t, handler, http.MethodPut, "/admin/middleware", `{"order":["fault"],"disabled":["compression"]}`, testToken)
+		assert.Equal(t, http.StatusAccepted, rec.Code)
+		assert.Equal(t, []string{"compression"}, server.Options.Middleware.Config().Disabled)
+		assert.Equal(t, []string{"gorilla"}, *switched, "Expected the running framework to be rebuilt")
+
+		rec = do(t, handler, http.MethodGet, "/admin/middleware", "", testToken)
+		assert.Equal(t, http.StatusOK, rec.Code)
+		var response common.MiddlewareResponse
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &response))
+		assert.Equal(t, []string{"fault"}, response.Config.Order)
+
+		rec = do(t, handler, http.MethodDelete, "/admin/middleware", "", testToken)
+		assert.Equal(t, http.StatusNoContent, rec.Code)
+		assert.Empty(t, server.Options.Middleware.Config().Disabled)
+		assert.Len(t, *switched, 2)
+	})
+
+	t.Run("Ready", func(t *testing.T) {
+		rec := do(t, handler, http.MethodPut, "/admin/ready", `{"ready":false}`, testToken)
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.False(t, server.Options.Runtime.Ready())
+	})
+
+	t.Run("Unknown field", func(t *testing.T) {
+		rec := do(t, handler, http.MethodPut, "/admin/ready", `{"readyy":true}`, testToken)
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+	})
+}
+
+func TestMutualTLS(t *testing.T) {
+	dir := t.TempDir()
+
+	caKey, caCert := newCertificate(t, "test-ca", nil, nil)
+	writePEM(t, filepath.Join(dir, "ca.pem"), "CERTIFICATE", caCert.Raw)
+
+	serverKey, serverCert := newCertificate(t, "127.0.0.1", caCert, caKey)
+	writePEM(t, filepath.Join(dir, "server.pem"), "CERTIFICATE", serverCert.Raw)
+	keyDER, err := x509.MarshalECPrivateKey(serverKey)
+	require.NoError(t, err)
+	writePEM(t, filepath.Join(dir, "server-key.pem"), "EC PRIVATE KEY", keyDER)
+
+	clientKey, clientCert := newCertificate(t, "operator", caCert, caKey)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	addr := listener.Addr().String()
+	listener.Close()
+
+	server, _ := newTestServer(t)
+	server.Options.Admin = common.AdminConfig{
+		ListenAddr:   addr,
+		TLSCertFile:  filepath.Join(dir, "server.pem"),
+		TLSKeyFile:   filepath.Join(dir, "server-key.pem"),
+		ClientCAFile: filepath.Join(dir, "ca.pem"),
+	}
+	require.NoError(t, server.Start(t.Context()))
+	t.Cleanup(func() { server.Stop(t.Context()) })
+
+	roots := x509.NewCertPool()
+	roots.AddCert(caCert)
+
+	client := func(certificates ...tls.Certificate) *http.Client {
+		return &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{RootCAs: roots, Certificates: certificates}}}
+	}
+
+	t.Run("Without client certificate", func(t *testing.T) {
+		_, err := client().Get("https://" + addr + "/admin/status")
+		assert.Error(t, err)
+	})
+
+	t.Run("With client certificate", func(t *testing.T) {
+		var resp *http.Response
+		require.Eventually(t, func() bool {
+			resp, err = client(tls.Certificate{Certificate: [][]byte{clientCert.Raw}, PrivateKey: clientKey}).Get("https://" + addr + "/admin/status")
+			return err == nil
+		}, 2*time.Second, 20*time.Millisecond)
+		defer resp.Body.Close()
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+	})
+}
+
+// newCertificate creates a key and certificate signed by parent, or a self-signed CA when parent is nil
+func newCertificate(t *testing.T, commonName string, parent *x509.Certificate, parentKey *ecdsa.PrivateKey) (*ecdsa.PrivateKey, *x509.Certificate) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: commonName},
+		NotBefore:    time.Now().Add(-time.Minute),
+		NotAfter:     time.Now().Add(time.Hour),
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+	}
+
+	if parent == nil {
+		template.IsCA = true
+		template.BasicConstraintsValid = true
+		template.KeyUsage = x509.KeyUsageCertSign
+		parent, parentKey = template, key
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, parent, &key.PublicKey, parentKey)
+	require.NoError(t, err)
+
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+	return key, cert
+}
+
+func writePEM(t *testing.T, path, blockType string, der []byte) {
+	t.Helper()
+	require.NoError(t, os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der}), 0o600))
+}
+
+func TestDashboardCORS(t *testing.T) {
+	server, _ := newTestServer(t)
+	handler := server.Handler()
+
+	preflight := func(origin string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodOptions, "http://example.com:9090/admin/framework", nil)
+		req.Header.Set("Origin", origin)
+		req.Header.Set("Access-Control-Request-Method", http.MethodPut)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+
+	t.Run("Dashboard on the same host", func(t *testing.T) {
+		rec := preflight("http://example.com:3000")
+		assert.Equal(t, http.StatusNoContent, rec.Code)
+		assert.Equal(t, "http://example.com:3000", rec.Header().Get("Access-Control-Allow-Origin"))
+		assert.Contains(t, rec.Header().Get("Access-Control-Allow-Headers"), "Authorization")
+
+		req := httptest.NewRequest(http.MethodPut, "http://example.com:9090/admin/framework", strings.NewReader(`{"framework":"chi"}`))
+		req.Header.Set("Origin", "http://example.com:3000")
+		rec = httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		assert.Equal(t, http.StatusUnauthorized, rec.Code, "Expected actual requests to be authenticated")
+		assert.Equal(t, "http://example.com:3000", rec.Header().Get("Access-Control-Allow-Origin"))
+	})
+
+	t.Run("Other origins", func(t *testing.T) {
+		rec := preflight("http://evil.example")
+		assert.Equal(t, http.StatusUnauthorized, rec.Code)
+		assert.Empty(t, rec.Header().Get("Access-Control-Allow-Origin"))
+	})
+}
@@ -12,45 +12,38 @@ func (s *Server) mainRoute(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 	response := s.SetMainResponse(ctx, r)
 
-	w.Header().Set("Content-Type", "application/json")
-	if err := json.NewEncoder(w).Encode(response); err != nil {
-		// Use the new standardized error types
-		appErr := utils.WrapError(err, utils.RuntimeError, "failed to encode main response in chi")
-		appErr.AddContext("path", r.URL.Path)
-		appErr.LogError(ctx)
-		http.Error(w, "Internal server error", http.StatusInternalServerError)
-		return
-	}
+	common.WriteResponse(w, r, s.FrameworkOptions.Problems, http.StatusOK, response)
 }
 
 func (s *Server) healthRoute(w http.ResponseWriter, r *http.Request) {
-	ctx := r.Context()
 	response := common.HealthResponse{Status: "ok"}
 
-	w.Header().Set("Content-Type", "application/json")
-	if err := json.NewEncoder(w).Encode(response); err != nil {
-		// Use the new standardized error types
-		appErr := utils.WrapError(err, utils.RuntimeError, "failed to encode health response in chi")
-		appErr.AddContext("path", r.URL.Path)
-		appErr.LogError(ctx)
-		http.Error(w, "Internal server error", http.StatusInternalServerError)
-		return
-	}
+	common.WriteResponse(w, r, s.FrameworkOptions.Problems, http.StatusOK, response)
 }
 
 func (s *Server) loggerRoute(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 	levelParam := r.URL.Query().Get("level")
-	response := s.SetLogLevelResponse(ctx, levelParam)
+	response, appErr := s.SetLogLevelResponse(ctx, common.LogLevelRequest{
+		Logger: r.URL.Query().Get("logger"),
+		Level:  levelParam,
+		TTL:    r.URL.Query().Get("ttl"),
+	})
+	if appErr != nil {
+		appErr.AddContext(utils.ContextPath, r.URL.Path)
+		appErr.LogError(ctx)
+		s.FrameworkOptions.Problems.Write(w, r, appErr)
+		return
+	}
 
 	w.Header().Set("Content-Type", "application/json")
 	if err := json.NewEncoder(w).Encode(response); err != nil {
 		// Use the new standardized error types
 		appErr := utils.WrapError(err, utils.RuntimeError, "failed to encode logger response in chi")
-		appErr.AddContext("path", r.URL.Path)
-		appErr.AddContext("log_level", levelParam)
+		appErr.AddContext(utils.ContextPath, r.URL.Path)
+		appErr.AddContext(utils.ContextLogLevel, levelParam)
 		appErr.LogError(ctx)
-		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		s.FrameworkOptions.Problems.Write(w, r, appErr)
 		return
 	}
 }
@@ -58,16 +51,53 @@ func (s *Server) loggerRoute(w http.ResponseWriter, r *http.Request) {
 func (s *Server) switchRoute(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 	nameParam := r.URL.Query().Get("name")
-	response := s.SetFrameworkResponse(ctx, nameParam)
+	response, appErr := s.SetFrameworkResponse(ctx, nameParam)
+	if appErr != nil {
+		appErr.AddContext(utils.ContextPath, r.URL.Path)
+		appErr.LogError(ctx)
+		s.FrameworkOptions.Problems.Write(w, r, appErr)
+		return
+	}
 
 	w.Header().Set("Content-Type", "application/json")
 	if err := json.NewEncoder(w).Encode(response); err != nil {
 		// Use the new standardized error types
 		appErr := utils.WrapError(err, utils.RuntimeError, "failed to encode switch response in chi")
-		appErr.AddContext("path", r.URL.Path)
-		appErr.AddContext("framework", nameParam)
+		appErr.AddContext(utils.ContextPath, r.URL.Path)
+		appErr.AddContext(utils.ContextFramework, nameParam)
 		appErr.LogError(ctx)
-		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		s.FrameworkOptions.Problems.Write(w, r, appErr)
 		return
 	}
 }
+
+func (s *Server) chainRoute(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	request, appErr := s.FrameworkOptions.Chain.ParseChainRequest(r.URL.Query(), r.Header.Get(common.ChainDepthHeader))
+	if appErr != nil {
+		appErr.AddContext(utils.ContextPath, r.URL.Path)
+		appErr.LogError(ctx)
+		s.FrameworkOptions.Problems.Write(w, r, appErr)
+		return
+	}
+
+	response := s.SetChainResponse(ctx, request)
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		// Use the new standardized error types
+		appErr := utils.WrapError(err, utils.RuntimeError, "failed to encode chain response in chi")
+		appErr.AddContext(utils.ContextPath, r.URL.Path)
+		appErr.LogError(ctx)
+		s.FrameworkOptions.Problems.Write(w, r, appErr)
+		return
+	}
+}
+
+func (s *Server) notFoundRoute(w http.ResponseWriter, r *http.Request) {
+	common.WriteProblem(w, s.FrameworkOptions.Problems.StatusProblem(r.Context(), http.StatusNotFound, "", r.URL.Path))
+}
+
+func (s *Server) methodNotAllowedRoute(w http.ResponseWriter, r *http.Request) {
+	common.WriteProblem(w, s.FrameworkOptions.Problems.StatusProblem(r.Context(), http.StatusMethodNotAllowed, "", r.URL.Path))
+}
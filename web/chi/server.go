@@ -2,9 +2,7 @@ package chi
 
 import (
 	"context"
-	"log/slog"
 	"net/http"
-	"os"
 	"strings"
 	"sync"
 
@@ -15,7 +13,6 @@ import (
 	"github.com/prometheus/client_golang/prometheus/collectors"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"github.com/riandyrn/otelchi"
-	slogchi "github.com/samber/slog-chi"
 	"github.com/wasilak/go-hello-world/utils"
 	"github.com/wasilak/go-hello-world/web/common"
 )
@@ -43,30 +40,94 @@ func (s *Server) setup() {
 	if promMiddleware == nil {
 		promMiddleware = chiprometheus.NewMiddleware(strings.ReplaceAll(utils.GetAppName(), "-", "_"))
 	}
-	r.Use(promMiddleware)
+
+	routePattern := func(r *http.Request) string {
+		return chi.RouteContext(r.Context()).RoutePattern()
+	}
+
+	// Middleware provided by chi, applied in the configured order
+	available := map[string][]func(http.Handler) http.Handler{
+		common.MiddlewareRecovery:    {middleware.Recoverer},
+		common.MiddlewarePrometheus:  {promMiddleware},
+		common.MiddlewareRequestID:   {s.FrameworkOptions.RequestID.Middleware()},
+		common.MiddlewareErrorScope:  {common.ErrorScopeMiddleware(s.Framework, routePattern)},
+		common.MiddlewareAccessLog:   {s.FrameworkOptions.AccessLog.Middleware(routePattern)},
+		common.MiddlewareBodyLimit:   {s.FrameworkOptions.Server.BodyLimitMiddleware(s.FrameworkOptions.Problems)},
+		common.MiddlewareRateLimit:   {s.FrameworkOptions.RateLimit.Middleware(s.FrameworkOptions.Problems)},
+		common.MiddlewareConcurrency: {s.FrameworkOptions.Concurrency.Middleware(s.Framework, s.FrameworkOptions.Problems)},
+		common.MiddlewareFault:       {common.FaultMiddleware(s.FrameworkOptions.Runtime, s.FrameworkOptions.Problems)},
+	}
+
+	// CORS headers and preflight responses, shared by all frameworks
+	if s.FrameworkOptions.CORS != nil {
+		available[common.MiddlewareCORS] = []func(http.Handler) http.Handler{s.FrameworkOptions.CORS.Middleware()}
+	}
+
+	// Security headers, shared by all frameworks
+	if s.FrameworkOptions.SecurityHeaders != nil {
+		available[common.MiddlewareSecurityHeaders] = []func(http.Handler) http.Handler{s.FrameworkOptions.SecurityHeaders.Middleware()}
+	}
+
+	// Response compression, shared by all frameworks
+	if s.FrameworkOptions.Compression != nil {
+		available[common.MiddlewareCompression] = []func(http.Handler) http.Handler{s.FrameworkOptions.Compression.Middleware()}
+	}
+
+	// OpenTelemetry HTTP server metrics
+	if s.FrameworkOptions.HTTPMetrics != nil {
+		available[common.MiddlewareHTTPMetrics] = []func(http.Handler) http.Handler{s.FrameworkOptions.HTTPMetrics.Middleware(s.Framework, routePattern)}
+	}
 
 	// OpenTelemetry Middleware
 	if s.FrameworkOptions.OtelEnabled {
-		r.Use(otelchi.Middleware(utils.GetAppName(), otelchi.WithFilter(func(r *http.Request) bool {
-			return !strings.Contains(r.URL.Path, "public/dist") && !strings.Contains(r.URL.Path, "health")
-		})))
+		tracing := s.FrameworkOptions.Tracing
+		available[common.MiddlewareOtel] = []func(http.Handler) http.Handler{
+			otelchi.Middleware(utils.GetAppName(),
+				otelchi.WithTracerProvider(tracing.TracerProvider),
+				otelchi.WithPropagators(tracing.GetPropagators()),
+				otelchi.WithChiRoutes(r),
+				otelchi.WithRequestMethodInSpanName(true),
+				otelchi.WithFilter(func(r *http.Request) bool {
+					return tracing.Traced(r.URL.Path)
+				}),
+			),
+			tracing.HeaderCaptureMiddleware(),
+		}
 	}
 
-	// Gzip Middleware
-	r.Use(middleware.NewCompressor(5).Handler)
+	if s.FrameworkOptions.StatsvizEnabled {
+		available[common.MiddlewareStatsviz] = nil
+	}
 
-	// Custom Logging Middleware
-	r.Use(slogchi.New(slog.Default()))
+	chain, chained := common.BuildMiddleware(s.WebServer, available)
+	r.Use(chained...)
 
 	// Define Routes
 	r.Get("/", s.mainRoute)
 	r.Get("/health", s.healthRoute)
+	r.Method(http.MethodGet, "/ready", common.ReadinessHandler(s.FrameworkOptions.Runtime))
+	r.Method(http.MethodGet, "/version", common.VersionHandler(s.FrameworkOptions))
+	r.Method(http.MethodGet, common.DashboardPath, s.Dashboard().Handler())
+	r.Method(http.MethodGet, common.DashboardPath+"/*", s.Dashboard().Handler())
 	r.Get("/logger", s.loggerRoute)
 	r.Get("/framework", s.switchRoute)
+	r.Get("/chain", s.chainRoute)
+	r.Method(http.MethodGet, "/middleware", common.MiddlewareHandler(s.FrameworkOptions))
+	r.NotFound(s.notFoundRoute)
+	r.MethodNotAllowed(s.methodNotAllowedRoute)
+
+	// Reverse proxy routes
+	if s.FrameworkOptions.Proxy.Enabled() {
+		proxy := common.NewProxyHandler(s.FrameworkOptions.Proxy, s.FrameworkOptions.Tracing, s.FrameworkOptions.Problems)
+		for _, route := range s.FrameworkOptions.Proxy.Routes {
+			r.Handle(route.Prefix, proxy)
+			r.Handle(route.Prefix+"/*", proxy)
+		}
+	}
 	r.Handle("/metrics", promhttp.Handler())
 
 	// Optional Statviz
-	if s.FrameworkOptions.StatsvizEnabled {
+	if chain.Includes(common.MiddlewareStatsviz) {
 		// Create statsviz server.
 		srv, _ := statsviz.NewServer()
 
@@ -79,35 +140,41 @@ func (s *Server) setup() {
 
 	s.Server = &http.Server{
 		Addr:    s.FrameworkOptions.ListenAddr,
-		Handler: r,
+		Handler: common.CountRequests(s.FrameworkOptions.Runtime)(r),
 	}
+	s.FrameworkOptions.Server.Apply(s.Server)
 }
 
-func (s *Server) Start(ctx context.Context) {
+func (s *Server) Start(ctx context.Context) error {
 	s.MU.Lock()
 	defer s.MU.Unlock()
 
-	s.wg.Add(1)
-
 	if s.Running {
-		slog.DebugContext(ctx, "Web server is already running")
-		return
+		utils.ComponentLogger(utils.ComponentServer).DebugContext(ctx, "Web server is already running")
+		return nil
 	}
 
+	if s.Server == nil {
+		s.setup()
+	}
+
+	listener, err := s.Listen()
+	if err != nil {
+		return err
+	}
+
+	s.wg.Add(1)
 	go func() {
 		defer s.wg.Done()
 
-		if s.Server == nil {
-			s.setup()
-		}
-		slog.DebugContext(ctx, "Starting server", "address", s.FrameworkOptions.ListenAddr)
-		if err := s.Server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-			slog.ErrorContext(ctx, "Server exited with error", "error", err)
-			os.Exit(1)
+		utils.ComponentLogger(utils.ComponentServer).DebugContext(ctx, "Starting server", "address", s.FrameworkOptions.ListenAddr)
+		if err := s.Server.Serve(listener); err != nil && err != http.ErrServerClosed {
+			s.Fail(err)
 		}
 	}()
 
 	s.Running = true
+	return nil
 }
 
 // Stop gracefully stops the web server.
@@ -116,16 +183,19 @@ func (s *Server) Stop(ctx context.Context) {
 	defer s.MU.Unlock()
 
 	if !s.Running {
-		slog.DebugContext(ctx, "Web server is not running")
+		utils.ComponentLogger(utils.ComponentServer).DebugContext(ctx, "Web server is not running")
 		return
 	}
 
-	slog.InfoContext(ctx, "Stopping web server")
+	utils.ComponentLogger(utils.ComponentServer).InfoContext(ctx, "Stopping web server")
+
+	// End the dashboard event streams, Shutdown waits for them
+	s.Dashboard().Close()
 
 	if err := s.Server.Shutdown(ctx); err != nil {
-		slog.ErrorContext(ctx, "Error stopping web server", "error", err)
+		utils.ComponentLogger(utils.ComponentServer).ErrorContext(ctx, "Error stopping web server", "error", err)
 	} else {
-		slog.InfoContext(ctx, "Web server stopped successfully")
+		utils.ComponentLogger(utils.ComponentServer).InfoContext(ctx, "Web server stopped successfully")
 	}
 
 	s.Running = false
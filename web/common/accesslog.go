@@ -0,0 +1,417 @@
+package common
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/wasilak/go-hello-world/utils"
+)
+
+// Access log fields
+const (
+	AccessFieldMethod       = "method"
+	AccessFieldPath         = "path"
+	AccessFieldRoute        = "route"
+	AccessFieldStatus       = "status"
+	AccessFieldBytes        = "bytes"
+	AccessFieldLatency      = "latency"
+	AccessFieldClientIP     = "client_ip"
+	AccessFieldUserAgent    = "user_agent"
+	AccessFieldTraceID      = "trace_id"
+	AccessFieldSpanID       = "span_id"
+	AccessFieldHeaders      = "headers"
+	AccessFieldRequestBody  = "request_body"
+	AccessFieldResponseBody = "response_body"
+)
+
+// maxRedactedBodyBytes bounds the body captured for redaction, larger bodies cannot be
+// parsed and are redacted as a whole
+const maxRedactedBodyBytes = 64 << 10
+
+// AccessLogFields lists the fields an access log entry can contain
+func AccessLogFields() []string {
+	return []string{
+		AccessFieldMethod, AccessFieldPath, AccessFieldRoute, AccessFieldStatus, AccessFieldBytes,
+		AccessFieldLatency, AccessFieldClientIP, AccessFieldUserAgent, AccessFieldTraceID, AccessFieldSpanID,
+		AccessFieldHeaders, AccessFieldRequestBody, AccessFieldResponseBody,
+	}
+}
+
+// DefaultAccessLogFields are logged when no fields are configured
+func DefaultAccessLogFields() []string {
+	return []string{
+		AccessFieldMethod, AccessFieldPath, AccessFieldRoute, AccessFieldStatus, AccessFieldBytes,
+		AccessFieldLatency, AccessFieldClientIP, AccessFieldTraceID, AccessFieldSpanID,
+	}
+}
+
+// AccessLogConfig configures the access log shared by all frameworks
+type AccessLogConfig struct {
+	// Fields logged for each request, DefaultAccessLogFields when empty
+	Fields []string
+
+	// Headers are the request headers logged with the headers field
+	Headers []string
+
+	// ExcludePaths are not logged, an entry also excludes the paths below it
+	ExcludePaths []string
+
+	// SampleRate is the fraction of requests logged, server errors are always logged
+	SampleRate float64
+
+	// RateLimit caps the sampled entries per second, 0 disables the cap
+	RateLimit int
+
+	// BodyLimit is the number of body bytes logged by the body fields
+	BodyLimit int
+
+	// Redaction redacts sensitive headers, query parameters and JSON body fields
+	Redaction *utils.Redactor
+}
+
+// Validate checks the access log configuration
+func (c AccessLogConfig) Validate() *utils.AppError {
+	for _, field := range c.Fields {
+		if !slices.Contains(AccessLogFields(), field) {
+			return utils.NewAppError(utils.ValidationError, "unknown access log field", nil).
+				WithCode("validation.access_log_field").
+				AddContext("field", field)
+		}
+	}
+	if c.SampleRate < 0 || c.SampleRate > 1 {
+		return utils.NewAppError(utils.ValidationError, "access log sample rate must be between 0 and 1", nil).WithCode("validation.access_log_sample_rate")
+	}
+	if c.RateLimit < 0 || c.BodyLimit < 0 {
+		return utils.NewAppError(utils.ValidationError, "access log rate and body limits must not be negative", nil).WithCode("validation.access_log_limit")
+	}
+	return nil
+}
+
+// AccessLogEntry describes a served request
+type AccessLogEntry struct {
+	Method       string
+	Path         string
+	Route        string
+	Status       int
+	Bytes        int64
+	Latency      time.Duration
+	ClientIP     string
+	UserAgent    string
+	Headers      http.Header
+	RequestBody  *BodyCapture
+	ResponseBody *BodyCapture
+}
+
+// AccessLogger writes access log entries in the same shape for every framework. It
+// outlives framework switches, and a nil logger logs nothing.
+type AccessLogger struct {
+	config  AccessLogConfig
+	fields  map[string]bool
+	limiter *windowLimiter
+}
+
+// NewAccessLogger creates the access logger
+func NewAccessLogger(config AccessLogConfig) *AccessLogger {
+	if len(config.Fields) == 0 {
+		config.Fields = DefaultAccessLogFields()
+	}
+
+	l := &AccessLogger{
+		config: config,
+		fields: make(map[string]bool),
+	}
+	for _, field := range config.Fields {
+		l.fields[field] = true
+	}
+	if config.RateLimit > 0 {
+		l.limiter = &windowLimiter{limit: config.RateLimit}
+	}
+	return l
+}
+
+// Excluded reports whether requests for path are not logged
+func (l *AccessLogger) Excluded(path string) bool {
+	if l == nil {
+		return true
+	}
+	return slices.ContainsFunc(l.config.ExcludePaths, func(excluded string) bool {
+		return underPath(path, excluded)
+	})
+}
+
+// RequestBodyLimit returns the request body bytes to capture, 0 when not logged
+func (l *AccessLogger) RequestBodyLimit() int {
+	if !l.fields[AccessFieldRequestBody] {
+		return 0
+	}
+	return l.captureLimit()
+}
+
+// ResponseBodyLimit returns the response body bytes to capture, 0 when not logged
+func (l *AccessLogger) ResponseBodyLimit() int {
+	if !l.fields[AccessFieldResponseBody] {
+		return 0
+	}
+	return l.captureLimit()
+}
+
+// captureLimit returns the body bytes to capture. With redaction the body is captured up
+// to maxRedactedBodyBytes, so it is redacted as a whole before it is truncated.
+func (l *AccessLogger) captureLimit() int {
+	if l.config.BodyLimit == 0 || l.config.Redaction == nil {
+		return l.config.BodyLimit
+	}
+	return max(l.config.BodyLimit, maxRedactedBodyBytes)
+}
+
+// sampled decides whether an entry with status is logged
+func (l *AccessLogger) sampled(status int) bool {
+	if status >= http.StatusInternalServerError {
+		return true
+	}
+	if l.config.SampleRate < 1 && rand.Float64() >= l.config.SampleRate {
+		return false
+	}
+	return l.limiter.allow()
+}
+
+// Log writes the entry when it is sampled. ctx carries the span and the request scope,
+// so route log levels apply to access logs.
+func (l *AccessLogger) Log(ctx context.Context, entry AccessLogEntry) {
+	if l == nil || !l.sampled(entry.Status) {
+		return
+	}
+
+	level := slog.LevelInfo
+	switch {
+	case entry.Status >= http.StatusInternalServerError:
+		level = slog.LevelError
+	case entry.Status >= http.StatusBadRequest:
+		level = slog.LevelWarn
+	}
+
+	logger := utils.ComponentLogger(utils.ComponentAccess)
+	if !logger.Enabled(ctx, level) {
+		return
+	}
+
+	spanContext := trace.SpanContextFromContext(ctx)
+
+	attrs := make([]slog.Attr, 0, len(l.config.Fields))
+	for _, field := range l.config.Fields {
+		switch field {
+		case AccessFieldMethod:
+			attrs = append(attrs, slog.String(field, entry.Method))
+		case AccessFieldPath:
+			attrs = append(attrs, slog.String(field, entry.Path))
+		case AccessFieldRoute:
+			attrs = append(attrs, slog.String(field, entry.Route))
+		case AccessFieldStatus:
+			attrs = append(attrs, slog.Int(field, entry.Status))
+		case AccessFieldBytes:
+			attrs = append(attrs, slog.Int64(field, entry.Bytes))
+		case AccessFieldLatency:
+			attrs = append(attrs, slog.Duration(field, entry.Latency))
+		case AccessFieldClientIP:
+			attrs = append(attrs, slog.String(field, entry.ClientIP))
+		case AccessFieldUserAgent:
+			attrs = append(attrs, slog.String(field, entry.UserAgent))
+		case AccessFieldTraceID:
+			if spanContext.HasTraceID() {
+				attrs = append(attrs, slog.String(field, spanContext.TraceID().String()))
+			}
+		case AccessFieldSpanID:
+			if spanContext.HasSpanID() {
+				attrs = append(attrs, slog.String(field, spanContext.SpanID().String()))
+			}
+		case AccessFieldHeaders:
+			attrs = append(attrs, l.headersAttr(entry.Headers))
+		case AccessFieldRequestBody:
+			if body := l.redactBody(entry.RequestBody); body != "" {
+				attrs = append(attrs, slog.String(field, body))
+			}
+		case AccessFieldResponseBody:
+			if body := l.redactBody(entry.ResponseBody); body != "" {
+				attrs = append(attrs, slog.String(field, body))
+			}
+		}
+	}
+
+	logger.LogAttrs(ctx, level, "access", attrs...)
+}
+
+// headersAttr groups the configured request headers, redacting sensitive values
+func (l *AccessLogger) headersAttr(headers http.Header) slog.Attr {
+	values := make([]any, 0, len(l.config.Headers)*2)
+	for _, name := range l.config.Headers {
+		value := headers.Get(name)
+		if value == "" {
+			continue
+		}
+		if l.config.Redaction.SensitiveHeader(name) {
+			if l.config.Redaction.Mode() == utils.RedactionDrop {
+				continue
+			}
+			value = l.config.Redaction.Value(value)
+		}
+		values = append(values, strings.ToLower(name), value)
+	}
+	return slog.Group(AccessFieldHeaders, values...)
+}
+
+// redactBody returns the captured body truncated to the body limit. With redaction the
+// fields of JSON bodies are redacted before truncating, other bodies and bodies over
+// maxRedactedBodyBytes cannot be parsed and are redacted as a whole.
+func (l *AccessLogger) redactBody(body *BodyCapture) string {
+	if body == nil || body.buf.Len() == 0 {
+		return ""
+	}
+
+	raw := body.buf.Bytes()
+	truncated := body.Truncated
+	if l.config.Redaction != nil {
+		var value any
+		if truncated || json.Unmarshal(raw, &value) != nil {
+			return l.config.Redaction.Value(string(raw))
+		}
+		redacted, err := json.Marshal(l.config.Redaction.JSONValue(value))
+		if err != nil {
+			return l.config.Redaction.Value(string(raw))
+		}
+		raw = redacted
+	}
+
+	if len(raw) > l.config.BodyLimit {
+		raw, truncated = raw[:l.config.BodyLimit], true
+	}
+	if truncated {
+		return string(raw) + "...[truncated]"
+	}
+	return string(raw)
+}
+
+// Middleware returns net/http middleware writing access log entries. routeFn is called
+// after the handler, when routers like chi have resolved the route template.
+func (l *AccessLogger) Middleware(routeFn func(*http.Request) string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if l.Excluded(r.URL.Path) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			start := time.Now()
+			recorder := NewResponseRecorder(w)
+
+			entry := AccessLogEntry{
+				Method:    r.Method,
+				Path:      r.URL.Path,
+				ClientIP:  ClientIP(r.RemoteAddr),
+				UserAgent: r.UserAgent(),
+				Headers:   r.Header,
+			}
+			if limit := l.RequestBodyLimit(); limit > 0 && r.Body != nil {
+				entry.RequestBody = NewBodyCapture(limit)
+				r.Body = entry.RequestBody.Tee(r.Body)
+			}
+			if limit := l.ResponseBodyLimit(); limit > 0 {
+				entry.ResponseBody = NewBodyCapture(limit)
+				recorder.Body = entry.ResponseBody
+			}
+
+			next.ServeHTTP(recorder, r)
+
+			entry.Route = routeFn(r)
+			entry.Status = recorder.Status
+			entry.Bytes = recorder.BytesWritten
+			entry.Latency = time.Since(start)
+			l.Log(r.Context(), entry)
+		})
+	}
+}
+
+// ClientIP returns the host of a remote address
+func ClientIP(remoteAddr string) string {
+	host, _, err := net.SplitHostPort(remoteAddr)
+	if err != nil {
+		return remoteAddr
+	}
+	return host
+}
+
+// BodyCapture keeps the first bytes of a request or response body, up to a limit
+type BodyCapture struct {
+	limit     int
+	buf       bytes.Buffer
+	Truncated bool
+}
+
+// NewBodyCapture creates a capture of up to limit bytes
+func NewBodyCapture(limit int) *BodyCapture {
+	return &BodyCapture{limit: limit}
+}
+
+// Write captures p up to the limit, it never fails so it can be used with io.TeeReader
+func (b *BodyCapture) Write(p []byte) (int, error) {
+	n := len(p)
+	if remaining := b.limit - b.buf.Len(); remaining < n {
+		b.Truncated = true
+		p = p[:max(remaining, 0)]
+	}
+	b.buf.Write(p)
+	return n, nil
+}
+
+// Bytes returns the captured bytes
+func (b *BodyCapture) Bytes() []byte {
+	return b.buf.Bytes()
+}
+
+// Tee returns a body capturing what the handler reads from body
+func (b *BodyCapture) Tee(body io.ReadCloser) io.ReadCloser {
+	return struct {
+		io.Reader
+		io.Closer
+	}{io.TeeReader(body, b), body}
+}
+
+// windowLimiter admits up to limit events per second, a nil limiter admits all
+type windowLimiter struct {
+	mu     sync.Mutex
+	limit  int
+	window int64
+	count  int
+}
+
+func (w *windowLimiter) allow() bool {
+	if w == nil {
+		return true
+	}
+
+	now := time.Now().Unix()
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if now != w.window {
+		w.window = now
+		w.count = 0
+	}
+	if w.count >= w.limit {
+		return false
+	}
+	w.count++
+	return true
+}
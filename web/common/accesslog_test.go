@@ -0,0 +1,145 @@
+package common
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/wasilak/go-hello-world/utils"
+)
+
+// captureAccessLog sends the default logger output to a buffer for the duration of the test
+func captureAccessLog(t *testing.T) *bytes.Buffer {
+	t.Helper()
+
+	var buf bytes.Buffer
+	previous := slog.Default()
+	slog.SetDefault(slog.New(slog.NewJSONHandler(&buf, nil)))
+	t.Cleanup(func() { slog.SetDefault(previous) })
+	return &buf
+}
+
+// accessLogEntries decodes the JSON log lines in buf
+func accessLogEntries(t *testing.T, buf *bytes.Buffer) []map[string]any {
+	t.Helper()
+
+	var entries []map[string]any
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		if line == "" {
+			continue
+		}
+		var entry map[string]any
+		require.NoError(t, json.Unmarshal([]byte(line), &entry))
+		entries = append(entries, entry)
+	}
+	return entries
+}
+
+func TestAccessLogConfigValidate(t *testing.T) {
+	assert.Nil(t, AccessLogConfig{SampleRate: 1}.Validate())
+	assert.NotNil(t, AccessLogConfig{Fields: []string{"cookies"}, SampleRate: 1}.Validate())
+	assert.NotNil(t, AccessLogConfig{SampleRate: 1.5}.Validate())
+	assert.NotNil(t, AccessLogConfig{SampleRate: 1, RateLimit: -1}.Validate())
+}
+
+func TestAccessLoggerExcluded(t *testing.T) {
+	logger := NewAccessLogger(AccessLogConfig{ExcludePaths: []string{"/health", "/debug/"}})
+
+	assert.True(t, logger.Excluded("/health"))
+	assert.True(t, logger.Excluded("/debug/statsviz"))
+	assert.False(t, logger.Excluded("/healthz"))
+	assert.False(t, logger.Excluded("/"))
+
+	var nilLogger *AccessLogger
+	assert.True(t, nilLogger.Excluded("/"))
+}
+
+func TestAccessLoggerSampling(t *testing.T) {
+	buf := captureAccessLog(t)
+
+	logger := NewAccessLogger(AccessLogConfig{SampleRate: 0})
+	logger.Log(t.Context(), AccessLogEntry{Status: http.StatusOK})
+	logger.Log(t.Context(), AccessLogEntry{Status: http.StatusBadGateway})
+	assert.Len(t, accessLogEntries(t, buf), 1, "Expected only the server error to be logged")
+
+	buf.Reset()
+	logger = NewAccessLogger(AccessLogConfig{SampleRate: 1, RateLimit: 2})
+	for range 5 {
+		logger.Log(t.Context(), AccessLogEntry{Status: http.StatusOK})
+	}
+	assert.Len(t, accessLogEntries(t, buf), 2, "Expected the rate limit to cap the entries")
+}
+
+func TestAccessLoggerMiddleware(t *testing.T) {
+	buf := captureAccessLog(t)
+
+	redactor, appErr := utils.NewRedactor(utils.RedactionConfig{Headers: []string{"authorization"}, Fields: []string{"password"}})
+	require.Nil(t, appErr)
+
+	logger := NewAccessLogger(AccessLogConfig{
+		Fields:     []string{AccessFieldMethod, AccessFieldRoute, AccessFieldStatus, AccessFieldBytes, AccessFieldHeaders, AccessFieldRequestBody, AccessFieldResponseBody},
+		Headers:    []string{"Authorization", "X-Tenant"},
+		SampleRate: 1,
+		BodyLimit:  64,
+		Redaction:  redactor,
+	})
+
+	handler := logger.Middleware(func(*http.Request) string { return "/login" })(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.ReadAll(r.Body)
+		w.WriteHeader(http.StatusCreated)
+		_, _ = w.Write([]byte(`{"data":"` + strings.Repeat("x", 100) + `","token":"t0ken"}`))
+	}))
+
+	req := httptest.NewRequest(http.MethodPost, "/login", strings.NewReader(`{"user":"jane","password":"hunter2"}`))
+	req.Header.Set("Authorization", "Bearer s3cret")
+	req.Header.Set("X-Tenant", "acme")
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	entries := accessLogEntries(t, buf)
+	require.Len(t, entries, 1)
+	entry := entries[0]
+
+	assert.Equal(t, "access", entry["component"])
+	assert.Equal(t, "POST", entry["method"])
+	assert.Equal(t, "/login", entry["route"])
+	assert.Equal(t, float64(http.StatusCreated), entry["status"])
+	assert.Equal(t, float64(127), entry["bytes"])
+	assert.Equal(t, map[string]any{"authorization": redactedValue, "x-tenant": "acme"}, entry["headers"])
+	assert.JSONEq(t, `{"user":"jane","password":"[REDACTED]"}`, entry["request_body"].(string))
+	assert.Equal(t, `{"data":"`+strings.Repeat("x", 55)+"...[truncated]", entry["response_body"])
+	assert.NotContains(t, buf.String(), "hunter2")
+	assert.NotContains(t, buf.String(), "s3cret")
+	assert.NotContains(t, buf.String(), "t0ken")
+}
+
+func TestAccessLoggerBodyRedaction(t *testing.T) {
+	redactor, appErr := utils.NewRedactor(utils.RedactionConfig{Fields: []string{"password"}})
+	require.Nil(t, appErr)
+
+	redacted := NewAccessLogger(AccessLogConfig{Fields: []string{AccessFieldRequestBody}, BodyLimit: 16, Redaction: redactor})
+	plain := NewAccessLogger(AccessLogConfig{Fields: []string{AccessFieldRequestBody}, BodyLimit: 16})
+
+	capture := func(logger *AccessLogger, body string) *BodyCapture {
+		capture := NewBodyCapture(logger.RequestBodyLimit())
+		_, _ = capture.Write([]byte(body))
+		return capture
+	}
+
+	// the secret is beyond the body limit, it is redacted before truncating
+	body := redacted.redactBody(capture(redacted, `{"comment":"`+strings.Repeat("x", 32)+`","password":"hunter2"}`))
+	assert.Equal(t, `{"comment":"xxxx...[truncated]`, body)
+
+	// bodies which cannot be parsed are redacted as a whole
+	assert.Equal(t, redactedValue, redacted.redactBody(capture(redacted, "user=jane&password=hunter2")))
+	assert.Equal(t, redactedValue, redacted.redactBody(capture(redacted, `{"password":"hunter2","data":"`+strings.Repeat("x", maxRedactedBodyBytes)+`"}`)))
+
+	assert.Equal(t, "user=jane&passwo...[truncated]", plain.redactBody(capture(plain, "user=jane&password=hunter2")))
+}
@@ -0,0 +1,273 @@
+package common
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/wasilak/go-hello-world/utils"
+)
+
+const (
+	// ChainModeSequential calls the downstream hops one after another
+	ChainModeSequential = "sequential"
+
+	// ChainModeParallel calls all downstream hops concurrently
+	ChainModeParallel = "parallel"
+
+	// maxChainHops bounds the fan-out of a single /chain request
+	maxChainHops = 16
+
+	// maxChainResponseBytes bounds how much of a downstream response is read
+	maxChainResponseBytes = 1 << 20
+
+	// ChainDepthHeader carries the number of /chain hops made before a request, each hop
+	// sends the incremented value so cycles and deep recursion are stopped
+	ChainDepthHeader = "X-Chain-Depth"
+
+	// DefaultChainMaxDepth is the depth above which /chain requests are rejected
+	DefaultChainMaxDepth = 8
+)
+
+// ChainConfig configures the downstream calls made by the /chain route
+type ChainConfig struct {
+	Hops    []string
+	Mode    string
+	Timeout time.Duration
+	Client  *http.Client
+
+	// MaxDepth is the highest ChainDepthHeader value accepted, 0 uses DefaultChainMaxDepth
+	MaxDepth int
+
+	// AllowedHops lists the downstreams hop query parameters may call besides Hops: hosts,
+	// with or without a port, or URL prefixes. Other hops are rejected, so clients can't
+	// make the service call arbitrary addresses.
+	AllowedHops []string
+}
+
+// ChainRequest is a validated /chain request
+type ChainRequest struct {
+	Hops  []string
+	Mode  string
+	Depth int
+}
+
+// ChainResponse type
+type ChainResponse struct {
+	Host      string     `json:"host"`
+	Framework string     `json:"framework"`
+	TraceID   string     `json:"trace_id,omitempty"`
+	Mode      string     `json:"mode"`
+	Hops      []ChainHop `json:"hops"`
+}
+
+// ChainHop type
+type ChainHop struct {
+	URL        string     `json:"url"`
+	StatusCode int        `json:"status_code,omitempty"`
+	LatencyMS  float64    `json:"latency_ms"`
+	Host       string     `json:"host,omitempty"`
+	Framework  string     `json:"framework,omitempty"`
+	Hops       []ChainHop `json:"hops,omitempty"`
+	Error      string     `json:"error,omitempty"`
+}
+
+// NewChainClient returns an HTTP client instrumented with otelhttp, using the shared
+// tracing configuration so context is propagated in the configured formats
+func NewChainClient(tracing TracingConfig, timeout time.Duration) *http.Client {
+	opts := []otelhttp.Option{
+		otelhttp.WithPropagators(tracing.GetPropagators()),
+	}
+	if tracing.TracerProvider != nil {
+		opts = append(opts, otelhttp.WithTracerProvider(tracing.TracerProvider))
+	}
+
+	return &http.Client{
+		Transport: otelhttp.NewTransport(http.DefaultTransport, opts...),
+		Timeout:   timeout,
+		// redirects are reported instead of followed, they could lead to any address
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
+
+// allowsHop reports whether a hop from the query may be called: a configured hop, or one
+// matching an allowed host or URL prefix
+func (c ChainConfig) allowsHop(hop string, parsed *url.URL) bool {
+	if slices.Contains(c.Hops, hop) {
+		return true
+	}
+
+	for _, allowed := range c.AllowedHops {
+		if prefix, ok := strings.CutSuffix(allowed, "/"); ok || strings.Contains(allowed, "://") {
+			// the prefix ends at a path segment, so http://svc:3000 doesn't allow http://svc:30001
+			if rest, found := strings.CutPrefix(hop, prefix); found && (rest == "" || strings.ContainsAny(rest[:1], "/?#")) {
+				return true
+			}
+			continue
+		}
+		if strings.EqualFold(allowed, parsed.Host) || strings.EqualFold(allowed, parsed.Hostname()) {
+			return true
+		}
+	}
+	return false
+}
+
+// ParseChainRequest reads the hop list and mode from the query, falling back to the configured
+// topology, and the depth from the ChainDepthHeader value
+func (c ChainConfig) ParseChainRequest(query url.Values, depthHeader string) (ChainRequest, *utils.AppError) {
+	depth := 0
+	if depthHeader != "" {
+		var err error
+		if depth, err = strconv.Atoi(depthHeader); err != nil || depth < 0 {
+			return ChainRequest{}, utils.NewAppError(utils.ValidationError, "invalid chain depth", err).WithCode("validation.chain_depth").AddContext(utils.ContextDepth, depthHeader)
+		}
+	}
+	maxDepth := c.MaxDepth
+	if maxDepth <= 0 {
+		maxDepth = DefaultChainMaxDepth
+	}
+	if depth > maxDepth {
+		return ChainRequest{}, utils.NewAppError(utils.ValidationError, "chain depth limit exceeded", nil).WithCode("validation.chain_depth").AddContext(utils.ContextDepth, depth)
+	}
+
+	hops := query["hop"]
+	if len(hops) == 0 {
+		hops = c.Hops
+	}
+
+	mode := strings.ToLower(query.Get("mode"))
+	if mode == "" {
+		mode = c.Mode
+	}
+	if mode == "" {
+		mode = ChainModeSequential
+	}
+
+	if mode != ChainModeSequential && mode != ChainModeParallel {
+		return ChainRequest{}, utils.NewAppError(utils.ValidationError, "invalid chain mode", nil).WithCode("validation.chain_mode").AddContext(utils.ContextMode, mode)
+	}
+
+	if len(hops) > maxChainHops {
+		return ChainRequest{}, utils.NewAppError(utils.ValidationError, "too many chain hops", nil).WithCode("validation.chain_hops").AddContext(utils.ContextHops, len(hops))
+	}
+
+	for _, hop := range hops {
+		parsed, err := url.Parse(hop)
+		if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+			return ChainRequest{}, utils.NewAppError(utils.ValidationError, "invalid chain hop URL", err).WithCode("validation.chain_hop_url").AddContext("hop", hop)
+		}
+		if !c.allowsHop(hop, parsed) {
+			return ChainRequest{}, utils.NewAppError(utils.ValidationError, "chain hop not allowed", nil).WithCode("validation.chain_hop_not_allowed").AddContext("hop", hop)
+		}
+	}
+
+	return ChainRequest{Hops: hops, Mode: mode, Depth: depth}, nil
+}
+
+// SetChainResponse calls the downstream hops and aggregates their responses into a tree
+func (w *WebServer) SetChainResponse(ctx context.Context, request ChainRequest) ChainResponse {
+	ctx, span := w.FrameworkOptions.Tracer.Start(ctx, "chainResponse")
+	defer span.End()
+
+	hostname, _ := os.Hostname()
+	response := ChainResponse{
+		Host:      hostname,
+		Framework: w.Framework,
+		Mode:      request.Mode,
+		Hops:      make([]ChainHop, len(request.Hops)),
+	}
+
+	if spanContext := trace.SpanContextFromContext(ctx); spanContext.HasTraceID() {
+		response.TraceID = spanContext.TraceID().String()
+	}
+
+	client := w.FrameworkOptions.Chain.Client
+	if client == nil {
+		client = NewChainClient(w.FrameworkOptions.Tracing, w.FrameworkOptions.Chain.Timeout)
+	}
+
+	requestIDHeader := w.FrameworkOptions.RequestID.HeaderName()
+	if request.Mode == ChainModeParallel {
+		var wg sync.WaitGroup
+		for i, hop := range request.Hops {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				response.Hops[i] = callChainHop(ctx, client, hop, requestIDHeader, request.Depth+1)
+			}()
+		}
+		wg.Wait()
+	} else {
+		for i, hop := range request.Hops {
+			response.Hops[i] = callChainHop(ctx, client, hop, requestIDHeader, request.Depth+1)
+		}
+	}
+
+	utils.ComponentLogger(utils.ComponentHandlers).DebugContext(ctx, "chain_completed", "mode", request.Mode, "hops", len(request.Hops), "depth", request.Depth)
+
+	return response
+}
+
+// callChainHop calls a single downstream and decodes the host, framework and nested hops
+// from its JSON response, which both the main route and the chain route provide. The
+// request ID is propagated in requestIDHeader and depth in ChainDepthHeader.
+func callChainHop(ctx context.Context, client *http.Client, hop, requestIDHeader string, depth int) (result ChainHop) {
+	result.URL = hop
+	start := time.Now()
+
+	defer func() {
+		result.LatencyMS = float64(time.Since(start).Microseconds()) / 1000
+	}()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, hop, nil)
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set(ChainDepthHeader, strconv.Itoa(depth))
+	if id := utils.RequestIDFromContext(ctx); id != "" {
+		req.Header.Set(requestIDHeader, id)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		appErr := utils.WrapError(err, utils.RuntimeError, "chain hop request failed").WithCode("runtime.chain_hop").WithRetryable(true)
+		appErr.AddContext("hop", hop)
+		appErr.LogError(ctx)
+		result.Error = err.Error()
+		return result
+	}
+	defer resp.Body.Close()
+
+	result.StatusCode = resp.StatusCode
+
+	var downstream struct {
+		Host      string     `json:"host"`
+		Framework string     `json:"framework"`
+		Hops      []ChainHop `json:"hops"`
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, maxChainResponseBytes)).Decode(&downstream); err != nil {
+		result.Error = "non JSON response: " + err.Error()
+		return result
+	}
+
+	result.Host = downstream.Host
+	result.Framework = downstream.Framework
+	result.Hops = downstream.Hops
+
+	return result
+}
@@ -2,13 +2,16 @@ package common
 
 import (
 	"context"
+	"fmt"
 	"log/slog"
+	"net"
 	"net/http"
 	"net/url"
 	"os"
 	"sync"
+	"time"
 
-	loggergoLib "github.com/wasilak/loggergo/lib"
+	"github.com/wasilak/go-hello-world/utils"
 	"go.opentelemetry.io/otel/trace"
 )
 
@@ -19,8 +22,18 @@ type HealthResponse struct {
 
 // LoggerResponse type
 type LoggerResponse struct {
-	LogLevelCurrent  string `json:"log_level_current"`
-	LogLevelPrevious string `json:"log_level_previous"`
+	Logger           string     `json:"logger"`
+	LogLevelCurrent  string     `json:"log_level_current"`
+	LogLevelPrevious string     `json:"log_level_previous"`
+	ExpiresAt        *time.Time `json:"expires_at,omitempty"`
+}
+
+// LogLevelRequest changes the level of a logger, the default, a component or "route:/path".
+// An empty level only reports the current level, a TTL such as "15m" reverts the change.
+type LogLevelRequest struct {
+	Logger string `json:"logger"`
+	Level  string `json:"level"`
+	TTL    string `json:"ttl"`
 }
 
 // FrameworkResponse type
@@ -41,11 +54,27 @@ type APIResponseRequest struct {
 	Headers    http.Header `json:"headers"`
 }
 
+// TraceContextResponse type
+type TraceContextResponse struct {
+	Fields     []string          `json:"fields"`
+	Valid      bool              `json:"valid"`
+	TraceID    string            `json:"trace_id,omitempty"`
+	SpanID     string            `json:"span_id,omitempty"`
+	Sampled    bool              `json:"sampled"`
+	TraceState string            `json:"trace_state,omitempty"`
+	Baggage    map[string]string `json:"baggage,omitempty"`
+}
+
 // APIResponse type
 type APIResponse struct {
-	Host      string             `json:"host"`
-	Framework string             `json:"framework"`
-	Request   APIResponseRequest `json:"request"`
+	Host         string               `json:"host"`
+	Framework    string               `json:"framework"`
+	Version      string               `json:"version"`
+	Commit       string               `json:"commit,omitempty"`
+	RequestID    string               `json:"request_id,omitempty"`
+	Instance     *InstanceInfo        `json:"instance,omitempty"`
+	Request      APIResponseRequest   `json:"request"`
+	TraceContext TraceContextResponse `json:"trace_context"`
 }
 
 type FrameworkOptions struct {
@@ -54,7 +83,25 @@ type FrameworkOptions struct {
 	StatsvizEnabled bool
 	Tracer          trace.Tracer
 	LogLevelConfig  *slog.LevelVar
-	TraceProvider   trace.TracerProvider
+	LogLevels       *utils.LevelRegistry
+	Tracing         TracingConfig
+	HTTPMetrics     *HTTPServerMetrics
+	Chain           ChainConfig
+	Proxy           ProxyConfig
+	Problems        ProblemConfig
+	Admin           AdminConfig
+	Runtime         *RuntimeState
+	AccessLog       *AccessLogger
+	Redaction       *utils.Redactor
+	RequestID       RequestIDConfig
+	RateLimit       *RateLimiter
+	Concurrency     *ConcurrencyLimiter
+	Server          ServerConfig
+	Instance        *InstanceInfo
+	Middleware      *MiddlewareRegistry
+	Compression     *Compressor
+	CORS            *CORSPolicy
+	SecurityHeaders *SecurityHeaders
 }
 
 type WebServer struct {
@@ -62,75 +109,234 @@ type WebServer struct {
 	Running          bool
 	Framework        string
 	FrameworkOptions FrameworkOptions
+
+	errs     chan error
+	errsOnce sync.Once
+
+	dashboard     *Dashboard
+	dashboardOnce sync.Once
 }
 
+// WebServerInterface is implemented by every framework. Start binds the listener and
+// returns its errors, failures of the running server are reported on Errors.
 type WebServerInterface interface {
-	Start(context.Context)
+	Start(ctx context.Context) error
 	Stop(ctx context.Context)
+	Errors() <-chan error
+}
+
+// Listen binds the listen address, so a failure is returned by Start instead of being
+// discovered by the serving goroutine
+func (w *WebServer) Listen() (net.Listener, error) {
+	listener, err := net.Listen("tcp", w.FrameworkOptions.ListenAddr)
+	if err != nil {
+		return nil, utils.WrapError(err, utils.FrameworkError, "failed to listen").
+			WithCode("framework.listen").
+			WithRetryable(true).
+			AddContext(utils.ContextFramework, w.Framework).
+			AddContext("address", w.FrameworkOptions.ListenAddr)
+	}
+	return listener, nil
+}
+
+// Errors returns the channel the failure of the running server is reported on
+func (w *WebServer) Errors() <-chan error {
+	return w.errors()
+}
+
+func (w *WebServer) errors() chan error {
+	w.errsOnce.Do(func() {
+		w.errs = make(chan error, 1)
+	})
+	return w.errs
+}
+
+// Dashboard returns the dashboard of the server, its streams are closed when the server stops
+func (w *WebServer) Dashboard() *Dashboard {
+	w.dashboardOnce.Do(func() {
+		w.dashboard = NewDashboard(w.FrameworkOptions)
+	})
+	return w.dashboard
+}
+
+// Fail reports that the running server exited with err, only the first failure is kept
+func (w *WebServer) Fail(err error) {
+	appErr := utils.WrapError(err, utils.FrameworkError, "server exited with error").
+		WithCode("framework.serve").
+		WithRetryable(true).
+		AddContext(utils.ContextFramework, w.Framework)
+
+	select {
+	case w.errors() <- appErr:
+	default:
+	}
+}
+
+// FrameworkSwitch requests a switch to Framework. When Result is not nil, it must be
+// buffered and receives the outcome of the switch.
+type FrameworkSwitch struct {
+	Framework string
+	Result    chan<- error
 }
 
 // Create a channel to signal framework changes
 var (
-	FrameworkChannel chan string
+	FrameworkChannel chan FrameworkSwitch
 )
 
+// SwitchFramework requests a switch to framework and waits for its outcome, the error of
+// a failed or rolled back switch
+func SwitchFramework(ctx context.Context, framework string) error {
+	result := make(chan error, 1)
+	select {
+	case FrameworkChannel <- FrameworkSwitch{Framework: framework, Result: result}:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	select {
+	case err := <-result:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// SetMainResponse describes the request, with sensitive headers and query parameters redacted
 func (w *WebServer) SetMainResponse(ctx context.Context, r *http.Request) APIResponse {
 	_, span := w.FrameworkOptions.Tracer.Start(ctx, "response")
 	hostname, _ := os.Hostname()
+	redaction := w.FrameworkOptions.Redaction
 	response := APIResponse{
 		Host:      hostname,
 		Framework: w.Framework,
+		Version:   utils.GetBuildInfo().Version,
+		Commit:    utils.GetBuildInfo().ShortCommit(),
+		RequestID: utils.RequestIDFromContext(ctx),
+		Instance:  w.FrameworkOptions.Instance,
 		Request: APIResponseRequest{
 			Host:       r.Host,
-			URL:        r.URL,
+			URL:        redaction.URL(r.URL),
 			RemoteAddr: r.RemoteAddr,
-			RequestURI: r.RequestURI,
+			RequestURI: redaction.RequestURI(r.RequestURI),
 			Method:     r.Method,
 			Proto:      r.Proto,
 			UserAgent:  r.UserAgent(),
-			Headers:    r.Header,
+			Headers:    redaction.Headers(r.Header),
 		},
+		TraceContext: ExtractTraceContext(w.FrameworkOptions.Tracing.GetPropagators(), r.Header),
 	}
 	span.End()
 	return response
 }
 
-func (w *WebServer) SetLogLevelResponse(ctx context.Context, current string) LoggerResponse {
-	ctx, span := w.FrameworkOptions.Tracer.Start(ctx, "logLevelResponse")
+// LevelRegistry returns the log level registry, a registry over LogLevelConfig when none is
+// configured, in which case only the default level takes effect
+func (o FrameworkOptions) LevelRegistry() *utils.LevelRegistry {
+	if o.LogLevels != nil {
+		return o.LogLevels
+	}
+	return utils.NewLevelRegistry(o.LogLevelConfig)
+}
 
-	response := LoggerResponse{
-		LogLevelCurrent: w.FrameworkOptions.LogLevelConfig.Level().String(),
+// SetLogLevel validates the request and changes the level of the logger in registry
+func SetLogLevel(registry *utils.LevelRegistry, req LogLevelRequest) (LoggerResponse, *utils.AppError) {
+	response := LoggerResponse{Logger: req.Logger}
+	if response.Logger == "" {
+		response.Logger = utils.DefaultLogger
 	}
 
-	newLogLevel := loggergoLib.LogLevelFromString(current)
+	var ttl time.Duration
+	if req.TTL != "" {
+		var err error
+		if ttl, err = time.ParseDuration(req.TTL); err != nil || ttl < 0 {
+			return response, utils.WrapError(err, utils.ValidationError, "invalid log level ttl, expected a duration such as 15m").
+				WithCode("validation.log_level_ttl").
+				AddContext(utils.ContextTTL, req.TTL)
+		}
+	}
 
-	w.FrameworkOptions.LogLevelConfig.Set(newLogLevel)
+	if req.Level == "" {
+		current := registry.LoggerLevel(response.Logger)
+		response.LogLevelCurrent = current.Level
+		response.LogLevelPrevious = current.Level
+		response.ExpiresAt = current.ExpiresAt
+		return response, nil
+	}
 
-	response.LogLevelPrevious = w.FrameworkOptions.LogLevelConfig.Level().String()
+	level, appErr := utils.ParseLevel(req.Level)
+	if appErr != nil {
+		return response, appErr
+	}
 
-	slog.DebugContext(ctx, "log_level_changed", "from", response.LogLevelPrevious, "to", response.LogLevelCurrent)
+	previous, appErr := registry.Set(response.Logger, level, ttl)
+	if appErr != nil {
+		return response, appErr
+	}
 
-	span.End()
+	current := registry.LoggerLevel(response.Logger)
+	response.LogLevelCurrent = current.Level
+	response.LogLevelPrevious = previous.String()
+	response.ExpiresAt = current.ExpiresAt
+	return response, nil
+}
 
-	return response
+func (w *WebServer) SetLogLevelResponse(ctx context.Context, req LogLevelRequest) (LoggerResponse, *utils.AppError) {
+	ctx, span := w.FrameworkOptions.Tracer.Start(ctx, "logLevelResponse")
+	defer span.End()
+
+	registry := w.FrameworkOptions.LevelRegistry()
+
+	// With the admin API enabled, state changes are only accepted on the admin listener
+	if w.FrameworkOptions.Admin.Enabled() && req.Level != "" {
+		return LoggerResponse{}, adminOnlyError("log level changes", "PUT /admin/log-level").AddContext(utils.ContextLogger, req.Logger)
+	}
+
+	response, appErr := SetLogLevel(registry, req)
+	if appErr != nil || req.Level == "" {
+		return response, appErr
+	}
+
+	utils.ComponentLogger(utils.ComponentHandlers).DebugContext(ctx, "log_level_changed", "logger", response.Logger, "from", response.LogLevelPrevious, "to", response.LogLevelCurrent, "ttl", req.TTL)
+
+	return response, nil
 }
 
-func (w *WebServer) SetFrameworkResponse(ctx context.Context, current string) FrameworkResponse {
+func (w *WebServer) SetFrameworkResponse(ctx context.Context, current string) (FrameworkResponse, *utils.AppError) {
 	ctx, span := w.FrameworkOptions.Tracer.Start(ctx, "FrameworkResponse")
 
 	response := FrameworkResponse{
 		FrameworkPrevious: w.Framework,
 	}
 
+	// With the admin API enabled, state changes are only accepted on the admin listener
+	if w.FrameworkOptions.Admin.Enabled() {
+		span.End()
+		if current != "" {
+			return response, adminOnlyError("framework switches", "PUT /admin/framework").AddContext(utils.ContextFramework, w.Framework)
+		}
+		response.FrameworkCurrent = w.Framework
+		return response, nil
+	}
+
+	// the switch stops this server, so its outcome can't be waited for here
 	if w.Framework != current {
-		FrameworkChannel <- current
+		FrameworkChannel <- FrameworkSwitch{Framework: current}
 	}
 
 	response.FrameworkCurrent = current
 
-	slog.DebugContext(ctx, "framework_not_changed", "from", response.FrameworkPrevious, "to", response.FrameworkCurrent)
+	utils.ComponentLogger(utils.ComponentHandlers).DebugContext(ctx, "framework_not_changed", "from", response.FrameworkPrevious, "to", response.FrameworkCurrent)
 
 	span.End()
 
-	return response
+	return response, nil
+}
+
+// adminOnlyError rejects a state change on the public listener while the admin API is
+// enabled, pointing the client to the admin endpoint accepting it
+func adminOnlyError(change, endpoint string) *utils.AppError {
+	return utils.NewAppError(utils.ForbiddenError, fmt.Sprintf("%s are only accepted by the admin API, use %s", change, endpoint), nil).
+		WithCode("forbidden.admin_only").
+		WithSeverity(utils.SeverityInfo)
 }
@@ -0,0 +1,453 @@
+package common
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"fmt"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/andybalholm/brotli"
+	"github.com/klauspost/compress/zstd"
+
+	"github.com/wasilak/go-hello-world/utils"
+)
+
+const (
+	// EncodingGzip compresses with gzip
+	EncodingGzip = "gzip"
+
+	// EncodingDeflate compresses with deflate in the zlib format, as HTTP defines it
+	EncodingDeflate = "deflate"
+
+	// EncodingBrotli compresses with brotli
+	EncodingBrotli = "br"
+
+	// EncodingZstd compresses with Zstandard
+	EncodingZstd = "zstd"
+)
+
+// CompressionEncodings lists the supported encodings in the default order of preference
+func CompressionEncodings() []string {
+	return []string{EncodingBrotli, EncodingZstd, EncodingGzip, EncodingDeflate}
+}
+
+// DefaultCompressionLevels returns the default level of every encoding
+func DefaultCompressionLevels() map[string]int {
+	return map[string]int{
+		EncodingGzip:    5,
+		EncodingDeflate: 5,
+		EncodingBrotli:  4,
+		EncodingZstd:    3,
+	}
+}
+
+// DefaultCompressionContentTypes lists the media types compressed by default, event
+// streams are left out so proxies do not buffer them
+func DefaultCompressionContentTypes() []string {
+	return []string{
+		"text/html", "text/plain", "text/css", "text/csv", "text/javascript", "text/xml",
+		"application/json", "application/problem+json", "application/javascript",
+		"application/xml", "application/yaml", "image/svg+xml",
+	}
+}
+
+// compressionLevelRanges are the valid levels of every encoding
+var compressionLevelRanges = map[string][2]int{
+	EncodingGzip:    {gzip.HuffmanOnly, gzip.BestCompression},
+	EncodingDeflate: {zlib.HuffmanOnly, zlib.BestCompression},
+	EncodingBrotli:  {brotli.BestSpeed, brotli.BestCompression},
+	EncodingZstd:    {1, 22},
+}
+
+// CompressionConfig configures the response compression of every framework
+type CompressionConfig struct {
+	// Encodings are offered in order of preference, which breaks ties between the
+	// encodings a client accepts with the same quality
+	Encodings []string
+
+	// Levels of the encodings, missing ones use the default level
+	Levels map[string]int
+
+	// MinSize is the smallest response body compressed, in bytes
+	MinSize int
+
+	// ContentTypes are the compressed media types, "text/*" matches all text types
+	ContentTypes []string
+}
+
+// Validate checks the compression configuration
+func (c CompressionConfig) Validate() *utils.AppError {
+	for _, encoding := range c.Encodings {
+		if !slices.Contains(CompressionEncodings(), encoding) {
+			return utils.NewAppError(utils.ValidationError, fmt.Sprintf("compression encodings must be %s", strings.Join(CompressionEncodings(), ", ")), nil).
+				WithCode("validation.compression_encoding").
+				AddContext(utils.ContextEncoding, encoding)
+		}
+	}
+	for encoding, level := range c.Levels {
+		levels, ok := compressionLevelRanges[encoding]
+		if !ok || level < levels[0] || level > levels[1] {
+			return utils.NewAppError(utils.ValidationError, "compression level out of range", nil).
+				WithCode("validation.compression_level").
+				AddContext(utils.ContextEncoding, encoding).
+				AddContext(utils.ContextLevel, level)
+		}
+	}
+	if c.MinSize < 0 {
+		return utils.NewAppError(utils.ValidationError, "compression minimum size must not be negative", nil).WithCode("validation.compression_min_size")
+	}
+	return nil
+}
+
+// ParseCompressionConfig builds and validates the configuration from the command line
+// values, levels are "encoding=level"
+func ParseCompressionConfig(encodings, levels []string, minSize int, contentTypes []string) (CompressionConfig, *utils.AppError) {
+	config := CompressionConfig{
+		Encodings:    encodings,
+		Levels:       DefaultCompressionLevels(),
+		MinSize:      minSize,
+		ContentTypes: contentTypes,
+	}
+
+	for _, value := range levels {
+		encoding, rawLevel, found := strings.Cut(value, "=")
+		level, err := strconv.Atoi(strings.TrimSpace(rawLevel))
+		if !found || err != nil {
+			return CompressionConfig{}, utils.NewAppError(utils.ValidationError, "compression levels must be encoding=level", nil).
+				WithCode("validation.compression_level").
+				AddContext(utils.ContextLevel, value)
+		}
+		config.Levels[strings.TrimSpace(encoding)] = level
+	}
+
+	return config, config.Validate()
+}
+
+// encoder is implemented by the writers of every encoding
+type encoder interface {
+	io.WriteCloser
+	Flush() error
+	Reset(w io.Writer)
+}
+
+// Compressor negotiates the encoding of responses and compresses them with pooled
+// encoders. A nil compressor never compresses.
+type Compressor struct {
+	config CompressionConfig
+	pools  map[string]*sync.Pool
+}
+
+// NewCompressor creates the compressor, the configuration must be valid
+func NewCompressor(config CompressionConfig) *Compressor {
+	c := &Compressor{config: config, pools: make(map[string]*sync.Pool, len(config.Encodings))}
+
+	for _, encoding := range config.Encodings {
+		level, ok := config.Levels[encoding]
+		if !ok {
+			level = DefaultCompressionLevels()[encoding]
+		}
+
+		var newEncoder func() encoder
+		switch encoding {
+		case EncodingGzip:
+			newEncoder = func() encoder {
+				w, _ := gzip.NewWriterLevel(io.Discard, level)
+				return w
+			}
+		case EncodingDeflate:
+			newEncoder = func() encoder {
+				w, _ := zlib.NewWriterLevel(io.Discard, level)
+				return w
+			}
+		case EncodingBrotli:
+			newEncoder = func() encoder {
+				return brotli.NewWriterLevel(io.Discard, level)
+			}
+		case EncodingZstd:
+			newEncoder = func() encoder {
+				// encode synchronously, responses are compressed concurrently anyway
+				w, _ := zstd.NewWriter(io.Discard,
+					zstd.WithEncoderLevel(zstd.EncoderLevelFromZstd(level)),
+					zstd.WithEncoderConcurrency(1),
+					zstd.WithLowerEncoderMem(true),
+				)
+				return w
+			}
+		}
+		c.pools[encoding] = &sync.Pool{New: func() any { return newEncoder() }}
+	}
+	return c
+}
+
+// Config returns the compression configuration
+func (c *Compressor) Config() CompressionConfig {
+	if c == nil {
+		return CompressionConfig{}
+	}
+	return c.config
+}
+
+// Negotiate returns the encoding of a response to a request with acceptEncoding, the
+// most preferred of those the client accepts with the highest quality, or "" for
+// an uncompressed response
+func (c *Compressor) Negotiate(acceptEncoding string) string {
+	if c == nil || acceptEncoding == "" {
+		return ""
+	}
+
+	qualities := make(map[string]float64)
+	for _, part := range strings.Split(acceptEncoding, ",") {
+		name, params, _ := strings.Cut(part, ";")
+		name = strings.ToLower(strings.TrimSpace(name))
+
+		quality := 1.0
+		if key, value, found := strings.Cut(strings.TrimSpace(params), "="); found && strings.TrimSpace(key) == "q" {
+			parsed, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+			if err != nil {
+				continue
+			}
+			quality = parsed
+		}
+		if name != "" {
+			qualities[name] = quality
+		}
+	}
+
+	best, bestQuality := "", 0.0
+	for _, encoding := range c.config.Encodings {
+		quality, ok := qualities[encoding]
+		if !ok {
+			quality, ok = qualities["*"]
+		}
+		if ok && quality > bestQuality {
+			best, bestQuality = encoding, quality
+		}
+	}
+	return best
+}
+
+// Compressible reports whether responses with contentType are compressed
+func (c *Compressor) Compressible(contentType string) bool {
+	if c == nil {
+		return false
+	}
+
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	return slices.ContainsFunc(c.config.ContentTypes, func(allowed string) bool {
+		if prefix, ok := strings.CutSuffix(allowed, "/*"); ok {
+			return strings.HasPrefix(mediaType, prefix+"/")
+		}
+		return mediaType == allowed
+	})
+}
+
+// Eligible reports whether a response with the status and headers may be compressed,
+// regardless of its size. Responses already encoded, partial or without a body are not.
+func (c *Compressor) Eligible(status int, contentType, contentEncoding, contentRange string) bool {
+	if status < http.StatusOK || status == http.StatusNoContent || status == http.StatusNotModified {
+		return false
+	}
+	return contentEncoding == "" && contentRange == "" && c.Compressible(contentType)
+}
+
+// AddVary adds name to the Vary header unless it is listed already
+func AddVary(header http.Header, name string) {
+	for _, value := range header.Values("Vary") {
+		for _, field := range strings.Split(value, ",") {
+			if strings.EqualFold(strings.TrimSpace(field), name) {
+				return
+			}
+		}
+	}
+	header.Add("Vary", name)
+}
+
+// Encode compresses body with encoding, for frameworks buffering whole responses
+func (c *Compressor) Encode(encoding string, body []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	buf.Grow(len(body) / 2)
+
+	enc := c.get(encoding, &buf)
+	defer c.put(encoding, enc)
+
+	if _, err := enc.Write(body); err != nil {
+		return nil, err
+	}
+	if err := enc.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (c *Compressor) get(encoding string, w io.Writer) encoder {
+	enc := c.pools[encoding].Get().(encoder)
+	enc.Reset(w)
+	return enc
+}
+
+func (c *Compressor) put(encoding string, enc encoder) {
+	enc.Reset(io.Discard)
+	c.pools[encoding].Put(enc)
+}
+
+// Middleware compresses the responses of net/http handlers
+func (c *Compressor) Middleware() func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			// upgraded connections, e.g. the statsviz websocket, are hijacked
+			if c == nil || r.Header.Get("Upgrade") != "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			cw := c.NewResponseWriter(w, c.Negotiate(r.Header.Get("Accept-Encoding")))
+			defer func() {
+				// a panicking handler leaves the response to the recovery middleware, closing
+				// would commit the held back response with its status
+				if p := recover(); p != nil {
+					panic(p)
+				}
+				_ = cw.Close()
+			}()
+			next.ServeHTTP(cw, r)
+		})
+	}
+}
+
+// NewResponseWriter returns a writer compressing the response written to w with
+// encoding, "" leaves it uncompressed. It must be closed once the response is written.
+func (c *Compressor) NewResponseWriter(w http.ResponseWriter, encoding string) *CompressResponseWriter {
+	return &CompressResponseWriter{ResponseWriter: w, compressor: c, encoding: encoding, status: http.StatusOK}
+}
+
+// CompressResponseWriter holds the response back until the minimum size is reached,
+// the handler flushes or the response ends, then writes it compressed when it is
+// eligible, or as it is
+type CompressResponseWriter struct {
+	http.ResponseWriter
+	compressor *Compressor
+	encoding   string
+
+	status  int
+	buf     []byte
+	decided bool
+	encoder encoder
+}
+
+// WriteHeader records the status, the headers are written once the encoding is decided
+func (w *CompressResponseWriter) WriteHeader(status int) {
+	if status >= 100 && status < 200 && status != http.StatusSwitchingProtocols {
+		w.ResponseWriter.WriteHeader(status)
+		return
+	}
+	if !w.decided {
+		w.status = status
+	}
+}
+
+func (w *CompressResponseWriter) Write(b []byte) (int, error) {
+	if !w.decided {
+		w.buf = append(w.buf, b...)
+		if len(w.buf) < w.compressor.config.MinSize {
+			return len(b), nil
+		}
+		if err := w.decide(true); err != nil {
+			return 0, err
+		}
+		return len(b), nil
+	}
+
+	if w.encoder != nil {
+		return w.encoder.Write(b)
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+// Flush decides the encoding of streamed responses without waiting for the minimum
+// size, and flushes the compressed data written so far
+func (w *CompressResponseWriter) Flush() {
+	if !w.decided {
+		if err := w.decide(true); err != nil {
+			return
+		}
+	}
+	if w.encoder != nil {
+		_ = w.encoder.Flush()
+	}
+	_ = http.NewResponseController(w.ResponseWriter).Flush()
+}
+
+// Close decides the encoding of responses shorter than the minimum size and finishes
+// the compressed stream
+func (w *CompressResponseWriter) Close() error {
+	if !w.decided {
+		if err := w.decide(false); err != nil {
+			return err
+		}
+	}
+	if w.encoder != nil {
+		err := w.encoder.Close()
+		w.compressor.put(w.encoding, w.encoder)
+		w.encoder = nil
+		return err
+	}
+	return nil
+}
+
+// Hijack lets upgraded connections through when the middleware did not skip them
+func (w *CompressResponseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	return http.NewResponseController(w.ResponseWriter).Hijack()
+}
+
+// Unwrap returns the wrapped writer for http.ResponseController
+func (w *CompressResponseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// decide writes the headers, compressed when sized is true and the response is
+// eligible, and the body held back so far
+func (w *CompressResponseWriter) decide(sized bool) error {
+	w.decided = true
+	header := w.Header()
+
+	contentType := header.Get("Content-Type")
+	if contentType == "" && len(w.buf) > 0 {
+		contentType = http.DetectContentType(w.buf)
+		header.Set("Content-Type", contentType)
+	}
+
+	if w.compressor.Eligible(w.status, contentType, header.Get("Content-Encoding"), header.Get("Content-Range")) {
+		AddVary(header, "Accept-Encoding")
+		if sized && w.encoding != "" {
+			header.Del("Content-Length")
+			header.Set("Content-Encoding", w.encoding)
+			w.encoder = w.compressor.get(w.encoding, w.ResponseWriter)
+		}
+	}
+
+	w.ResponseWriter.WriteHeader(w.status)
+
+	buf := w.buf
+	w.buf = nil
+	if len(buf) == 0 {
+		return nil
+	}
+	if w.encoder != nil {
+		_, err := w.encoder.Write(buf)
+		return err
+	}
+	_, err := w.ResponseWriter.Write(buf)
+	return err
+}
@@ -0,0 +1,241 @@
+package common
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/andybalholm/brotli"
+	"github.com/klauspost/compress/zstd"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// decodeBody decompresses body encoded with encoding
+func decodeBody(t *testing.T, encoding string, body []byte) string {
+	t.Helper()
+
+	var r io.Reader
+	switch encoding {
+	case EncodingGzip:
+		gr, err := gzip.NewReader(bytes.NewReader(body))
+		require.NoError(t, err)
+		r = gr
+	case EncodingDeflate:
+		zr, err := zlib.NewReader(bytes.NewReader(body))
+		require.NoError(t, err)
+		r = zr
+	case EncodingBrotli:
+		r = brotli.NewReader(bytes.NewReader(body))
+	case EncodingZstd:
+		zr, err := zstd.NewReader(bytes.NewReader(body))
+		require.NoError(t, err)
+		defer zr.Close()
+		r = zr
+	default:
+		return string(body)
+	}
+
+	decoded, err := io.ReadAll(r)
+	require.NoError(t, err)
+	return string(decoded)
+}
+
+func newTestCompressor(t *testing.T, minSize int) *Compressor {
+	t.Helper()
+	config, appErr := ParseCompressionConfig(CompressionEncodings(), nil, minSize, DefaultCompressionContentTypes())
+	require.Nil(t, appErr)
+	return NewCompressor(config)
+}
+
+func TestParseCompressionConfig(t *testing.T) {
+	config, appErr := ParseCompressionConfig([]string{EncodingZstd, EncodingGzip}, []string{"gzip=9", "zstd = 1"}, 512, []string{"text/*"})
+	require.Nil(t, appErr)
+	assert.Equal(t, []string{EncodingZstd, EncodingGzip}, config.Encodings)
+	assert.Equal(t, 9, config.Levels[EncodingGzip])
+	assert.Equal(t, 1, config.Levels[EncodingZstd])
+	assert.Equal(t, 4, config.Levels[EncodingBrotli])
+
+	for _, tc := range []struct {
+		name, code string
+		encodings  []string
+		levels     []string
+		minSize    int
+	}{
+		{"Unknown encoding", "validation.compression_encoding", []string{"lzma"}, nil, 0},
+		{"Level out of range", "validation.compression_level", nil, []string{"br=12"}, 0},
+		{"Unknown level encoding", "validation.compression_level", nil, []string{"lzma=1"}, 0},
+		{"Malformed level", "validation.compression_level", nil, []string{"gzip"}, 0},
+		{"Negative minimum size", "validation.compression_min_size", nil, nil, -1},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			_, appErr := ParseCompressionConfig(tc.encodings, tc.levels, tc.minSize, nil)
+			require.NotNil(t, appErr)
+			assert.Equal(t, tc.code, string(appErr.Code))
+		})
+	}
+}
+
+func TestCompressorNegotiate(t *testing.T) {
+	compressor := newTestCompressor(t, 0)
+
+	for _, tc := range []struct {
+		acceptEncoding, expected string
+	}{
+		{"", ""},
+		{"identity", ""},
+		{"gzip", EncodingGzip},
+		{"gzip, deflate, br, zstd", EncodingBrotli},
+		{"gzip;q=1.0, br;q=0.5", EncodingGzip},
+		{"deflate, gzip", EncodingGzip},
+		{"ZSTD", EncodingZstd},
+		{"*", EncodingBrotli},
+		{"br;q=0, *;q=0.1", EncodingZstd},
+		{"gzip;q=0", ""},
+		{"gzip;q=bad, deflate", EncodingDeflate},
+	} {
+		t.Run(tc.acceptEncoding, func(t *testing.T) {
+			assert.Equal(t, tc.expected, compressor.Negotiate(tc.acceptEncoding))
+		})
+	}
+
+	var disabled *Compressor
+	assert.Empty(t, disabled.Negotiate("gzip"))
+}
+
+func TestCompressorCompressible(t *testing.T) {
+	compressor := NewCompressor(CompressionConfig{ContentTypes: []string{"text/*", "application/json"}})
+
+	assert.True(t, compressor.Compressible("text/html; charset=utf-8"))
+	assert.True(t, compressor.Compressible("application/json"))
+	assert.False(t, compressor.Compressible("application/problem+json"))
+	assert.False(t, compressor.Compressible("image/png"))
+	assert.False(t, compressor.Compressible(""))
+}
+
+func TestCompressorEncode(t *testing.T) {
+	compressor := newTestCompressor(t, 0)
+	body := strings.Repeat("hello world ", 100)
+
+	for _, encoding := range CompressionEncodings() {
+		t.Run(encoding, func(t *testing.T) {
+			// twice, so pooled encoders are reused
+			for range 2 {
+				encoded, err := compressor.Encode(encoding, []byte(body))
+				require.NoError(t, err)
+				assert.Less(t, len(encoded), len(body))
+				assert.Equal(t, body, decodeBody(t, encoding, encoded))
+			}
+		})
+	}
+}
+
+func TestCompressorMiddleware(t *testing.T) {
+	compressor := newTestCompressor(t, 64)
+	long := strings.Repeat("a", 128)
+
+	for _, tc := range []struct {
+		name, body, contentType, contentEncoding string
+		compressed                               bool
+		vary                                     bool
+	}{
+		{"Compressed", long, "application/json", "", true, true},
+		{"Below minimum size", "short", "application/json", "", false, true},
+		{"Sniffed content type", long, "", "", true, true},
+		{"Not allowed content type", long, "image/png", "", false, false},
+		{"Already encoded", long, "application/json", EncodingGzip, false, false},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			handler := compressor.Middleware()(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+				if tc.contentType != "" {
+					w.Header().Set("Content-Type", tc.contentType)
+				}
+				if tc.contentEncoding != "" {
+					w.Header().Set("Content-Encoding", tc.contentEncoding)
+				}
+				w.WriteHeader(http.StatusCreated)
+				// in pieces, so the minimum size is reached across writes
+				_, _ = io.WriteString(w, tc.body[:len(tc.body)/2])
+				_, _ = io.WriteString(w, tc.body[len(tc.body)/2:])
+			}))
+
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.Header.Set("Accept-Encoding", "br;q=0.5, zstd")
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			assert.Equal(t, http.StatusCreated, rec.Code)
+			if tc.compressed {
+				assert.Equal(t, EncodingZstd, rec.Header().Get("Content-Encoding"))
+				assert.Equal(t, tc.body, decodeBody(t, EncodingZstd, rec.Body.Bytes()))
+			} else {
+				assert.Equal(t, tc.contentEncoding, rec.Header().Get("Content-Encoding"))
+				assert.Equal(t, tc.body, rec.Body.String())
+			}
+			if tc.vary {
+				assert.Equal(t, "Accept-Encoding", rec.Header().Get("Vary"))
+			} else {
+				assert.Empty(t, rec.Header().Get("Vary"))
+			}
+		})
+	}
+}
+
+func TestCompressorMiddlewarePanic(t *testing.T) {
+	compressor := newTestCompressor(t, 64)
+
+	handler := compressor.Middleware()(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = io.WriteString(w, "partial")
+		panic("boom")
+	}))
+	// the recovery middleware writes its response to the writer it was given
+	recovery := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		defer func() {
+			if p := recover(); p != nil {
+				w.WriteHeader(http.StatusInternalServerError)
+			}
+		}()
+		handler.ServeHTTP(w, r)
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	rec := httptest.NewRecorder()
+	recovery.ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusInternalServerError, rec.Code)
+	assert.Empty(t, rec.Body.String())
+	assert.Empty(t, rec.Header().Get("Content-Encoding"))
+}
+
+func TestCompressResponseWriterFlush(t *testing.T) {
+	compressor := newTestCompressor(t, 1024)
+
+	rec := httptest.NewRecorder()
+	w := compressor.NewResponseWriter(rec, EncodingGzip)
+	w.Header().Set("Content-Type", "text/plain")
+	_, _ = io.WriteString(w, "first")
+
+	// flushing decides without waiting for the minimum size
+	w.Flush()
+	assert.Equal(t, EncodingGzip, rec.Header().Get("Content-Encoding"))
+	assert.True(t, rec.Flushed)
+
+	_, _ = io.WriteString(w, " second")
+	require.NoError(t, w.Close())
+	assert.Equal(t, "first second", decodeBody(t, EncodingGzip, rec.Body.Bytes()))
+}
+
+func TestAddVary(t *testing.T) {
+	header := http.Header{"Vary": {"Origin, accept-encoding"}}
+	AddVary(header, "Accept-Encoding")
+	assert.Equal(t, []string{"Origin, accept-encoding"}, header.Values("Vary"))
+
+	AddVary(header, "Accept")
+	assert.Equal(t, []string{"Origin, accept-encoding", "Accept"}, header.Values("Vary"))
+}
@@ -0,0 +1,260 @@
+package common
+
+import (
+	"context"
+	"net/http"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+
+	"github.com/wasilak/go-hello-world/utils"
+)
+
+const (
+	// ShedQueueFull labels requests shed because the queue was full
+	ShedQueueFull = "queue_full"
+
+	// ShedQueueTimeout labels requests shed because they waited too long for a slot
+	ShedQueueTimeout = "queue_timeout"
+
+	// ShedCanceled labels requests whose client went away while queued
+	ShedCanceled = "canceled"
+
+	// aimdDecrease is the factor the adaptive limit is multiplied by on slow requests
+	aimdDecrease = 0.9
+)
+
+var (
+	// ConcurrencyInFlight is the number of requests being served under the concurrency limit
+	ConcurrencyInFlight = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "http_concurrency_in_flight_requests",
+		Help: "Number of requests being served under the concurrency limit",
+	})
+
+	// ConcurrencyQueued is the number of requests waiting for a slot
+	ConcurrencyQueued = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "http_concurrency_queued_requests",
+		Help: "Number of requests waiting for a concurrency slot",
+	})
+
+	// ConcurrencyLimit is the current concurrency limit, which moves in adaptive mode
+	ConcurrencyLimit = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "http_concurrency_limit",
+		Help: "Current limit of concurrently served requests",
+	})
+
+	// ConcurrencyShedCounterVec counts the shed requests by framework and reason
+	ConcurrencyShedCounterVec = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "http_concurrency_shed_requests_total",
+			Help: "Total number of requests shed by the concurrency limiter by framework and reason",
+		},
+		[]string{"framework", "reason"},
+	)
+)
+
+// ConcurrencyConfig configures the in-flight request limiter, which is disabled with a
+// zero limit
+type ConcurrencyConfig struct {
+	// Limit is the maximum number of requests served concurrently
+	Limit int
+
+	// QueueSize is the number of requests waiting for a slot, others are shed
+	QueueSize int
+
+	// QueueTimeout is how long a queued request waits for a slot before it is shed
+	QueueTimeout time.Duration
+
+	// Adaptive moves the limit between MinLimit and Limit with AIMD: it decreases
+	// multiplicatively when requests are slower than TargetLatency and increases
+	// additively otherwise
+	Adaptive      bool
+	MinLimit      int
+	TargetLatency time.Duration
+
+	// RetryAfter is returned to clients of shed requests
+	RetryAfter time.Duration
+
+	// ExcludePaths are never limited, an entry also excludes the paths below it
+	ExcludePaths []string
+}
+
+// Validate checks the concurrency limiter configuration
+func (c ConcurrencyConfig) Validate() *utils.AppError {
+	if c.Limit < 0 || c.QueueSize < 0 {
+		return utils.NewAppError(utils.ValidationError, "concurrency limit and queue size must not be negative", nil).WithCode("validation.concurrency_limit")
+	}
+	if c.QueueSize > 0 && c.QueueTimeout <= 0 {
+		return utils.NewAppError(utils.ValidationError, "a concurrency queue needs a positive queue timeout", nil).WithCode("validation.concurrency_queue_timeout")
+	}
+	if c.Adaptive && (c.MinLimit < 1 || c.MinLimit > c.Limit || c.TargetLatency <= 0) {
+		return utils.NewAppError(utils.ValidationError, "adaptive concurrency needs a minimum limit between 1 and the limit and a positive target latency", nil).
+			WithCode("validation.concurrency_adaptive")
+	}
+	return nil
+}
+
+// ConcurrencyLimiter bounds the requests served concurrently, queueing the excess for a
+// while before shedding it. It outlives framework switches, and a nil limiter admits
+// everything.
+type ConcurrencyLimiter struct {
+	config   ConcurrencyConfig
+	mu       sync.Mutex
+	limit    float64
+	inFlight int
+	waiters  []chan struct{}
+}
+
+// NewConcurrencyLimiter creates the limiter, nil when the configuration has no limit
+func NewConcurrencyLimiter(config ConcurrencyConfig) *ConcurrencyLimiter {
+	if config.Limit == 0 {
+		return nil
+	}
+	if config.RetryAfter <= 0 {
+		config.RetryAfter = time.Second
+	}
+
+	ConcurrencyLimit.Set(float64(config.Limit))
+	return &ConcurrencyLimiter{config: config, limit: float64(config.Limit)}
+}
+
+// Limit returns the current limit
+func (l *ConcurrencyLimiter) Limit() int {
+	if l == nil {
+		return 0
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return int(l.limit)
+}
+
+// Acquire admits a request for path, waiting in the queue when all slots are taken. It
+// returns the function releasing the slot once the request is served, or the reason the
+// request was shed. Excluded paths and the dashboard event stream are not limited.
+func (l *ConcurrencyLimiter) Acquire(ctx context.Context, path string) (func(), string) {
+	if l == nil || path == DashboardEventsPath || slices.ContainsFunc(l.config.ExcludePaths, func(excluded string) bool {
+		return underPath(path, excluded)
+	}) {
+		return func() {}, ""
+	}
+
+	l.mu.Lock()
+	if l.inFlight < int(l.limit) {
+		l.inFlight++
+		ConcurrencyInFlight.Set(float64(l.inFlight))
+		l.mu.Unlock()
+		return l.releaser(), ""
+	}
+	if len(l.waiters) >= l.config.QueueSize {
+		l.mu.Unlock()
+		return nil, ShedQueueFull
+	}
+
+	ready := make(chan struct{})
+	l.waiters = append(l.waiters, ready)
+	ConcurrencyQueued.Inc()
+	l.mu.Unlock()
+
+	timer := time.NewTimer(l.config.QueueTimeout)
+	defer timer.Stop()
+
+	reason := ""
+	select {
+	case <-ready:
+		return l.releaser(), ""
+	case <-timer.C:
+		reason = ShedQueueTimeout
+	case <-ctx.Done():
+		reason = ShedCanceled
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	i := slices.Index(l.waiters, ready)
+	if i < 0 {
+		// the slot was handed over while timing out
+		return l.releaser(), ""
+	}
+	l.waiters = slices.Delete(l.waiters, i, i+1)
+	ConcurrencyQueued.Dec()
+	return nil, reason
+}
+
+// releaser returns the function releasing a slot, which feeds the request latency to the
+// adaptive limit and hands the freed slots to the queued requests
+func (l *ConcurrencyLimiter) releaser() func() {
+	start := time.Now()
+	var once sync.Once
+
+	return func() {
+		once.Do(func() {
+			latency := time.Since(start)
+
+			l.mu.Lock()
+			defer l.mu.Unlock()
+
+			if l.config.Adaptive {
+				if latency > l.config.TargetLatency {
+					l.limit = max(float64(l.config.MinLimit), l.limit*aimdDecrease)
+				} else {
+					l.limit = min(float64(l.config.Limit), l.limit+1/l.limit)
+				}
+				ConcurrencyLimit.Set(float64(int(l.limit)))
+			}
+
+			l.inFlight--
+			for len(l.waiters) > 0 && l.inFlight < int(l.limit) {
+				ready := l.waiters[0]
+				l.waiters = l.waiters[1:]
+				l.inFlight++
+				ConcurrencyQueued.Dec()
+				close(ready)
+			}
+			ConcurrencyInFlight.Set(float64(l.inFlight))
+		})
+	}
+}
+
+// RetryAfterSeconds returns the Retry-After value of shed requests
+func (l *ConcurrencyLimiter) RetryAfterSeconds() string {
+	return strconv.Itoa(max(1, ceilSeconds(l.config.RetryAfter)))
+}
+
+// Shed counts a shed request of framework
+func (l *ConcurrencyLimiter) Shed(framework, reason string) {
+	ConcurrencyShedCounterVec.WithLabelValues(framework, reason).Inc()
+}
+
+// OverloadProblem returns the problem rendered for a shed request
+func (c ProblemConfig) OverloadProblem(ctx context.Context, reason, path string) Problem {
+	appErr := utils.NewAppError(utils.OverloadError, "server overloaded, request shed", nil).
+		WithCode("overload.shed").
+		WithRetryable(true).
+		AddContext(utils.ContextReason, reason)
+	return c.NewProblem(ctx, appErr, path)
+}
+
+// Middleware returns net/http middleware limiting the requests served concurrently, shed
+// requests get a 503 problem response with Retry-After
+func (l *ConcurrencyLimiter) Middleware(framework string, problems ProblemConfig) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			release, reason := l.Acquire(r.Context(), r.URL.Path)
+			if release == nil {
+				l.Shed(framework, reason)
+				w.Header().Set("Retry-After", l.RetryAfterSeconds())
+				WriteProblem(w, problems.OverloadProblem(r.Context(), reason, r.URL.Path))
+				return
+			}
+			defer release()
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,119 @@
+package common
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConcurrencyConfigValidate(t *testing.T) {
+	assert.Nil(t, ConcurrencyConfig{}.Validate())
+	assert.Nil(t, ConcurrencyConfig{Limit: 10, QueueSize: 5, QueueTimeout: time.Second}.Validate())
+	assert.NotNil(t, ConcurrencyConfig{Limit: -1}.Validate())
+	assert.NotNil(t, ConcurrencyConfig{Limit: 10, QueueSize: 5}.Validate())
+	assert.NotNil(t, ConcurrencyConfig{Limit: 10, Adaptive: true, MinLimit: 20, TargetLatency: time.Second}.Validate())
+	assert.Nil(t, NewConcurrencyLimiter(ConcurrencyConfig{}))
+}
+
+func TestConcurrencyLimiterQueue(t *testing.T) {
+	limiter := NewConcurrencyLimiter(ConcurrencyConfig{Limit: 1, QueueSize: 1, QueueTimeout: time.Second, ExcludePaths: []string{"/health"}})
+	ctx := context.Background()
+
+	release, reason := limiter.Acquire(ctx, "/")
+	require.NotNil(t, release)
+	assert.Empty(t, reason)
+
+	queued := make(chan func())
+	go func() {
+		release, _ := limiter.Acquire(ctx, "/")
+		queued <- release
+	}()
+
+	require.Eventually(t, func() bool {
+		limiter.mu.Lock()
+		defer limiter.mu.Unlock()
+		return len(limiter.waiters) == 1
+	}, time.Second, time.Millisecond)
+
+	shed, reason := limiter.Acquire(ctx, "/")
+	assert.Nil(t, shed)
+	assert.Equal(t, ShedQueueFull, reason)
+
+	excluded, _ := limiter.Acquire(ctx, "/health")
+	assert.NotNil(t, excluded, "Expected excluded paths to bypass the limit")
+
+	stream, _ := limiter.Acquire(ctx, DashboardEventsPath)
+	assert.NotNil(t, stream, "Expected the dashboard event stream to bypass the limit")
+
+	release()
+	release()
+	second := <-queued
+	require.NotNil(t, second, "Expected the queued request to get the released slot")
+	second()
+
+	limiter.mu.Lock()
+	defer limiter.mu.Unlock()
+	assert.Equal(t, 0, limiter.inFlight, "Expected releasing twice to free a single slot")
+}
+
+func TestConcurrencyLimiterQueueTimeout(t *testing.T) {
+	limiter := NewConcurrencyLimiter(ConcurrencyConfig{Limit: 1, QueueSize: 1, QueueTimeout: 10 * time.Millisecond})
+
+	release, _ := limiter.Acquire(context.Background(), "/")
+	defer release()
+
+	shed, reason := limiter.Acquire(context.Background(), "/")
+	assert.Nil(t, shed)
+	assert.Equal(t, ShedQueueTimeout, reason)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, reason = limiter.Acquire(ctx, "/")
+	assert.Equal(t, ShedCanceled, reason)
+}
+
+func TestConcurrencyLimiterAdaptive(t *testing.T) {
+	limiter := NewConcurrencyLimiter(ConcurrencyConfig{Limit: 10, Adaptive: true, MinLimit: 2, TargetLatency: time.Nanosecond})
+
+	for range 50 {
+		release, _ := limiter.Acquire(context.Background(), "/")
+		time.Sleep(time.Microsecond)
+		release()
+	}
+	assert.Equal(t, 2, limiter.Limit(), "Expected slow requests to decrease the limit to the minimum")
+
+	limiter.config.TargetLatency = time.Hour
+	for range 100 {
+		release, _ := limiter.Acquire(context.Background(), "/")
+		release()
+	}
+	assert.Equal(t, 10, limiter.Limit(), "Expected fast requests to increase the limit back")
+}
+
+func TestConcurrencyLimiterMiddleware(t *testing.T) {
+	limiter := NewConcurrencyLimiter(ConcurrencyConfig{Limit: 1, RetryAfter: 2 * time.Second})
+	handler := limiter.Middleware("gorilla", ProblemConfig{ContextAllowlist: []string{"reason"}})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	release, _ := limiter.Acquire(context.Background(), "/")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	release()
+
+	assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+	assert.Equal(t, "2", rec.Header().Get("Retry-After"))
+
+	var problem Problem
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &problem))
+	assert.Equal(t, "overload.shed", problem.Code)
+	assert.Equal(t, map[string]interface{}{"reason": ShedQueueFull}, problem.Context)
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+}
@@ -0,0 +1,311 @@
+package common
+
+import (
+	"net/http"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+
+	"github.com/wasilak/go-hello-world/utils"
+)
+
+// CORSRequestsCounterVec counts the cross-origin requests by type, "preflight" or "actual",
+// and result, "allowed" or "rejected"
+var CORSRequestsCounterVec = promauto.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: "http_cors_requests_total",
+		Help: "Total number of cross-origin requests by type and result",
+	},
+	[]string{"type", "result"},
+)
+
+// DefaultCORSMethods lists the methods allowed by default
+func DefaultCORSMethods() []string {
+	return []string{http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete}
+}
+
+// DefaultCORSHeaders lists the request headers allowed by default, with the trace context
+// headers so browsers can propagate traces
+func DefaultCORSHeaders() []string {
+	return []string{"Accept", "Authorization", "Content-Type", DefaultRequestIDHeader, "traceparent", "tracestate", "baggage"}
+}
+
+// DefaultCORSExposedHeaders lists the response headers exposed to scripts by default
+func DefaultCORSExposedHeaders() []string {
+	return []string{DefaultRequestIDHeader, "RateLimit-Limit", "RateLimit-Remaining", "RateLimit-Reset", "Retry-After"}
+}
+
+// CORSConfig configures the CORS policy of every framework, which is disabled without
+// allowed origins
+type CORSConfig struct {
+	// AllowedOrigins are exact origins, "*" for any origin, origins with "*" wildcards
+	// matching one or more characters other than "/", e.g. "https://*.example.com", or
+	// regular expressions prefixed with "~", matching the whole origin case-insensitively
+	AllowedOrigins []string
+
+	// AllowedMethods are returned to preflight requests, "*" allows any method
+	AllowedMethods []string
+
+	// AllowedHeaders are the request headers allowed, "*" allows any header
+	AllowedHeaders []string
+
+	// ExposedHeaders are the response headers scripts can read
+	ExposedHeaders []string
+
+	// AllowCredentials allows cookies and authorization, it can't be combined with the "*"
+	// origin, which would expose credentialed responses to any site
+	AllowCredentials bool
+
+	// MaxAge is how long browsers cache preflight responses, 0 leaves it to the browser
+	MaxAge time.Duration
+}
+
+// Enabled reports whether any origin is allowed
+func (c CORSConfig) Enabled() bool {
+	return len(c.AllowedOrigins) > 0
+}
+
+// Validate checks the CORS configuration
+func (c CORSConfig) Validate() *utils.AppError {
+	for _, origin := range c.AllowedOrigins {
+		if origin == "*" && c.AllowCredentials {
+			return utils.NewAppError(utils.ValidationError, "CORS credentials can't be allowed for any origin", nil).
+				WithCode("validation.cors_credentials").
+				AddContext(utils.ContextOrigin, origin)
+		}
+		if _, err := originPattern(origin); err != nil {
+			return utils.WrapError(err, utils.ValidationError, "invalid CORS origin").
+				WithCode("validation.cors_origin").
+				AddContext(utils.ContextOrigin, origin)
+		}
+	}
+	if c.MaxAge < 0 {
+		return utils.NewAppError(utils.ValidationError, "CORS max age must not be negative", nil).WithCode("validation.cors_max_age")
+	}
+	return nil
+}
+
+// ParseCORSConfig builds and validates the configuration from the command line values
+func ParseCORSConfig(origins, methods, headers, exposedHeaders []string, allowCredentials bool, maxAge time.Duration) (CORSConfig, *utils.AppError) {
+	config := CORSConfig{
+		AllowedOrigins:   origins,
+		AllowedMethods:   methods,
+		AllowedHeaders:   headers,
+		ExposedHeaders:   exposedHeaders,
+		AllowCredentials: allowCredentials,
+		MaxAge:           maxAge,
+	}
+	return config, config.Validate()
+}
+
+// originPattern returns the expression matching a wildcard or regular expression origin,
+// nil for exact origins and "*". Regular expressions are anchored, so they can't match a
+// part of an origin, and case-insensitive like the other origins.
+func originPattern(origin string) (*regexp.Regexp, error) {
+	if expr, ok := strings.CutPrefix(origin, "~"); ok {
+		return regexp.Compile("(?i)^(?:" + expr + ")$")
+	}
+	if origin == "*" || !strings.Contains(origin, "*") {
+		return nil, nil
+	}
+	expr := strings.ReplaceAll(regexp.QuoteMeta(strings.ToLower(origin)), `\*`, `[^/]+`)
+	return regexp.Compile("^" + expr + "$")
+}
+
+// CORSPolicy answers cross-origin requests of every framework. A nil policy is left out
+// of the chain.
+type CORSPolicy struct {
+	config    CORSConfig
+	anyOrigin bool
+	origins   []string
+	patterns  []*regexp.Regexp
+	anyMethod bool
+	methods   []string
+	anyHeader bool
+	headers   []string
+}
+
+// NewCORSPolicy creates the policy, the configuration must be valid. It returns nil when
+// no origin is allowed.
+func NewCORSPolicy(config CORSConfig) *CORSPolicy {
+	if !config.Enabled() {
+		return nil
+	}
+
+	p := &CORSPolicy{config: config}
+	for _, origin := range config.AllowedOrigins {
+		pattern, _ := originPattern(origin)
+		switch {
+		case origin == "*":
+			p.anyOrigin = true
+		case pattern != nil:
+			p.patterns = append(p.patterns, pattern)
+		default:
+			p.origins = append(p.origins, strings.ToLower(origin))
+		}
+	}
+	for _, method := range config.AllowedMethods {
+		if method == "*" {
+			p.anyMethod = true
+		}
+		p.methods = append(p.methods, strings.ToUpper(method))
+	}
+	for _, header := range config.AllowedHeaders {
+		if header == "*" {
+			p.anyHeader = true
+		}
+		p.headers = append(p.headers, strings.ToLower(header))
+	}
+	return p
+}
+
+// Config returns the CORS configuration
+func (p *CORSPolicy) Config() CORSConfig {
+	if p == nil {
+		return CORSConfig{}
+	}
+	return p.config
+}
+
+// AllowsOrigin reports whether requests from origin are allowed
+func (p *CORSPolicy) AllowsOrigin(origin string) bool {
+	if p.anyOrigin {
+		return true
+	}
+	origin = strings.ToLower(origin)
+	return slices.Contains(p.origins, origin) || slices.ContainsFunc(p.patterns, func(pattern *regexp.Regexp) bool {
+		return pattern.MatchString(origin)
+	})
+}
+
+// allowsMethod reports whether a preflight request may ask for method, the CORS-safelisted
+// methods are always allowed
+func (p *CORSPolicy) allowsMethod(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodPost:
+		return true
+	}
+	return p.anyMethod || slices.Contains(p.methods, method)
+}
+
+// allowsHeaders reports whether a preflight request may ask for every header of the
+// comma separated headers
+func (p *CORSPolicy) allowsHeaders(headers string) bool {
+	if p.anyHeader {
+		return true
+	}
+	for _, header := range strings.Split(headers, ",") {
+		header = strings.ToLower(strings.TrimSpace(header))
+		if header != "" && !slices.Contains(p.headers, header) {
+			return false
+		}
+	}
+	return true
+}
+
+// CORSResult is the answer of the policy to a request
+type CORSResult struct {
+	// Header holds the Access-Control-* response headers
+	Header http.Header
+
+	// Vary lists the request headers the answer depends on
+	Vary []string
+
+	// Preflight requests are answered with 204 No Content without calling the handlers
+	Preflight bool
+}
+
+// SetHeaders sets the response headers through set and adds the Vary headers through
+// vary, so every framework can use it
+func (r CORSResult) SetHeaders(set func(name, value string), vary func(name string)) {
+	for _, name := range r.Vary {
+		vary(name)
+	}
+	for name, values := range r.Header {
+		set(name, strings.Join(values, ", "))
+	}
+}
+
+// Evaluate answers a request with method and the request headers returned by header.
+// Requests without an Origin header are not cross-origin and get no CORS headers.
+func (p *CORSPolicy) Evaluate(method string, header func(name string) string) CORSResult {
+	result := CORSResult{Header: make(http.Header)}
+	if !p.anyOrigin {
+		result.Vary = append(result.Vary, "Origin")
+	}
+
+	origin := header("Origin")
+	requestMethod := header("Access-Control-Request-Method")
+	requestHeaders := header("Access-Control-Request-Headers")
+	if method == http.MethodOptions && requestMethod != "" {
+		result.Vary = append(result.Vary, "Access-Control-Request-Method", "Access-Control-Request-Headers")
+		result.Preflight = origin != ""
+	}
+	if origin == "" {
+		return result
+	}
+
+	kind := "actual"
+	if result.Preflight {
+		kind = "preflight"
+	}
+	if !p.AllowsOrigin(origin) || (result.Preflight && (!p.allowsMethod(requestMethod) || !p.allowsHeaders(requestHeaders))) {
+		CORSRequestsCounterVec.WithLabelValues(kind, "rejected").Inc()
+		return result
+	}
+	CORSRequestsCounterVec.WithLabelValues(kind, "allowed").Inc()
+
+	if p.anyOrigin {
+		result.Header.Set("Access-Control-Allow-Origin", "*")
+	} else {
+		result.Header.Set("Access-Control-Allow-Origin", origin)
+	}
+	if p.config.AllowCredentials {
+		result.Header.Set("Access-Control-Allow-Credentials", "true")
+	}
+
+	if !result.Preflight {
+		if len(p.config.ExposedHeaders) > 0 {
+			result.Header.Set("Access-Control-Expose-Headers", strings.Join(p.config.ExposedHeaders, ", "))
+		}
+		return result
+	}
+
+	// "*" is not honored with credentials, so the requested method and headers are returned
+	if p.anyMethod {
+		result.Header.Set("Access-Control-Allow-Methods", requestMethod)
+	} else if len(p.config.AllowedMethods) > 0 {
+		result.Header.Set("Access-Control-Allow-Methods", strings.Join(p.methods, ", "))
+	}
+	if p.anyHeader && requestHeaders != "" {
+		result.Header.Set("Access-Control-Allow-Headers", requestHeaders)
+	} else if !p.anyHeader && len(p.config.AllowedHeaders) > 0 {
+		result.Header.Set("Access-Control-Allow-Headers", strings.Join(p.config.AllowedHeaders, ", "))
+	}
+	if p.config.MaxAge > 0 {
+		result.Header.Set("Access-Control-Max-Age", strconv.Itoa(int(p.config.MaxAge.Seconds())))
+	}
+	return result
+}
+
+// Middleware returns net/http middleware setting the CORS headers and answering
+// preflight requests
+func (p *CORSPolicy) Middleware() func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			result := p.Evaluate(r.Method, r.Header.Get)
+			result.SetHeaders(w.Header().Set, func(name string) { AddVary(w.Header(), name) })
+
+			if result.Preflight {
+				w.WriteHeader(http.StatusNoContent)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
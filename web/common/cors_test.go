@@ -0,0 +1,174 @@
+package common
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestCORSPolicy(t *testing.T, config CORSConfig) *CORSPolicy {
+	t.Helper()
+	require.Nil(t, config.Validate())
+	return NewCORSPolicy(config)
+}
+
+func TestParseCORSConfig(t *testing.T) {
+	config, appErr := ParseCORSConfig([]string{"https://*.example.com", "~^http://localhost:\\d+$"}, DefaultCORSMethods(), nil, nil, true, time.Minute)
+	require.Nil(t, appErr)
+	assert.True(t, config.Enabled())
+
+	config, appErr = ParseCORSConfig(nil, DefaultCORSMethods(), DefaultCORSHeaders(), nil, false, 0)
+	require.Nil(t, appErr)
+	assert.False(t, config.Enabled())
+	assert.Nil(t, NewCORSPolicy(config))
+
+	_, appErr = ParseCORSConfig([]string{"~("}, nil, nil, nil, false, 0)
+	require.NotNil(t, appErr)
+	assert.Equal(t, "validation.cors_origin", string(appErr.Code))
+
+	_, appErr = ParseCORSConfig([]string{"https://app.example.com", "*"}, nil, nil, nil, true, 0)
+	require.NotNil(t, appErr)
+	assert.Equal(t, "validation.cors_credentials", string(appErr.Code))
+
+	_, appErr = ParseCORSConfig([]string{"*"}, nil, nil, nil, false, -time.Second)
+	require.NotNil(t, appErr)
+	assert.Equal(t, "validation.cors_max_age", string(appErr.Code))
+}
+
+func TestCORSPolicyAllowsOrigin(t *testing.T) {
+	policy := newTestCORSPolicy(t, CORSConfig{AllowedOrigins: []string{
+		"https://app.example.org",
+		"https://*.example.com",
+		"http://localhost:*",
+		"~^https://[a-z]+\\.test$",
+		"~https://[a-z]+\\.internal",
+	}})
+
+	for origin, allowed := range map[string]bool{
+		"https://app.example.org":             true,
+		"HTTPS://APP.EXAMPLE.ORG":             true,
+		"https://other.example.org":           false,
+		"https://a.example.com":               true,
+		"https://a.b.example.com":             true,
+		"https://example.com":                 false,
+		"https://a.example.com.evil.io":       false,
+		"http://localhost:3000":               true,
+		"https://tools.test":                  true,
+		"https://tools1.test":                 false,
+		"https://Tools.TEST":                  true,
+		"https://ci.internal":                 true,
+		"https://ci.internal.evil.io":         false,
+		"https://evil.io/https://ci.internal": false,
+	} {
+		assert.Equal(t, allowed, policy.AllowsOrigin(origin), origin)
+	}
+}
+
+func TestCORSPolicyEvaluate(t *testing.T) {
+	policy := newTestCORSPolicy(t, CORSConfig{
+		AllowedOrigins: []string{"https://*.example.com"},
+		AllowedMethods: []string{"GET", "put"},
+		AllowedHeaders: []string{"Content-Type", "X-Request-ID"},
+		ExposedHeaders: []string{"X-Request-ID"},
+		MaxAge:         10 * time.Minute,
+	})
+
+	headers := func(values map[string]string) func(string) string {
+		return func(name string) string { return values[name] }
+	}
+
+	t.Run("Same origin", func(t *testing.T) {
+		result := policy.Evaluate(http.MethodGet, headers(nil))
+		assert.False(t, result.Preflight)
+		assert.Empty(t, result.Header)
+		assert.Equal(t, []string{"Origin"}, result.Vary)
+	})
+
+	t.Run("Actual request", func(t *testing.T) {
+		result := policy.Evaluate(http.MethodGet, headers(map[string]string{"Origin": "https://a.example.com"}))
+		assert.False(t, result.Preflight)
+		assert.Equal(t, "https://a.example.com", result.Header.Get("Access-Control-Allow-Origin"))
+		assert.Equal(t, "X-Request-ID", result.Header.Get("Access-Control-Expose-Headers"))
+		assert.Empty(t, result.Header.Get("Access-Control-Allow-Credentials"))
+	})
+
+	t.Run("Preflight", func(t *testing.T) {
+		result := policy.Evaluate(http.MethodOptions, headers(map[string]string{
+			"Origin":                         "https://a.example.com",
+			"Access-Control-Request-Method":  http.MethodPut,
+			"Access-Control-Request-Headers": "content-type, x-request-id",
+		}))
+		assert.True(t, result.Preflight)
+		assert.Equal(t, "https://a.example.com", result.Header.Get("Access-Control-Allow-Origin"))
+		assert.Equal(t, "GET, PUT", result.Header.Get("Access-Control-Allow-Methods"))
+		assert.Equal(t, "Content-Type, X-Request-ID", result.Header.Get("Access-Control-Allow-Headers"))
+		assert.Equal(t, "600", result.Header.Get("Access-Control-Max-Age"))
+		assert.Equal(t, []string{"Origin", "Access-Control-Request-Method", "Access-Control-Request-Headers"}, result.Vary)
+	})
+
+	for _, tc := range []struct {
+		name    string
+		request map[string]string
+	}{
+		{"Origin not allowed", map[string]string{"Origin": "https://evil.io", "Access-Control-Request-Method": http.MethodGet}},
+		{"Method not allowed", map[string]string{"Origin": "https://a.example.com", "Access-Control-Request-Method": http.MethodDelete}},
+		{"Header not allowed", map[string]string{"Origin": "https://a.example.com", "Access-Control-Request-Method": http.MethodGet, "Access-Control-Request-Headers": "X-Secret"}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			// rejected preflight requests are answered without the Access-Control-Allow-* headers
+			result := policy.Evaluate(http.MethodOptions, headers(tc.request))
+			assert.True(t, result.Preflight)
+			assert.Empty(t, result.Header)
+		})
+	}
+}
+
+func TestCORSPolicyAnyOrigin(t *testing.T) {
+	policy := newTestCORSPolicy(t, CORSConfig{AllowedOrigins: []string{"*"}, AllowedMethods: []string{"*"}, AllowedHeaders: []string{"*"}})
+	request := map[string]string{
+		"Origin":                         "https://anywhere.io",
+		"Access-Control-Request-Method":  "PURGE",
+		"Access-Control-Request-Headers": "X-Anything",
+	}
+
+	result := policy.Evaluate(http.MethodOptions, func(name string) string { return request[name] })
+	assert.Equal(t, "*", result.Header.Get("Access-Control-Allow-Origin"))
+	assert.Equal(t, "PURGE", result.Header.Get("Access-Control-Allow-Methods"))
+	assert.Equal(t, "X-Anything", result.Header.Get("Access-Control-Allow-Headers"))
+	assert.NotContains(t, result.Vary, "Origin")
+	assert.Empty(t, result.Header.Get("Access-Control-Allow-Credentials"))
+}
+
+func TestCORSPolicyMiddleware(t *testing.T) {
+	policy := newTestCORSPolicy(t, CORSConfig{AllowedOrigins: []string{"https://app.example.com"}, AllowedMethods: DefaultCORSMethods()})
+
+	called := false
+	handler := policy.Middleware()(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		called = true
+		w.WriteHeader(http.StatusTeapot)
+	}))
+
+	req := httptest.NewRequest(http.MethodOptions, "/", nil)
+	req.Header.Set("Origin", "https://app.example.com")
+	req.Header.Set("Access-Control-Request-Method", http.MethodDelete)
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusNoContent, rec.Code)
+	assert.False(t, called)
+	assert.Equal(t, "https://app.example.com", rec.Header().Get("Access-Control-Allow-Origin"))
+
+	// OPTIONS requests without a requested method are not preflight requests
+	req = httptest.NewRequest(http.MethodOptions, "/", nil)
+	req.Header.Set("Origin", "https://app.example.com")
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusTeapot, rec.Code)
+	assert.True(t, called)
+	assert.Equal(t, "Origin", rec.Header().Get("Vary"))
+}
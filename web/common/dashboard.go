@@ -0,0 +1,305 @@
+package common
+
+import (
+	"context"
+	"embed"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/wasilak/go-hello-world/utils"
+)
+
+const (
+	// DashboardPath is where every framework serves the dashboard
+	DashboardPath = "/ui"
+
+	// DashboardEventsPath streams the dashboard state as server-sent events
+	DashboardEventsPath = DashboardPath + "/events"
+
+	// DashboardStatePath serves the dashboard state once
+	DashboardStatePath = DashboardPath + "/state"
+
+	// EventStreamContentType is the media type of server-sent events
+	EventStreamContentType = "text/event-stream"
+
+	// dashboardInterval is the delay between two streamed states
+	dashboardInterval = 2 * time.Second
+
+	// dashboardRecentErrors is the number of recent errors shown by the dashboard
+	dashboardRecentErrors = 10
+)
+
+//go:embed dashboard
+var dashboardFiles embed.FS
+
+// DashboardCheck is a health check shown by the dashboard
+type DashboardCheck struct {
+	Name    string `json:"name"`
+	Healthy bool   `json:"healthy"`
+	Detail  string `json:"detail,omitempty"`
+}
+
+// DashboardError is a recently logged error, without its context which may hold request data
+type DashboardError struct {
+	OccurredAt time.Time       `json:"occurred_at"`
+	Type       utils.ErrorType `json:"type"`
+	Code       utils.ErrorCode `json:"code,omitempty"`
+	Message    string          `json:"message"`
+	Framework  string          `json:"framework,omitempty"`
+	Route      string          `json:"route,omitempty"`
+}
+
+// DashboardAdmin tells the dashboard where runtime changes are sent. Without the admin
+// API the public /framework and /logger routes accept them.
+type DashboardAdmin struct {
+	Enabled bool   `json:"enabled"`
+	Port    string `json:"port,omitempty"`
+	TLS     bool   `json:"tls,omitempty"`
+}
+
+// DashboardState is the state shown by the dashboard
+type DashboardState struct {
+	Host          string           `json:"host"`
+	Framework     string           `json:"framework"`
+	Frameworks    []string         `json:"frameworks"`
+	LogLevel      string           `json:"log_level"`
+	Version       string           `json:"version"`
+	UptimeSeconds int64            `json:"uptime_seconds"`
+	Requests      uint64           `json:"requests_total"`
+	RequestRate   float64          `json:"request_rate"`
+	Checks        []DashboardCheck `json:"checks"`
+	RecentErrors  []DashboardError `json:"recent_errors"`
+	Admin         DashboardAdmin   `json:"admin"`
+}
+
+// Dashboard serves the embedded runtime control page and streams its state. Close ends
+// the running streams, so they do not hold up the shutdown of the server.
+type Dashboard struct {
+	options FrameworkOptions
+	static  http.Handler
+
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+// NewDashboard creates the dashboard of a web server
+func NewDashboard(options FrameworkOptions) *Dashboard {
+	files, _ := fs.Sub(dashboardFiles, "dashboard")
+
+	return &Dashboard{
+		options: options,
+		static:  http.StripPrefix(DashboardPath, http.FileServerFS(files)),
+		done:    make(chan struct{}),
+	}
+}
+
+// Close ends the running event streams
+func (d *Dashboard) Close() {
+	d.closeOnce.Do(func() {
+		close(d.done)
+	})
+}
+
+// State returns the current state, the request rate is computed from the previous state
+func (d *Dashboard) State(previous *DashboardState, elapsed time.Duration) DashboardState {
+	hostname, _ := os.Hostname()
+	runtime := d.options.Runtime
+
+	state := DashboardState{
+		Host:          hostname,
+		Framework:     runtime.Framework(),
+		Frameworks:    SupportedFrameworks(),
+		LogLevel:      d.options.LevelRegistry().LoggerLevel(utils.DefaultLogger).Level,
+		Version:       utils.GetBuildInfo().Version,
+		UptimeSeconds: int64(runtime.Uptime().Seconds()),
+		Requests:      runtime.Requests(),
+		Checks:        d.checks(),
+		RecentErrors:  []DashboardError{},
+		Admin:         d.admin(),
+	}
+	if previous != nil && elapsed > 0 && state.Requests >= previous.Requests {
+		state.RequestRate = float64(state.Requests-previous.Requests) / elapsed.Seconds()
+	}
+
+	for _, record := range utils.RecentErrors().Snapshot(dashboardRecentErrors) {
+		if record.Error == nil {
+			continue
+		}
+		state.RecentErrors = append(state.RecentErrors, DashboardError{
+			OccurredAt: record.Error.OccurredAt,
+			Type:       record.Error.Type,
+			Code:       record.Error.Code,
+			Message:    record.Error.Message,
+			Framework:  record.Framework,
+			Route:      record.Route,
+		})
+	}
+
+	return state
+}
+
+// checks returns the health checks of the instance
+func (d *Dashboard) checks() []DashboardCheck {
+	runtime := d.options.Runtime
+
+	server := DashboardCheck{Name: "server", Healthy: runtime.Framework() != ""}
+	if !server.Healthy {
+		server.Detail = "no web server running"
+	}
+
+	ready := DashboardCheck{Name: "ready", Healthy: runtime.Ready()}
+	if !ready.Healthy {
+		ready.Detail = "marked not ready"
+	}
+
+	faults := DashboardCheck{Name: "faults", Healthy: runtime.Faults() == nil}
+	if config := runtime.Faults(); config != nil {
+		faults.Detail = fmt.Sprintf("latency %dms, error rate %g", config.LatencyMS, config.ErrorRate)
+	}
+
+	return []DashboardCheck{server, ready, faults}
+}
+
+// admin returns where the dashboard sends runtime changes
+func (d *Dashboard) admin() DashboardAdmin {
+	config := d.options.Admin
+	if !config.Enabled() {
+		return DashboardAdmin{}
+	}
+
+	_, port, _ := net.SplitHostPort(config.ListenAddr)
+	return DashboardAdmin{Enabled: true, Port: port, TLS: config.TLSCertFile != ""}
+}
+
+// Handler serves the dashboard page, its state and the event stream below DashboardPath
+func (d *Dashboard) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch strings.TrimSuffix(r.URL.Path, "/") {
+		case DashboardPath:
+			if !strings.HasSuffix(r.URL.Path, "/") {
+				http.Redirect(w, r, DashboardPath+"/", http.StatusMovedPermanently)
+				return
+			}
+		case DashboardStatePath:
+			w.Header().Set("Content-Type", "application/json")
+			w.Header().Set("Cache-Control", "no-store")
+			_ = json.NewEncoder(w).Encode(d.State(nil, 0))
+			return
+		case DashboardEventsPath:
+			d.serveEvents(w, r)
+			return
+		}
+
+		if policy := d.ContentSecurityPolicy(r.Host); policy != "" {
+			w.Header().Set("Content-Security-Policy", policy)
+		}
+		d.static.ServeHTTP(w, r)
+	})
+}
+
+// ContentSecurityPolicy returns the policy of the page requested from host, which lets the
+// controls call the admin API on its own origin. It returns "" when the policy of the
+// security headers applies as it is.
+func (d *Dashboard) ContentSecurityPolicy(host string) string {
+	admin := d.admin()
+	if !admin.Enabled {
+		return ""
+	}
+
+	scheme := "http"
+	if admin.TLS {
+		scheme = "https"
+	}
+	hostname, _, err := net.SplitHostPort(host)
+	if err != nil {
+		hostname = strings.Trim(host, "[]")
+	}
+	return d.options.SecurityHeaders.AllowConnect(scheme + "://" + net.JoinHostPort(hostname, admin.Port))
+}
+
+// serveEvents streams the state to a net/http client. The write timeout of the server is
+// lifted for the stream; when a middleware hides the connection, the stream ends before
+// the timeout instead and the browser reconnects.
+func (d *Dashboard) serveEvents(w http.ResponseWriter, r *http.Request) {
+	controller := http.NewResponseController(w)
+
+	lifetime := d.StreamLifetime()
+	if err := controller.SetWriteDeadline(time.Time{}); err == nil {
+		lifetime = 0
+	}
+
+	SetEventStreamHeaders(w.Header())
+	w.WriteHeader(http.StatusOK)
+
+	_ = d.Stream(r.Context(), w, controller.Flush, lifetime)
+}
+
+// SetEventStreamHeaders sets the headers of a server-sent events response
+func SetEventStreamHeaders(header http.Header) {
+	header.Set("Content-Type", EventStreamContentType)
+	header.Set("Cache-Control", "no-cache")
+	header.Set("X-Accel-Buffering", "no")
+}
+
+// StreamLifetime returns how long a stream may last before the write timeout of the server
+// closes it, 0 without a write timeout
+func (d *Dashboard) StreamLifetime() time.Duration {
+	timeout := d.options.Server.WriteTimeout
+	return timeout - timeout/10
+}
+
+// Stream writes the state as server-sent events until ctx is done, the dashboard is
+// closed, lifetime (when positive) elapses or the client is gone
+func (d *Dashboard) Stream(ctx context.Context, w io.Writer, flush func() error, lifetime time.Duration) error {
+	var expired <-chan time.Time
+	if lifetime > 0 {
+		timer := time.NewTimer(lifetime)
+		defer timer.Stop()
+		expired = timer.C
+	}
+
+	ticker := time.NewTicker(dashboardInterval)
+	defer ticker.Stop()
+
+	// the browser reconnects after a second when the stream ends
+	if _, err := io.WriteString(w, "retry: 1000\n\n"); err != nil {
+		return err
+	}
+
+	var previous *DashboardState
+	last := time.Now()
+	for {
+		now := time.Now()
+		state := d.State(previous, now.Sub(last))
+		previous, last = &state, now
+
+		data, err := json.Marshal(state)
+		if err != nil {
+			return err
+		}
+		if _, err := fmt.Fprintf(w, "event: state\ndata: %s\n\n", data); err != nil {
+			return err
+		}
+		if err := flush(); err != nil {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-d.done:
+			return nil
+		case <-expired:
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,142 @@
+"use strict";
+
+// The dashboard renders the state streamed from events and sends runtime changes to the
+// admin API, or to the public /framework and /logger routes when it is disabled.
+
+const $ = (id) => document.getElementById(id);
+
+let state = null;
+
+function formatUptime(seconds) {
+  const days = Math.floor(seconds / 86400);
+  const hours = Math.floor((seconds % 86400) / 3600);
+  const minutes = Math.floor((seconds % 3600) / 60);
+  const parts = [];
+  if (days > 0) parts.push(`${days}d`);
+  if (days > 0 || hours > 0) parts.push(`${hours}h`);
+  parts.push(`${minutes}m`, `${seconds % 60}s`);
+  return parts.join(" ");
+}
+
+function cell(text) {
+  const td = document.createElement("td");
+  td.textContent = text;
+  return td;
+}
+
+function render(next) {
+  const first = state === null;
+  state = next;
+
+  $("host").textContent = next.host;
+  $("version").textContent = next.version;
+  $("framework").textContent = next.framework || "none";
+  $("log-level").textContent = next.log_level;
+  $("uptime").textContent = formatUptime(next.uptime_seconds);
+  $("request-rate").textContent = next.request_rate.toFixed(1);
+  $("requests").textContent = next.requests_total;
+
+  if (first) {
+    const select = $("framework-select");
+    for (const framework of next.frameworks) {
+      select.add(new Option(framework, framework));
+    }
+    select.value = next.framework;
+    $("log-level-select").value = next.log_level;
+
+    if (next.admin.enabled) {
+      $("admin-settings").hidden = false;
+      $("admin-url").value = sessionStorage.getItem("adminURL") ||
+        `${next.admin.tls ? "https" : "http"}://${location.hostname}:${next.admin.port}`;
+      $("admin-token").value = sessionStorage.getItem("adminToken") || "";
+    }
+  }
+
+  const checks = $("checks");
+  checks.replaceChildren(...next.checks.map((check) => {
+    const li = document.createElement("li");
+    li.className = check.healthy ? "" : "failing";
+    li.textContent = check.detail ? `${check.name}: ${check.detail}` : check.name;
+    return li;
+  }));
+
+  const errors = $("errors");
+  if (next.recent_errors.length === 0) {
+    const empty = cell("No recent errors");
+    empty.colSpan = 5;
+    const tr = document.createElement("tr");
+    tr.append(empty);
+    errors.replaceChildren(tr);
+    return;
+  }
+  errors.replaceChildren(...next.recent_errors.map((error) => {
+    const tr = document.createElement("tr");
+    tr.append(
+      cell(new Date(error.occurred_at).toLocaleTimeString()),
+      cell(error.type),
+      cell(error.code || ""),
+      cell(error.message),
+      cell(error.route || ""),
+    );
+    return tr;
+  }));
+}
+
+function showResult(message, failed) {
+  const result = $("control-result");
+  result.textContent = message;
+  result.className = failed ? "error" : "";
+}
+
+async function change(kind, value) {
+  let response;
+  try {
+    if (state.admin.enabled) {
+      const url = $("admin-url").value.replace(/\/+$/, "");
+      const token = $("admin-token").value;
+      sessionStorage.setItem("adminURL", url);
+      sessionStorage.setItem("adminToken", token);
+
+      const path = kind === "framework" ? "/admin/framework" : "/admin/log-level";
+      const body = kind === "framework" ? { framework: value } : { level: value };
+      const headers = { "Content-Type": "application/json" };
+      if (token) headers.Authorization = `Bearer ${token}`;
+
+      response = await fetch(url + path, { method: "PUT", headers, body: JSON.stringify(body) });
+    } else {
+      const path = kind === "framework" ? "../framework?name=" : "../logger?level=";
+      response = await fetch(path + encodeURIComponent(value));
+    }
+  } catch (err) {
+    showResult(`Request failed: ${err.message}`, true);
+    return;
+  }
+
+  if (!response.ok) {
+    const problem = await response.json().catch(() => ({}));
+    showResult(problem.detail || problem.title || response.statusText, true);
+    return;
+  }
+  showResult(kind === "framework" ? `Switching to ${value}` : `Log level set to ${value}`, false);
+}
+
+$("framework-form").addEventListener("submit", (event) => {
+  event.preventDefault();
+  change("framework", $("framework-select").value);
+});
+
+$("log-level-form").addEventListener("submit", (event) => {
+  event.preventDefault();
+  change("log-level", $("log-level-select").value);
+});
+
+const events = new EventSource("events");
+events.addEventListener("state", (event) => render(JSON.parse(event.data)));
+events.addEventListener("open", () => {
+  $("connection").textContent = "live";
+  $("connection").className = "badge live";
+});
+events.addEventListener("error", () => {
+  $("connection").textContent = "reconnecting";
+  $("connection").className = "badge offline";
+});
@@ -0,0 +1,80 @@
+package common
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestDashboard() *Dashboard {
+	runtime := NewRuntimeState()
+	runtime.SetFramework("chi")
+
+	return NewDashboard(FrameworkOptions{
+		LogLevelConfig: new(slog.LevelVar),
+		Runtime:        runtime,
+		Admin:          AdminConfig{ListenAddr: "0.0.0.0:9090", TLSCertFile: "tls.crt"},
+		Server:         ServerConfig{WriteTimeout: time.Minute},
+	})
+}
+
+func TestDashboardState(t *testing.T) {
+	dashboard := newTestDashboard()
+	runtime := dashboard.options.Runtime
+
+	first := dashboard.State(nil, 0)
+	assert.Equal(t, "chi", first.Framework)
+	assert.Equal(t, "INFO", first.LogLevel)
+	assert.Equal(t, DashboardAdmin{Enabled: true, Port: "9090", TLS: true}, first.Admin)
+	assert.Zero(t, first.RequestRate)
+
+	for range 10 {
+		runtime.CountRequest()
+	}
+	runtime.SetReady(false)
+	runtime.SetFaults(&FaultConfig{LatencyMS: 100})
+
+	second := dashboard.State(&first, 2*time.Second)
+	assert.Equal(t, uint64(10), second.Requests)
+	assert.Equal(t, 5.0, second.RequestRate)
+	assert.Equal(t, []DashboardCheck{
+		{Name: "server", Healthy: true},
+		{Name: "ready", Healthy: false, Detail: "marked not ready"},
+		{Name: "faults", Healthy: false, Detail: "latency 100ms, error rate 0"},
+	}, second.Checks)
+}
+
+func TestDashboardStream(t *testing.T) {
+	t.Run("Lifetime", func(t *testing.T) {
+		dashboard := newTestDashboard()
+		assert.Equal(t, 54*time.Second, dashboard.StreamLifetime())
+
+		var out bytes.Buffer
+		require.NoError(t, dashboard.Stream(context.Background(), &out, func() error { return nil }, 10*time.Millisecond))
+		assert.True(t, strings.HasPrefix(out.String(), "retry: 1000\n\nevent: state\ndata: {"))
+	})
+
+	t.Run("Close ends the streams", func(t *testing.T) {
+		dashboard := newTestDashboard()
+
+		done := make(chan error)
+		go func() {
+			done <- dashboard.Stream(context.Background(), &bytes.Buffer{}, func() error { return nil }, 0)
+		}()
+
+		dashboard.Close()
+		dashboard.Close()
+		select {
+		case err := <-done:
+			assert.NoError(t, err)
+		case <-time.After(time.Second):
+			t.Fatal("Expected the stream to end when the dashboard is closed")
+		}
+	})
+}
@@ -0,0 +1,54 @@
+package common
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/wasilak/go-hello-world/utils"
+)
+
+// RecentErrorsResponse type
+type RecentErrorsResponse struct {
+	Count  int                 `json:"count"`
+	Errors []utils.ErrorRecord `json:"errors"`
+}
+
+// ErrorScopeMiddleware attaches the framework and route to the request context, so logged
+// errors are labelled with them in the error metrics
+func ErrorScopeMiddleware(framework string, routeFn func(*http.Request) string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx := utils.WithErrorScope(r.Context(), utils.ErrorScope{
+				Framework: framework,
+				Route:     func() string { return routeFn(r) },
+			})
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// RecentErrorsHandler serves the most recently logged errors, newest first, the
+// number of errors can be limited with the limit query parameter
+func RecentErrorsHandler(problems ProblemConfig) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		limit := 0
+		if value := r.URL.Query().Get("limit"); value != "" {
+			parsed, err := strconv.Atoi(value)
+			if err != nil || parsed < 0 {
+				appErr := utils.NewAppError(utils.ValidationError, "limit must be a non-negative integer", err).
+					WithCode("validation.limit").
+					WithSeverity(utils.SeverityInfo)
+				appErr.AddContext("limit", value)
+				problems.Write(w, r, appErr)
+				return
+			}
+			limit = parsed
+		}
+
+		records := utils.RecentErrors().Snapshot(limit)
+
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(RecentErrorsResponse{Count: len(records), Errors: records})
+	})
+}
@@ -6,7 +6,6 @@ import (
 	"log/slog"
 	"net/http"
 	"os"
-	"strings"
 
 	"github.com/wasilak/go-hello-world/utils"
 )
@@ -35,7 +34,7 @@ func (f *RouteHandlerFactory) MainRouteHandler() http.HandlerFunc {
 		if err := sendJSONResponse(ctx, w, response, http.StatusOK); err != nil {
 			// Use the new standardized error types
 			appErr := utils.WrapError(err, utils.RuntimeError, "failed to send main route response")
-			appErr.AddContext("path", r.URL.Path)
+			appErr.AddContext(utils.ContextPath, r.URL.Path)
 			appErr.LogError(ctx)
 		}
 	}
@@ -55,7 +54,7 @@ func (f *RouteHandlerFactory) HealthRouteHandler() http.HandlerFunc {
 		if err := sendJSONResponse(ctx, w, response, http.StatusOK); err != nil {
 			// Use the new standardized error types
 			appErr := utils.WrapError(err, utils.RuntimeError, "failed to send health route response")
-			appErr.AddContext("path", r.URL.Path)
+			appErr.AddContext(utils.ContextPath, r.URL.Path)
 			appErr.LogError(ctx)
 		}
 	}
@@ -68,26 +67,31 @@ func (f *RouteHandlerFactory) LoggerRouteHandler() http.HandlerFunc {
 		ctx, span := f.WebServer.FrameworkOptions.Tracer.Start(ctx, "loggerRoute")
 		defer span.End()
 
-		var req struct {
-			Level string `json:"level"`
-		}
+		var req LogLevelRequest
 
 		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 			// Use the new standardized error types
 			appErr := utils.WrapError(err, utils.ValidationError, "invalid JSON in logger route request")
-			appErr.AddContext("path", r.URL.Path)
+			appErr.AddContext(utils.ContextPath, r.URL.Path)
 			appErr.LogError(ctx)
 
-			http.Error(w, "Invalid JSON", http.StatusBadRequest)
+			f.WebServer.FrameworkOptions.Problems.Write(w, r, appErr)
 			return
 		}
 
-		response := f.WebServer.SetLogLevelResponse(ctx, strings.ToUpper(req.Level))
+		response, appErr := f.WebServer.SetLogLevelResponse(ctx, req)
+		if appErr != nil {
+			appErr.AddContext(utils.ContextPath, r.URL.Path)
+			appErr.LogError(ctx)
+
+			f.WebServer.FrameworkOptions.Problems.Write(w, r, appErr)
+			return
+		}
 
 		if err := sendJSONResponse(ctx, w, response, http.StatusOK); err != nil {
 			// Use the new standardized error types
 			appErr := utils.WrapError(err, utils.RuntimeError, "failed to send logger route response")
-			appErr.AddContext("path", r.URL.Path)
+			appErr.AddContext(utils.ContextPath, r.URL.Path)
 			appErr.LogError(ctx)
 		}
 	}
@@ -107,19 +111,26 @@ func (f *RouteHandlerFactory) SwitchRouteHandler() http.HandlerFunc {
 		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 			// Use the new standardized error types
 			appErr := utils.WrapError(err, utils.ValidationError, "invalid JSON in switch route request")
-			appErr.AddContext("path", r.URL.Path)
+			appErr.AddContext(utils.ContextPath, r.URL.Path)
 			appErr.LogError(ctx)
 
-			http.Error(w, "Invalid JSON", http.StatusBadRequest)
+			f.WebServer.FrameworkOptions.Problems.Write(w, r, appErr)
 			return
 		}
 
-		response := f.WebServer.SetFrameworkResponse(ctx, req.Framework)
+		response, appErr := f.WebServer.SetFrameworkResponse(ctx, req.Framework)
+		if appErr != nil {
+			appErr.AddContext(utils.ContextPath, r.URL.Path)
+			appErr.LogError(ctx)
+
+			f.WebServer.FrameworkOptions.Problems.Write(w, r, appErr)
+			return
+		}
 
 		if err := sendJSONResponse(ctx, w, response, http.StatusOK); err != nil {
 			// Use the new standardized error types
 			appErr := utils.WrapError(err, utils.RuntimeError, "failed to send switch route response")
-			appErr.AddContext("path", r.URL.Path)
+			appErr.AddContext(utils.ContextPath, r.URL.Path)
 			appErr.LogError(ctx)
 		}
 	}
@@ -142,10 +153,10 @@ func sendJSONResponse(ctx context.Context, w http.ResponseWriter, data interface
 func (f *RouteHandlerFactory) GenericErrorHandler(err error, path string, w http.ResponseWriter) {
 	// Use the new standardized error types
 	appErr := utils.WrapError(err, utils.RuntimeError, "route handler error")
-	appErr.AddContext("path", path)
+	appErr.AddContext(utils.ContextPath, path)
 	appErr.LogError(context.Background()) // Use background context since original may be cancelled
 
-	http.Error(w, "Internal server error", http.StatusInternalServerError)
+	WriteProblem(w, f.WebServer.FrameworkOptions.Problems.NewProblem(context.Background(), appErr, path))
 }
 
 // ValidateRequest is a helper to validate incoming requests
@@ -0,0 +1,188 @@
+package common
+
+import (
+	"bufio"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/wasilak/go-hello-world/utils"
+)
+
+const (
+	// DefaultPodInfoPath is where the Helm chart mounts the downward API volume
+	DefaultPodInfoPath = "/etc/podinfo"
+
+	// DefaultCgroupRoot is the cgroup filesystem of the container
+	DefaultCgroupRoot = "/sys/fs/cgroup"
+
+	// cgroupV1Unlimited is the smallest memory limit cgroup v1 reports for an unlimited
+	// cgroup, the exact value depends on the page size
+	cgroupV1Unlimited = 1 << 62
+)
+
+// InstanceConfig configures where the instance info is read from
+type InstanceConfig struct {
+	// PodInfoPath is the directory of the downward API volume with the labels and
+	// annotations files
+	PodInfoPath string
+
+	// CgroupRoot is the cgroup filesystem the CPU and memory limits are read from
+	CgroupRoot string
+
+	// ContainerImage is the image of the container, which the downward API does not expose
+	ContainerImage string
+}
+
+// InstanceInfo describes the replica and node serving the request, read once at startup
+// from the downward API and the cgroup filesystem. Unavailable values are omitted.
+type InstanceInfo struct {
+	PodName          string            `json:"pod_name,omitempty"`
+	Namespace        string            `json:"namespace,omitempty"`
+	NodeName         string            `json:"node_name,omitempty"`
+	NodeIP           string            `json:"node_ip,omitempty"`
+	PodIP            string            `json:"pod_ip,omitempty"`
+	ServiceAccount   string            `json:"service_account,omitempty"`
+	ContainerImage   string            `json:"container_image,omitempty"`
+	Labels           map[string]string `json:"labels,omitempty"`
+	Annotations      map[string]string `json:"annotations,omitempty"`
+	CPULimit         float64           `json:"cpu_limit,omitempty"`
+	MemoryLimitBytes int64             `json:"memory_limit_bytes,omitempty"`
+}
+
+// LoadInstanceInfo reads the instance info from the POD_NAME, POD_NAMESPACE, NODE_NAME,
+// NODE_IP, POD_IP and POD_SERVICE_ACCOUNT downward API variables and the configured files.
+// Annotations matching the redaction policy are redacted, and missing files, expected
+// outside Kubernetes, are skipped.
+func LoadInstanceInfo(config InstanceConfig, redaction *utils.Redactor) *InstanceInfo {
+	info := &InstanceInfo{
+		PodName:        os.Getenv("POD_NAME"),
+		Namespace:      os.Getenv("POD_NAMESPACE"),
+		NodeName:       os.Getenv("NODE_NAME"),
+		NodeIP:         os.Getenv("NODE_IP"),
+		PodIP:          os.Getenv("POD_IP"),
+		ServiceAccount: os.Getenv("POD_SERVICE_ACCOUNT"),
+		ContainerImage: config.ContainerImage,
+	}
+
+	if config.PodInfoPath != "" {
+		info.Labels = readPodInfoFile(filepath.Join(config.PodInfoPath, "labels"))
+		info.Annotations = readPodInfoFile(filepath.Join(config.PodInfoPath, "annotations"))
+	}
+	for key, value := range info.Annotations {
+		if !redaction.SensitiveField(key) {
+			continue
+		}
+		if redacted := redaction.Value(value); redacted != "" {
+			info.Annotations[key] = redacted
+		} else {
+			delete(info.Annotations, key)
+		}
+	}
+
+	if config.CgroupRoot != "" {
+		info.CPULimit, info.MemoryLimitBytes = readCgroupLimits(config.CgroupRoot)
+	}
+
+	return info
+}
+
+// readPodInfoFile parses a downward API labels or annotations file, one key="value" per line
+func readPodInfoFile(path string) map[string]string {
+	file, err := os.Open(path)
+	if err != nil {
+		logInstanceFileError(path, err)
+		return nil
+	}
+	defer file.Close()
+
+	values := map[string]string{}
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(nil, 1<<20)
+	for scanner.Scan() {
+		key, quoted, found := strings.Cut(scanner.Text(), "=")
+		if !found {
+			continue
+		}
+		value, err := strconv.Unquote(quoted)
+		if err != nil {
+			value = quoted
+		}
+		values[key] = value
+	}
+	if err := scanner.Err(); err != nil {
+		logInstanceFileError(path, err)
+	}
+
+	if len(values) == 0 {
+		return nil
+	}
+	return values
+}
+
+// readCgroupLimits returns the CPU limit in cores and the memory limit in bytes of the
+// container, zero when unlimited. cgroup v2 is tried before cgroup v1.
+func readCgroupLimits(root string) (float64, int64) {
+	if cpuMax, err := readCgroupFile(filepath.Join(root, "cpu.max")); err == nil {
+		// cgroup v2: "<quota> <period>" with "max" for no limit
+		var cpu float64
+		if quota, period, found := strings.Cut(cpuMax, " "); found && quota != "max" {
+			cpu = cpuLimit(quota, period)
+		}
+
+		var memory int64
+		if memoryMax, err := readCgroupFile(filepath.Join(root, "memory.max")); err == nil && memoryMax != "max" {
+			memory, _ = strconv.ParseInt(memoryMax, 10, 64)
+		}
+		return cpu, memory
+	}
+
+	// cgroup v1: a negative quota and a huge memory limit mean no limit
+	var cpu float64
+	quota, quotaErr := readCgroupFile(filepath.Join(root, "cpu", "cpu.cfs_quota_us"))
+	period, periodErr := readCgroupFile(filepath.Join(root, "cpu", "cpu.cfs_period_us"))
+	if quotaErr == nil && periodErr == nil && !strings.HasPrefix(quota, "-") {
+		cpu = cpuLimit(quota, period)
+	}
+
+	var memory int64
+	if limit, err := readCgroupFile(filepath.Join(root, "memory", "memory.limit_in_bytes")); err == nil {
+		if memory, _ = strconv.ParseInt(limit, 10, 64); memory >= cgroupV1Unlimited {
+			memory = 0
+		}
+	}
+	return cpu, memory
+}
+
+// cpuLimit divides a CFS quota by its period
+func cpuLimit(quota, period string) float64 {
+	q, err := strconv.ParseFloat(quota, 64)
+	if err != nil {
+		return 0
+	}
+	p, err := strconv.ParseFloat(period, 64)
+	if err != nil || p <= 0 {
+		return 0
+	}
+	return q / p
+}
+
+// readCgroupFile returns the trimmed content of a cgroup file
+func readCgroupFile(path string) (string, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		logInstanceFileError(path, err)
+		return "", err
+	}
+	return strings.TrimSpace(string(content)), nil
+}
+
+// logInstanceFileError logs unreadable instance info files, missing ones are expected
+func logInstanceFileError(path string, err error) {
+	if !errors.Is(err, fs.ErrNotExist) {
+		utils.ComponentLogger(utils.ComponentServer).Warn("Failed to read instance info", "path", path, "error", err)
+	}
+}
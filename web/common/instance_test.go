@@ -0,0 +1,104 @@
+package common
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/wasilak/go-hello-world/utils"
+)
+
+func writeFiles(t *testing.T, root string, files map[string]string) {
+	t.Helper()
+
+	for name, content := range files {
+		path := filepath.Join(root, name)
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+		require.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+	}
+}
+
+func TestLoadInstanceInfo(t *testing.T) {
+	t.Setenv("POD_NAME", "hello-7d9c-x2k4p")
+	t.Setenv("POD_NAMESPACE", "demo")
+	t.Setenv("NODE_NAME", "node-1")
+	t.Setenv("NODE_IP", "10.0.0.1")
+	t.Setenv("POD_IP", "10.1.2.3")
+
+	podInfo := t.TempDir()
+	writeFiles(t, podInfo, map[string]string{
+		"labels":      "app=\"go-hello-world\"\npod-template-hash=\"7d9c\"\n",
+		"annotations": "chart=\"go-hello-world-chart-0.6.0\"\nvault.hashicorp.com/agent-inject-token=\"s3cret\"\nnote=\"line\\nbreak\"\n",
+	})
+
+	cgroup := t.TempDir()
+	writeFiles(t, cgroup, map[string]string{
+		"cpu.max":    "50000 100000\n",
+		"memory.max": "268435456\n",
+	})
+
+	redactor, appErr := utils.NewRedactor(utils.RedactionConfig{HeaderPatterns: []string{"token"}})
+	require.Nil(t, appErr)
+
+	info := LoadInstanceInfo(InstanceConfig{PodInfoPath: podInfo, CgroupRoot: cgroup, ContainerImage: "ghcr.io/wasilak/go-hello-world:1.8.2"}, redactor)
+
+	assert.Equal(t, "hello-7d9c-x2k4p", info.PodName)
+	assert.Equal(t, "demo", info.Namespace)
+	assert.Equal(t, "node-1", info.NodeName)
+	assert.Equal(t, "10.0.0.1", info.NodeIP)
+	assert.Equal(t, "10.1.2.3", info.PodIP)
+	assert.Equal(t, "ghcr.io/wasilak/go-hello-world:1.8.2", info.ContainerImage)
+	assert.Equal(t, map[string]string{"app": "go-hello-world", "pod-template-hash": "7d9c"}, info.Labels)
+	assert.Equal(t, "line\nbreak", info.Annotations["note"])
+	assert.Equal(t, utils.RedactedValue, info.Annotations["vault.hashicorp.com/agent-inject-token"])
+	assert.Equal(t, 0.5, info.CPULimit)
+	assert.Equal(t, int64(256<<20), info.MemoryLimitBytes)
+}
+
+func TestLoadInstanceInfoOutsideKubernetes(t *testing.T) {
+	info := LoadInstanceInfo(InstanceConfig{PodInfoPath: filepath.Join(t.TempDir(), "missing"), CgroupRoot: t.TempDir()}, nil)
+
+	assert.Nil(t, info.Labels)
+	assert.Nil(t, info.Annotations)
+	assert.Zero(t, info.CPULimit)
+	assert.Zero(t, info.MemoryLimitBytes)
+}
+
+func TestReadCgroupLimits(t *testing.T) {
+	t.Run("v2 unlimited", func(t *testing.T) {
+		root := t.TempDir()
+		writeFiles(t, root, map[string]string{"cpu.max": "max 100000\n", "memory.max": "max\n"})
+
+		cpu, memory := readCgroupLimits(root)
+		assert.Zero(t, cpu)
+		assert.Zero(t, memory)
+	})
+
+	t.Run("v1", func(t *testing.T) {
+		root := t.TempDir()
+		writeFiles(t, root, map[string]string{
+			"cpu/cpu.cfs_quota_us":         "200000\n",
+			"cpu/cpu.cfs_period_us":        "100000\n",
+			"memory/memory.limit_in_bytes": "536870912\n",
+		})
+
+		cpu, memory := readCgroupLimits(root)
+		assert.Equal(t, 2.0, cpu)
+		assert.Equal(t, int64(512<<20), memory)
+	})
+
+	t.Run("v1 unlimited", func(t *testing.T) {
+		root := t.TempDir()
+		writeFiles(t, root, map[string]string{
+			"cpu/cpu.cfs_quota_us":         "-1\n",
+			"cpu/cpu.cfs_period_us":        "100000\n",
+			"memory/memory.limit_in_bytes": "9223372036854771712\n",
+		})
+
+		cpu, memory := readCgroupLimits(root)
+		assert.Zero(t, cpu)
+		assert.Zero(t, memory)
+	})
+}
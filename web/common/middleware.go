@@ -0,0 +1,296 @@
+package common
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+
+	"github.com/wasilak/go-hello-world/utils"
+)
+
+const (
+	// MiddlewareRecovery recovers panics of the handlers and the middleware after it
+	MiddlewareRecovery = "recovery"
+
+	// MiddlewareOtel traces requests and records the captured headers, with -otel-enabled
+	MiddlewareOtel = "otel"
+
+	// MiddlewarePrometheus records the Prometheus metrics of the framework
+	MiddlewarePrometheus = "prometheus"
+
+	// MiddlewareHTTPMetrics records the OpenTelemetry HTTP server metrics, with -otel-metrics-exporters
+	MiddlewareHTTPMetrics = "http_metrics"
+
+	// MiddlewareCORS sets the CORS headers and answers preflight requests, with
+	// -cors-allowed-origins
+	MiddlewareCORS = "cors"
+
+	// MiddlewareSecurityHeaders sets the security headers of -security-headers
+	MiddlewareSecurityHeaders = "security_headers"
+
+	// MiddlewareCompression compresses responses with the negotiated encoding
+	MiddlewareCompression = "compression"
+
+	// MiddlewareRequestID accepts, generates and returns request IDs
+	MiddlewareRequestID = "request_id"
+
+	// MiddlewareErrorScope labels error metrics with the framework and route
+	MiddlewareErrorScope = "error_scope"
+
+	// MiddlewareAccessLog writes the access log
+	MiddlewareAccessLog = "access_log"
+
+	// MiddlewareRouterLog is the request log of gin, written through slog
+	MiddlewareRouterLog = "router_log"
+
+	// MiddlewareBodyLimit rejects request bodies above -server-max-body-bytes, fiber
+	// enforces the limit in the server instead
+	MiddlewareBodyLimit = "body_limit"
+
+	// MiddlewareRateLimit applies the rate limits
+	MiddlewareRateLimit = "rate_limit"
+
+	// MiddlewareConcurrency applies the concurrency limit
+	MiddlewareConcurrency = "concurrency"
+
+	// MiddlewareFault injects the faults configured through the admin API
+	MiddlewareFault = "fault"
+
+	// MiddlewareStatsviz serves the statsviz routes, with -statsviz-enabled. It is not a
+	// middleware, so its position in the chain has no effect.
+	MiddlewareStatsviz = "statsviz"
+)
+
+// MiddlewareActiveGaugeVec is 1 for the middleware in the chain of the running server and 0
+// for the others, so latency changes can be related to the chain
+var MiddlewareActiveGaugeVec = promauto.NewGaugeVec(
+	prometheus.GaugeOpts{
+		Name: "http_middleware_active",
+		Help: "Whether a middleware is in the chain of the running web server, 1 or 0",
+	},
+	[]string{"middleware"},
+)
+
+// DefaultMiddlewareOrder lists every middleware in the default order, the first one is the
+// outermost
+func DefaultMiddlewareOrder() []string {
+	return []string{
+		MiddlewareRecovery,
+		MiddlewareOtel,
+		MiddlewarePrometheus,
+		MiddlewareHTTPMetrics,
+		MiddlewareCORS,
+		MiddlewareSecurityHeaders,
+		MiddlewareCompression,
+		MiddlewareRequestID,
+		MiddlewareErrorScope,
+		MiddlewareAccessLog,
+		MiddlewareRouterLog,
+		MiddlewareBodyLimit,
+		MiddlewareRateLimit,
+		MiddlewareConcurrency,
+		MiddlewareFault,
+		MiddlewareStatsviz,
+	}
+}
+
+// MiddlewareConfig enables and orders the middleware of every framework
+type MiddlewareConfig struct {
+	// Order lists middleware from the outermost, those not listed follow in the default order
+	Order []string `json:"order,omitempty"`
+
+	// Disabled middleware are left out of the chain
+	Disabled []string `json:"disabled,omitempty"`
+}
+
+// Validate checks that only known middleware are ordered and disabled, each at most once
+func (c MiddlewareConfig) Validate() *utils.AppError {
+	known := DefaultMiddlewareOrder()
+
+	for field, names := range map[string][]string{"order": c.Order, "disabled": c.Disabled} {
+		seen := make(map[string]bool, len(names))
+		for _, name := range names {
+			if !slices.Contains(known, name) {
+				return utils.NewAppError(utils.ValidationError, fmt.Sprintf("unknown middleware, expected one of %s", strings.Join(known, ", ")), nil).
+					WithCode("validation.middleware").
+					AddContext(utils.ContextMiddleware, name)
+			}
+			if seen[name] {
+				return utils.NewAppError(utils.ValidationError, "middleware listed more than once", nil).
+					WithCode("validation.middleware").
+					AddContext(utils.ContextMiddleware, name).
+					AddContext("field", field)
+			}
+			seen[name] = true
+		}
+	}
+	return nil
+}
+
+// Chain returns the enabled middleware in order, the first one is the outermost
+func (c MiddlewareConfig) Chain() []string {
+	chain := make([]string, 0, len(DefaultMiddlewareOrder()))
+	for _, name := range append(slices.Clone(c.Order), DefaultMiddlewareOrder()...) {
+		if !slices.Contains(chain, name) && !slices.Contains(c.Disabled, name) {
+			chain = append(chain, name)
+		}
+	}
+	return chain
+}
+
+// MiddlewareChain describes the middleware of a web server
+type MiddlewareChain struct {
+	Framework string `json:"framework"`
+
+	// Active middleware in order, the first one is the outermost
+	Active []string `json:"active"`
+
+	// Disabled middleware are turned off by the configuration
+	Disabled []string `json:"disabled"`
+
+	// Unavailable middleware are enabled, but the framework or the options do not provide them
+	Unavailable []string `json:"unavailable"`
+}
+
+// Title names the framework of the chain
+func (c MiddlewareChain) Title() string {
+	return "Middleware of " + c.Framework
+}
+
+// MiddlewareResponse is the middleware configuration with the chain of the running server
+type MiddlewareResponse struct {
+	Config MiddlewareConfig `json:"config"`
+	Chain  MiddlewareChain  `json:"chain"`
+}
+
+// MiddlewareRegistry holds the middleware configuration and the chain of the running web
+// server. It outlives framework switches, a changed configuration applies when the router
+// is rebuilt, and a nil registry serves the default chain.
+type MiddlewareRegistry struct {
+	mu      sync.Mutex
+	initial MiddlewareConfig
+	config  MiddlewareConfig
+	chain   MiddlewareChain
+}
+
+// NewMiddlewareRegistry creates the registry, the configuration must be valid
+func NewMiddlewareRegistry(config MiddlewareConfig) *MiddlewareRegistry {
+	return &MiddlewareRegistry{initial: config, config: config}
+}
+
+// ParseMiddlewareConfig builds and validates the configuration from the command line values
+func ParseMiddlewareConfig(order, disabled []string) (MiddlewareConfig, *utils.AppError) {
+	config := MiddlewareConfig{Order: order, Disabled: disabled}
+	return config, config.Validate()
+}
+
+// Config returns the configuration the next router is built with
+func (r *MiddlewareRegistry) Config() MiddlewareConfig {
+	if r == nil {
+		return MiddlewareConfig{}
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.config
+}
+
+// SetConfig validates and stores a new configuration, it applies when the router is rebuilt
+func (r *MiddlewareRegistry) SetConfig(config MiddlewareConfig) *utils.AppError {
+	if appErr := config.Validate(); appErr != nil {
+		return appErr
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.config = config
+	return nil
+}
+
+// Reset restores the configuration the registry was created with
+func (r *MiddlewareRegistry) Reset() MiddlewareConfig {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.config = r.initial
+	return r.config
+}
+
+// Chain returns the chain of the last router built
+func (r *MiddlewareRegistry) Chain() MiddlewareChain {
+	if r == nil {
+		return MiddlewareChain{}
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.chain
+}
+
+// Build returns the chain of framework from the middleware it provides and records it as
+// the chain of the running server
+func (r *MiddlewareRegistry) Build(framework string, available func(name string) bool) MiddlewareChain {
+	config := r.Config()
+
+	chain := MiddlewareChain{Framework: framework, Active: []string{}, Disabled: []string{}, Unavailable: []string{}}
+	enabled := config.Chain()
+	for _, name := range enabled {
+		if available(name) {
+			chain.Active = append(chain.Active, name)
+		} else {
+			chain.Unavailable = append(chain.Unavailable, name)
+		}
+	}
+	for _, name := range DefaultMiddlewareOrder() {
+		if !slices.Contains(enabled, name) {
+			chain.Disabled = append(chain.Disabled, name)
+		}
+	}
+
+	for _, name := range DefaultMiddlewareOrder() {
+		active := 0.0
+		if slices.Contains(chain.Active, name) {
+			active = 1
+		}
+		MiddlewareActiveGaugeVec.WithLabelValues(name).Set(active)
+	}
+
+	if r != nil {
+		r.mu.Lock()
+		r.chain = chain
+		r.mu.Unlock()
+	}
+	return chain
+}
+
+// BuildMiddleware returns the chain of w and its middleware in order, from the middleware
+// the framework provides. A name without middleware is in the chain without applying
+// any, e.g. statsviz, which registers routes instead.
+func BuildMiddleware[M any](w *WebServer, available map[string][]M) (MiddlewareChain, []M) {
+	chain := w.FrameworkOptions.Middleware.Build(w.Framework, func(name string) bool {
+		_, ok := available[name]
+		return ok
+	})
+
+	var middleware []M
+	for _, name := range chain.Active {
+		middleware = append(middleware, available[name]...)
+	}
+	return chain, middleware
+}
+
+// Includes reports whether name is in the active chain
+func (c MiddlewareChain) Includes(name string) bool {
+	return slices.Contains(c.Active, name)
+}
+
+// MiddlewareHandler serves the chain of the running server in the negotiated format
+func MiddlewareHandler(options FrameworkOptions) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		WriteResponse(w, r, options.Problems, http.StatusOK, options.Middleware.Chain())
+	})
+}
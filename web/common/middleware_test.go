@@ -0,0 +1,56 @@
+package common
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseMiddlewareConfig(t *testing.T) {
+	config, appErr := ParseMiddlewareConfig([]string{MiddlewareCompression, MiddlewareRecovery}, []string{MiddlewareOtel})
+	require.Nil(t, appErr)
+
+	chain := config.Chain()
+	assert.Equal(t, []string{MiddlewareCompression, MiddlewareRecovery, MiddlewarePrometheus}, chain[:3])
+	assert.NotContains(t, chain, MiddlewareOtel)
+	assert.Len(t, chain, len(DefaultMiddlewareOrder())-1)
+
+	for _, tc := range []struct {
+		name            string
+		order, disabled []string
+	}{
+		{"Unknown middleware", []string{"csrf"}, nil},
+		{"Unknown disabled middleware", nil, []string{"csrf"}},
+		{"Duplicate", []string{MiddlewareCompression, MiddlewareCompression}, nil},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			_, appErr := ParseMiddlewareConfig(tc.order, tc.disabled)
+			require.NotNil(t, appErr)
+			assert.Equal(t, "validation.middleware", string(appErr.Code))
+		})
+	}
+}
+
+func TestMiddlewareRegistryBuild(t *testing.T) {
+	registry := NewMiddlewareRegistry(MiddlewareConfig{Disabled: []string{MiddlewareCompression}})
+
+	provided := []string{MiddlewareFault, MiddlewareCompression, MiddlewareRecovery}
+	chain := registry.Build("chi", func(name string) bool {
+		return slices.Contains(provided, name)
+	})
+
+	assert.Equal(t, "chi", chain.Framework)
+	assert.Equal(t, []string{MiddlewareRecovery, MiddlewareFault}, chain.Active)
+	assert.Equal(t, []string{MiddlewareCompression}, chain.Disabled)
+	assert.Contains(t, chain.Unavailable, MiddlewareOtel)
+	assert.True(t, chain.Includes(MiddlewareFault))
+	assert.Equal(t, chain, registry.Chain())
+
+	require.Nil(t, registry.SetConfig(MiddlewareConfig{Order: []string{MiddlewareFault}}))
+	assert.Equal(t, chain, registry.Chain(), "Expected the chain to change only when the router is rebuilt")
+	assert.NotNil(t, registry.SetConfig(MiddlewareConfig{Disabled: []string{"csrf"}}))
+
+	assert.Equal(t, MiddlewareConfig{Disabled: []string{MiddlewareCompression}}, registry.Reset())
+}
@@ -0,0 +1,209 @@
+package common
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	otelgocommon "github.com/wasilak/otelgo/common"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/exporters/otlp/otlpmetric/otlpmetricgrpc"
+	"go.opentelemetry.io/otel/exporters/otlp/otlpmetric/otlpmetrichttp"
+	otelprom "go.opentelemetry.io/otel/exporters/prometheus"
+	"go.opentelemetry.io/otel/metric"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/resource"
+	semconv "go.opentelemetry.io/otel/semconv/v1.40.0"
+	"go.opentelemetry.io/otel/semconv/v1.40.0/httpconv"
+
+	"github.com/wasilak/go-hello-world/utils"
+)
+
+const (
+	// MetricsExporterOTLP pushes OTel metrics using the OTLP exporter configured via OTEL_EXPORTER_OTLP_* env vars
+	MetricsExporterOTLP = "otlp"
+
+	// MetricsExporterPrometheus bridges OTel metrics to the default Prometheus registry served on /metrics
+	MetricsExporterPrometheus = "prometheus"
+)
+
+// meterName is the instrumentation scope of the HTTP server metrics
+const meterName = "github.com/wasilak/go-hello-world/web/common"
+
+// InitMeterProvider creates a MeterProvider with one reader per requested exporter.
+// It returns nil when no exporters are requested.
+func InitMeterProvider(ctx context.Context, exporters []string) (*sdkmetric.MeterProvider, error) {
+	var opts []sdkmetric.Option
+
+	for _, name := range exporters {
+		switch strings.ToLower(strings.TrimSpace(name)) {
+		case "":
+			continue
+		case MetricsExporterOTLP:
+			exporter, err := newOTLPMetricExporter(ctx)
+			if err != nil {
+				return nil, utils.WrapError(err, utils.ConfigError, "failed to create OTLP metrics exporter")
+			}
+			opts = append(opts, sdkmetric.WithReader(sdkmetric.NewPeriodicReader(exporter)))
+		case MetricsExporterPrometheus:
+			reader, err := otelprom.New()
+			if err != nil {
+				return nil, utils.WrapError(err, utils.ConfigError, "failed to create Prometheus metrics bridge")
+			}
+			opts = append(opts, sdkmetric.WithReader(reader))
+		default:
+			return nil, utils.NewAppError(utils.ConfigError, "unknown metrics exporter", nil).AddContext("exporter", name)
+		}
+	}
+
+	if len(opts) == 0 {
+		return nil, nil
+	}
+
+	res, err := resource.New(ctx,
+		resource.WithFromEnv(),
+		resource.WithTelemetrySDK(),
+		resource.WithHost(),
+		resource.WithAttributes(semconv.ServiceName(utils.GetAppName())),
+	)
+	if err != nil {
+		return nil, utils.WrapError(err, utils.ConfigError, "failed to create metrics resource")
+	}
+
+	opts = append(opts, sdkmetric.WithResource(res))
+
+	return sdkmetric.NewMeterProvider(opts...), nil
+}
+
+// newOTLPMetricExporter follows the same protocol selection as otelgo
+func newOTLPMetricExporter(ctx context.Context) (sdkmetric.Exporter, error) {
+	if otelgocommon.IsOtlpProtocolGrpc("OTEL_EXPORTER_OTLP_METRICS_PROTOCOL") {
+		return otlpmetricgrpc.New(ctx)
+	}
+	return otlpmetrichttp.New(ctx)
+}
+
+// HTTPServerMetrics records the OTel HTTP server semantic convention metrics
+type HTTPServerMetrics struct {
+	requestDuration httpconv.ServerRequestDuration
+	activeRequests  httpconv.ServerActiveRequests
+	requestSize     httpconv.ServerRequestBodySize
+	responseSize    httpconv.ServerResponseBodySize
+}
+
+// NewHTTPServerMetrics creates the HTTP server instruments on the given provider
+func NewHTTPServerMetrics(mp metric.MeterProvider) (*HTTPServerMetrics, error) {
+	meter := mp.Meter(meterName)
+
+	requestDuration, err := httpconv.NewServerRequestDuration(meter)
+	if err != nil {
+		return nil, utils.WrapError(err, utils.ConfigError, "failed to create request duration instrument")
+	}
+
+	activeRequests, err := httpconv.NewServerActiveRequests(meter)
+	if err != nil {
+		return nil, utils.WrapError(err, utils.ConfigError, "failed to create active requests instrument")
+	}
+
+	requestSize, err := httpconv.NewServerRequestBodySize(meter)
+	if err != nil {
+		return nil, utils.WrapError(err, utils.ConfigError, "failed to create request size instrument")
+	}
+
+	responseSize, err := httpconv.NewServerResponseBodySize(meter)
+	if err != nil {
+		return nil, utils.WrapError(err, utils.ConfigError, "failed to create response size instrument")
+	}
+
+	return &HTTPServerMetrics{
+		requestDuration: requestDuration,
+		activeRequests:  activeRequests,
+		requestSize:     requestSize,
+		responseSize:    responseSize,
+	}, nil
+}
+
+// HTTPServerMetricsDone is returned by Begin and must be called once the response has been written
+type HTTPServerMetricsDone func(route string, statusCode int, requestSize, responseSize int64)
+
+// Begin marks a request as active and returns the function finishing the measurement.
+// It is the framework-agnostic core used by the per-framework middlewares.
+func (m *HTTPServerMetrics) Begin(ctx context.Context, method, scheme, framework string) HTTPServerMetricsDone {
+	start := time.Now()
+	requestMethod := normalizeRequestMethod(method)
+	frameworkAttr := attribute.String("web.framework", framework)
+
+	m.activeRequests.Add(ctx, 1, requestMethod, scheme, frameworkAttr)
+
+	return func(route string, statusCode int, requestSize, responseSize int64) {
+		m.activeRequests.Add(ctx, -1, requestMethod, scheme, frameworkAttr)
+
+		attrs := []attribute.KeyValue{
+			frameworkAttr,
+			m.requestDuration.AttrResponseStatusCode(statusCode),
+		}
+		if route != "" {
+			attrs = append(attrs, m.requestDuration.AttrRoute(route))
+		}
+		if statusCode >= http.StatusInternalServerError {
+			attrs = append(attrs, semconv.ErrorTypeKey.String(strconv.Itoa(statusCode)))
+		}
+
+		m.requestDuration.Record(ctx, time.Since(start).Seconds(), requestMethod, scheme, attrs...)
+		if requestSize >= 0 {
+			m.requestSize.Record(ctx, requestSize, requestMethod, scheme, attrs...)
+		}
+		m.responseSize.Record(ctx, responseSize, requestMethod, scheme, attrs...)
+	}
+}
+
+// Middleware returns net/http middleware recording the HTTP server metrics.
+// routeFn is called after the handler, when routers like chi have resolved the route template.
+func (m *HTTPServerMetrics) Middleware(framework string, routeFn func(*http.Request) string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			done := m.Begin(r.Context(), r.Method, RequestScheme(r), framework)
+			recorder := NewResponseRecorder(w)
+
+			next.ServeHTTP(recorder, r)
+
+			done(routeFn(r), recorder.Status, r.ContentLength, recorder.BytesWritten)
+		})
+	}
+}
+
+// RequestScheme returns the url.scheme of an incoming request
+func RequestScheme(r *http.Request) string {
+	if r.TLS != nil {
+		return "https"
+	}
+	return "http"
+}
+
+// normalizeRequestMethod maps unknown methods to _OTHER to keep cardinality bounded
+func normalizeRequestMethod(method string) httpconv.RequestMethodAttr {
+	switch strings.ToUpper(method) {
+	case http.MethodConnect:
+		return httpconv.RequestMethodConnect
+	case http.MethodDelete:
+		return httpconv.RequestMethodDelete
+	case http.MethodGet:
+		return httpconv.RequestMethodGet
+	case http.MethodHead:
+		return httpconv.RequestMethodHead
+	case http.MethodOptions:
+		return httpconv.RequestMethodOptions
+	case http.MethodPatch:
+		return httpconv.RequestMethodPatch
+	case http.MethodPost:
+		return httpconv.RequestMethodPost
+	case http.MethodPut:
+		return httpconv.RequestMethodPut
+	case http.MethodTrace:
+		return httpconv.RequestMethodTrace
+	default:
+		return httpconv.RequestMethodOther
+	}
+}
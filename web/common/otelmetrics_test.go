@@ -0,0 +1,91 @@
+package common
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+)
+
+func TestHTTPServerMetricsMiddleware(t *testing.T) {
+	reader := sdkmetric.NewManualReader()
+	provider := sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader))
+
+	metrics, err := NewHTTPServerMetrics(provider)
+	require.NoError(t, err)
+
+	handler := metrics.Middleware("test", func(r *http.Request) string { return "/items/{id}" })(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusTeapot)
+			_, _ = w.Write([]byte("hello"))
+		}),
+	)
+
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/items/1", nil))
+
+	var rm metricdata.ResourceMetrics
+	require.NoError(t, reader.Collect(context.Background(), &rm))
+	require.Len(t, rm.ScopeMetrics, 1)
+
+	byName := map[string]metricdata.Metrics{}
+	for _, m := range rm.ScopeMetrics[0].Metrics {
+		byName[m.Name] = m
+	}
+
+	t.Run("Semantic convention names", func(t *testing.T) {
+		for _, name := range []string{
+			"http.server.request.duration",
+			"http.server.active_requests",
+			"http.server.request.body.size",
+			"http.server.response.body.size",
+		} {
+			assert.Contains(t, byName, name)
+		}
+	})
+
+	t.Run("Duration attributes", func(t *testing.T) {
+		duration := byName["http.server.request.duration"].Data.(metricdata.Histogram[float64])
+		require.Len(t, duration.DataPoints, 1)
+
+		attrs := duration.DataPoints[0].Attributes
+		route, _ := attrs.Value("http.route")
+		status, _ := attrs.Value("http.response.status_code")
+		method, _ := attrs.Value("http.request.method")
+		framework, _ := attrs.Value("web.framework")
+
+		assert.Equal(t, "/items/{id}", route.AsString())
+		assert.Equal(t, int64(http.StatusTeapot), status.AsInt64())
+		assert.Equal(t, http.MethodGet, method.AsString())
+		assert.Equal(t, "test", framework.AsString())
+	})
+
+	t.Run("Response size", func(t *testing.T) {
+		size := byName["http.server.response.body.size"].Data.(metricdata.Histogram[int64])
+		require.Len(t, size.DataPoints, 1)
+		assert.Equal(t, int64(5), size.DataPoints[0].Sum)
+	})
+
+	t.Run("Active requests back to zero", func(t *testing.T) {
+		active := byName["http.server.active_requests"].Data.(metricdata.Sum[int64])
+		require.Len(t, active.DataPoints, 1)
+		assert.Equal(t, int64(0), active.DataPoints[0].Value)
+	})
+}
+
+func TestInitMeterProvider(t *testing.T) {
+	t.Run("No exporters", func(t *testing.T) {
+		provider, err := InitMeterProvider(context.Background(), []string{""})
+		assert.NoError(t, err)
+		assert.Nil(t, provider)
+	})
+
+	t.Run("Unknown exporter", func(t *testing.T) {
+		_, err := InitMeterProvider(context.Background(), []string{"statsd"})
+		assert.Error(t, err)
+	})
+}
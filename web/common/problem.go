@@ -0,0 +1,178 @@
+package common
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/wasilak/go-hello-world/utils"
+)
+
+const (
+	// ProblemContentType is the RFC 9457 media type of error responses
+	ProblemContentType = "application/problem+json"
+
+	// DefaultProblemTypeURIPrefix prefixes the error type to build the problem type URI
+	DefaultProblemTypeURIPrefix = "urn:go-hello-world:problem:"
+
+	// redactedValue replaces context values which are not allowlisted
+	redactedValue = utils.RedactedValue
+)
+
+// ProblemConfig configures the RFC 9457 problem details rendered for errors
+type ProblemConfig struct {
+	TypeURIPrefix    string
+	ContextAllowlist []string
+
+	// Redaction redacts sensitive context values, allowlisted ones included
+	Redaction *utils.Redactor
+}
+
+// Problem is an RFC 9457 problem details body, with the trace ID and the AppError
+// context as extension members
+type Problem struct {
+	Type     string                 `json:"type"`
+	Title    string                 `json:"title"`
+	Status   int                    `json:"status"`
+	Code     string                 `json:"code,omitempty"`
+	Detail   string                 `json:"detail,omitempty"`
+	Instance string                 `json:"instance,omitempty"`
+	TraceID  string                 `json:"trace_id,omitempty"`
+	Context  map[string]interface{} `json:"context,omitempty"`
+}
+
+// ProblemStatus maps an error type to the HTTP status code returned to clients
+func ProblemStatus(errType utils.ErrorType) int {
+	switch errType {
+	case utils.ValidationError:
+		return http.StatusBadRequest
+	case utils.FrameworkError, utils.OverloadError:
+		return http.StatusServiceUnavailable
+	case utils.RateLimitError:
+		return http.StatusTooManyRequests
+	case utils.ForbiddenError:
+		return http.StatusForbidden
+	case utils.UnauthorizedError:
+		return http.StatusUnauthorized
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// problemTitle returns the short, human readable summary of an error type
+func problemTitle(errType utils.ErrorType) string {
+	switch errType {
+	case utils.ConfigError:
+		return "Configuration error"
+	case utils.RuntimeError:
+		return "Runtime error"
+	case utils.FrameworkError:
+		return "Framework error"
+	case utils.ValidationError:
+		return "Validation error"
+	case utils.RateLimitError:
+		return "Too many requests"
+	case utils.OverloadError:
+		return "Service overloaded"
+	case utils.ForbiddenError:
+		return "Forbidden"
+	case utils.UnauthorizedError:
+		return "Unauthorized"
+	default:
+		return http.StatusText(http.StatusInternalServerError)
+	}
+}
+
+// NewProblem builds the problem details of an AppError. The detail is the AppError message,
+// the wrapped error is only logged, and context values not on the allowlist or matching the
+// redaction policy are redacted.
+func (c ProblemConfig) NewProblem(ctx context.Context, appErr *utils.AppError, instance string) Problem {
+	prefix := c.TypeURIPrefix
+	if prefix == "" {
+		prefix = DefaultProblemTypeURIPrefix
+	}
+
+	problem := Problem{
+		Type:     prefix + string(appErr.Type),
+		Title:    problemTitle(appErr.Type),
+		Status:   ProblemStatus(appErr.Type),
+		Code:     string(appErr.Code),
+		Detail:   appErr.Message,
+		Instance: instance,
+		TraceID:  problemTraceID(ctx),
+	}
+
+	if fields := c.Redaction.Fields(appErr.Context()); len(fields) > 0 {
+		problem.Context = make(map[string]interface{}, len(fields))
+		for key, value := range fields {
+			problem.Context[key] = c.contextValue(key, value)
+		}
+	}
+
+	return problem
+}
+
+// StatusProblem builds a problem for errors raised by the frameworks themselves, e.g. unknown routes
+func (c ProblemConfig) StatusProblem(ctx context.Context, status int, detail, instance string) Problem {
+	if detail == http.StatusText(status) {
+		detail = ""
+	}
+
+	return Problem{
+		Type:     "about:blank",
+		Title:    http.StatusText(status),
+		Status:   status,
+		Detail:   detail,
+		Instance: instance,
+		TraceID:  problemTraceID(ctx),
+	}
+}
+
+// FromError builds the problem of any error, errors which are not AppErrors become
+// a generic 500 without details
+func (c ProblemConfig) FromError(ctx context.Context, err error, instance string) Problem {
+	if appErr, ok := utils.AsAppError(err); ok {
+		return c.NewProblem(ctx, appErr, instance)
+	}
+	return c.StatusProblem(ctx, http.StatusInternalServerError, "", instance)
+}
+
+// Write renders err as problem details for the request
+func (c ProblemConfig) Write(w http.ResponseWriter, r *http.Request, err error) {
+	WriteProblem(w, c.FromError(r.Context(), err, r.URL.Path))
+}
+
+// WriteProblem writes the problem with the application/problem+json content type
+func WriteProblem(w http.ResponseWriter, problem Problem) {
+	w.Header().Set("Content-Type", ProblemContentType)
+	w.WriteHeader(problem.Status)
+
+	if err := json.NewEncoder(w).Encode(problem); err != nil {
+		slog.Error("Error encoding problem response", "error", err)
+	}
+}
+
+// contextValue returns the value exposed to clients for an AppError context field
+func (c ProblemConfig) contextValue(key string, value interface{}) interface{} {
+	for _, allowed := range c.ContextAllowlist {
+		if strings.EqualFold(allowed, key) {
+			if err, ok := value.(error); ok {
+				return err.Error()
+			}
+			return value
+		}
+	}
+	return redactedValue
+}
+
+// problemTraceID returns the ID of the trace the error occurred in, if any
+func problemTraceID(ctx context.Context) string {
+	if spanContext := trace.SpanContextFromContext(ctx); spanContext.HasTraceID() {
+		return spanContext.TraceID().String()
+	}
+	return ""
+}
@@ -0,0 +1,84 @@
+package common
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/wasilak/go-hello-world/utils"
+)
+
+func TestProblemStatus(t *testing.T) {
+	assert.Equal(t, http.StatusBadRequest, ProblemStatus(utils.ValidationError))
+	assert.Equal(t, http.StatusInternalServerError, ProblemStatus(utils.ConfigError))
+	assert.Equal(t, http.StatusInternalServerError, ProblemStatus(utils.RuntimeError))
+	assert.Equal(t, http.StatusServiceUnavailable, ProblemStatus(utils.FrameworkError))
+	assert.Equal(t, http.StatusForbidden, ProblemStatus(utils.ForbiddenError))
+	assert.Equal(t, http.StatusUnauthorized, ProblemStatus(utils.UnauthorizedError))
+}
+
+func TestNewProblem(t *testing.T) {
+	config := ProblemConfig{ContextAllowlist: []string{"mode"}}
+	traceID, _ := trace.TraceIDFromHex("4bf92f3577b34da6a3ce929d0e0e4736")
+	ctx := trace.ContextWithSpanContext(context.Background(), trace.NewSpanContext(trace.SpanContextConfig{TraceID: traceID}))
+
+	appErr := utils.WrapError(errors.New("secret internals"), utils.ValidationError, "invalid chain mode").
+		AddContext("mode", "random").
+		AddContext("token", "abc")
+
+	problem := config.NewProblem(ctx, appErr, "/chain")
+
+	assert.Equal(t, DefaultProblemTypeURIPrefix+"validation", problem.Type)
+	assert.Equal(t, "Validation error", problem.Title)
+	assert.Equal(t, http.StatusBadRequest, problem.Status)
+	assert.Equal(t, "invalid chain mode", problem.Detail)
+	assert.Equal(t, "/chain", problem.Instance)
+	assert.Equal(t, traceID.String(), problem.TraceID)
+	assert.Equal(t, map[string]interface{}{"mode": "random", "token": redactedValue}, problem.Context)
+}
+
+func TestNewProblemRedaction(t *testing.T) {
+	redactor, appErr := utils.NewRedactor(utils.RedactionConfig{Fields: []string{"token"}, Mode: utils.RedactionHash, HashKey: "k"})
+	require.Nil(t, appErr)
+	config := ProblemConfig{ContextAllowlist: []string{"mode", "token"}, Redaction: redactor}
+
+	problem := config.NewProblem(context.Background(), utils.NewAppError(utils.ValidationError, "invalid token", nil).
+		AddContext("mode", "random").
+		AddContext("token", "abc"), "/")
+
+	assert.Equal(t, "random", problem.Context["mode"])
+	assert.Equal(t, redactor.Value("abc"), problem.Context["token"], "Expected allowlisted sensitive fields to be hashed")
+	assert.NotContains(t, problem.Context["token"], "abc")
+}
+
+func TestFromError(t *testing.T) {
+	problem := ProblemConfig{}.FromError(context.Background(), errors.New("boom"), "/")
+
+	assert.Equal(t, "about:blank", problem.Type)
+	assert.Equal(t, http.StatusInternalServerError, problem.Status)
+	assert.Equal(t, "Internal Server Error", problem.Title)
+	assert.Empty(t, problem.Detail)
+}
+
+func TestWriteProblem(t *testing.T) {
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/logger", nil)
+
+	ProblemConfig{TypeURIPrefix: "https://example.com/problems/"}.Write(rec, req, utils.NewAppError(utils.RuntimeError, "failed", nil))
+
+	assert.Equal(t, http.StatusInternalServerError, rec.Code)
+	assert.Equal(t, ProblemContentType, rec.Header().Get("Content-Type"))
+
+	var body map[string]interface{}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &body))
+	assert.Equal(t, "https://example.com/problems/runtime", body["type"])
+	assert.Equal(t, "/logger", body["instance"])
+	assert.NotContains(t, body, "context")
+}
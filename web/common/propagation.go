@@ -0,0 +1,93 @@
+package common
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	"go.opentelemetry.io/contrib/propagators/b3"
+	"go.opentelemetry.io/contrib/propagators/jaeger"
+	"go.opentelemetry.io/otel/baggage"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/wasilak/go-hello-world/utils"
+)
+
+const (
+	// PropagatorTraceContext is the W3C traceparent/tracestate format
+	PropagatorTraceContext = "tracecontext"
+
+	// PropagatorBaggage is the W3C baggage format
+	PropagatorBaggage = "baggage"
+
+	// PropagatorB3 is the B3 single header format
+	PropagatorB3 = "b3"
+
+	// PropagatorB3Multi is the B3 multiple headers (X-B3-*) format
+	PropagatorB3Multi = "b3multi"
+
+	// PropagatorJaeger is the uber-trace-id format
+	PropagatorJaeger = "jaeger"
+)
+
+// AllPropagators lists the supported propagation formats, using the OTEL_PROPAGATORS names
+func AllPropagators() []string {
+	return []string{PropagatorTraceContext, PropagatorBaggage, PropagatorB3, PropagatorB3Multi, PropagatorJaeger}
+}
+
+// NewPropagator builds a composite propagator from the given format names, in order
+func NewPropagator(names []string) (propagation.TextMapPropagator, error) {
+	var propagators []propagation.TextMapPropagator
+
+	for _, name := range names {
+		switch strings.ToLower(strings.TrimSpace(name)) {
+		case PropagatorTraceContext:
+			propagators = append(propagators, propagation.TraceContext{})
+		case PropagatorBaggage:
+			propagators = append(propagators, propagation.Baggage{})
+		case PropagatorB3:
+			propagators = append(propagators, b3.New(b3.WithInjectEncoding(b3.B3SingleHeader)))
+		case PropagatorB3Multi:
+			propagators = append(propagators, b3.New(b3.WithInjectEncoding(b3.B3MultipleHeader)))
+		case PropagatorJaeger:
+			propagators = append(propagators, jaeger.Jaeger{})
+		default:
+			return nil, utils.NewAppError(utils.ConfigError, "unknown propagator", nil).AddContext("propagator", name)
+		}
+	}
+
+	if len(propagators) == 0 {
+		return nil, utils.NewAppError(utils.ConfigError, "at least one propagator is required", nil)
+	}
+
+	return propagation.NewCompositeTextMapPropagator(propagators...), nil
+}
+
+// ExtractTraceContext parses the incoming trace context and baggage with the given propagator
+func ExtractTraceContext(propagator propagation.TextMapPropagator, headers http.Header) TraceContextResponse {
+	ctx := propagator.Extract(context.Background(), propagation.HeaderCarrier(headers))
+	spanContext := trace.SpanContextFromContext(ctx)
+
+	response := TraceContextResponse{
+		Fields: propagator.Fields(),
+		Valid:  spanContext.IsValid(),
+	}
+
+	if spanContext.IsValid() {
+		response.TraceID = spanContext.TraceID().String()
+		response.SpanID = spanContext.SpanID().String()
+		response.Sampled = spanContext.IsSampled()
+		response.TraceState = spanContext.TraceState().String()
+	}
+
+	members := baggage.FromContext(ctx).Members()
+	if len(members) > 0 {
+		response.Baggage = make(map[string]string, len(members))
+		for _, member := range members {
+			response.Baggage[member.Key()] = member.Value()
+		}
+	}
+
+	return response
+}
@@ -0,0 +1,102 @@
+package common
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewPropagator(t *testing.T) {
+	t.Run("Unknown propagator", func(t *testing.T) {
+		_, err := NewPropagator([]string{"tracecontext", "xray"})
+		assert.Error(t, err)
+	})
+
+	t.Run("Empty list", func(t *testing.T) {
+		_, err := NewPropagator(nil)
+		assert.Error(t, err)
+	})
+
+	t.Run("All propagators", func(t *testing.T) {
+		propagator, err := NewPropagator(AllPropagators())
+		require.NoError(t, err)
+		assert.Subset(t, propagator.Fields(), []string{"traceparent", "baggage", "b3", "x-b3-traceid", "uber-trace-id"})
+	})
+}
+
+func TestExtractTraceContext(t *testing.T) {
+	tests := []struct {
+		name        string
+		propagators []string
+		headers     map[string]string
+		valid       bool
+	}{
+		{
+			name:        "W3C tracecontext",
+			propagators: []string{PropagatorTraceContext},
+			headers:     map[string]string{"traceparent": "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"},
+			valid:       true,
+		},
+		{
+			name:        "B3 single header",
+			propagators: []string{PropagatorB3},
+			headers:     map[string]string{"b3": "4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-1"},
+			valid:       true,
+		},
+		{
+			name:        "B3 multiple headers",
+			propagators: []string{PropagatorB3Multi},
+			headers: map[string]string{
+				"X-B3-TraceId": "4bf92f3577b34da6a3ce929d0e0e4736",
+				"X-B3-SpanId":  "00f067aa0ba902b7",
+				"X-B3-Sampled": "1",
+			},
+			valid: true,
+		},
+		{
+			name:        "Jaeger",
+			propagators: []string{PropagatorJaeger},
+			headers:     map[string]string{"uber-trace-id": "4bf92f3577b34da6a3ce929d0e0e4736:00f067aa0ba902b7:0:1"},
+			valid:       true,
+		},
+		{
+			name:        "Mismatched format",
+			propagators: []string{PropagatorTraceContext},
+			headers:     map[string]string{"b3": "4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-1"},
+			valid:       false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			propagator, err := NewPropagator(tt.propagators)
+			require.NoError(t, err)
+
+			headers := http.Header{}
+			for key, value := range tt.headers {
+				headers.Set(key, value)
+			}
+
+			response := ExtractTraceContext(propagator, headers)
+			assert.Equal(t, tt.valid, response.Valid)
+			if tt.valid {
+				assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", response.TraceID)
+				assert.Equal(t, "00f067aa0ba902b7", response.SpanID)
+				assert.True(t, response.Sampled)
+			}
+		})
+	}
+
+	t.Run("Baggage", func(t *testing.T) {
+		propagator, err := NewPropagator([]string{PropagatorBaggage})
+		require.NoError(t, err)
+
+		headers := http.Header{}
+		headers.Set("baggage", "tenant=acme,region=eu")
+
+		response := ExtractTraceContext(propagator, headers)
+		assert.Equal(t, map[string]string{"tenant": "acme", "region": "eu"}, response.Baggage)
+	})
+}
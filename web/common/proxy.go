@@ -0,0 +1,207 @@
+package common
+
+import (
+	"net"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+
+	"github.com/wasilak/go-hello-world/utils"
+)
+
+// ProxyRoute forwards requests below Prefix to Target
+type ProxyRoute struct {
+	Prefix string
+	Target *url.URL
+}
+
+// ProxyConfig configures the reverse proxy mode
+type ProxyConfig struct {
+	Routes       []ProxyRoute
+	StripPrefix  bool
+	SetHeaders   map[string]string
+	StripHeaders []string
+	Timeout      time.Duration
+	Retries      int
+}
+
+// Enabled reports whether any proxy routes are configured
+func (c ProxyConfig) Enabled() bool {
+	return len(c.Routes) > 0
+}
+
+// ParseProxyRoutes parses "prefix=url" specs, e.g. "/api=http://backend:8080"
+func ParseProxyRoutes(specs []string) ([]ProxyRoute, error) {
+	routes := make([]ProxyRoute, 0, len(specs))
+
+	for _, spec := range specs {
+		prefix, target, found := strings.Cut(spec, "=")
+		prefix = "/" + strings.Trim(strings.TrimSpace(prefix), "/")
+		if !found || prefix == "/" {
+			return nil, utils.NewAppError(utils.ConfigError, "proxy upstream must be prefix=url with a non-root prefix", nil).AddContext("upstream", spec)
+		}
+
+		targetURL, err := url.Parse(strings.TrimSpace(target))
+		if err != nil || (targetURL.Scheme != "http" && targetURL.Scheme != "https") || targetURL.Host == "" {
+			return nil, utils.NewAppError(utils.ConfigError, "invalid proxy upstream URL", err).AddContext("upstream", spec)
+		}
+
+		routes = append(routes, ProxyRoute{Prefix: prefix, Target: targetURL})
+	}
+
+	// longest prefix first, so the most specific route wins
+	sort.SliceStable(routes, func(i, j int) bool {
+		return len(routes[i].Prefix) > len(routes[j].Prefix)
+	})
+
+	return routes, nil
+}
+
+// ParseHeaderAssignments parses "Name=value" items into a header map
+func ParseHeaderAssignments(items []string) (map[string]string, error) {
+	headers := make(map[string]string, len(items))
+	for _, item := range items {
+		name, value, found := strings.Cut(item, "=")
+		if !found || strings.TrimSpace(name) == "" {
+			return nil, utils.NewAppError(utils.ConfigError, "header must be Name=value", nil).AddContext("header", item)
+		}
+		headers[http.CanonicalHeaderKey(strings.TrimSpace(name))] = strings.TrimSpace(value)
+	}
+	return headers, nil
+}
+
+// match returns the route serving path, if any
+func (c ProxyConfig) match(path string) (ProxyRoute, bool) {
+	for _, route := range c.Routes {
+		if path == route.Prefix || strings.HasPrefix(path, route.Prefix+"/") {
+			return route, true
+		}
+	}
+	return ProxyRoute{}, false
+}
+
+// NewProxyHandler returns the reverse proxy handler mounted by every framework on the
+// configured prefixes. Upstream calls go through an otelhttp transport, so the proxy
+// hop shows up as a client span below the framework's server span.
+func NewProxyHandler(config ProxyConfig, tracing TracingConfig, problems ProblemConfig) http.Handler {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if config.Timeout > 0 {
+		transport.DialContext = (&net.Dialer{Timeout: config.Timeout}).DialContext
+		transport.ResponseHeaderTimeout = config.Timeout
+	}
+
+	opts := []otelhttp.Option{
+		otelhttp.WithPropagators(tracing.GetPropagators()),
+		otelhttp.WithSpanNameFormatter(func(_ string, r *http.Request) string {
+			return "proxy " + r.Method + " " + r.URL.Host
+		}),
+	}
+	if tracing.TracerProvider != nil {
+		opts = append(opts, otelhttp.WithTracerProvider(tracing.TracerProvider))
+	}
+
+	proxy := &httputil.ReverseProxy{
+		Transport: &retryTransport{
+			next:    otelhttp.NewTransport(transport, opts...),
+			retries: config.Retries,
+		},
+		Rewrite: func(pr *httputil.ProxyRequest) {
+			route, _ := config.match(pr.In.URL.Path)
+
+			if config.StripPrefix {
+				pr.Out.URL.Path = "/" + strings.TrimPrefix(strings.TrimPrefix(pr.In.URL.Path, route.Prefix), "/")
+				pr.Out.URL.RawPath = ""
+			}
+
+			pr.SetURL(route.Target)
+			pr.SetXForwarded()
+
+			for _, name := range config.StripHeaders {
+				pr.Out.Header.Del(name)
+			}
+			for name, value := range config.SetHeaders {
+				pr.Out.Header.Set(name, value)
+			}
+		},
+		ErrorHandler: func(w http.ResponseWriter, r *http.Request, err error) {
+			appErr := utils.WrapError(err, utils.RuntimeError, "proxy upstream request failed").WithCode("runtime.proxy_upstream").WithRetryable(true)
+			appErr.AddContext(utils.ContextPath, r.URL.Path)
+			appErr.LogError(r.Context())
+
+			problem := problems.NewProblem(r.Context(), appErr, r.URL.Path)
+			problem.Status = http.StatusBadGateway
+			WriteProblem(w, problem)
+		},
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, ok := config.match(r.URL.Path); !ok {
+			WriteProblem(w, problems.StatusProblem(r.Context(), http.StatusNotFound, "", r.URL.Path))
+			return
+		}
+		proxy.ServeHTTP(w, r)
+	})
+}
+
+// retryTransport retries idempotent upstream requests on connection errors and 502/503/504
+type retryTransport struct {
+	next    http.RoundTripper
+	retries int
+}
+
+func (t *retryTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := t.next.RoundTrip(req)
+
+	for attempt := 1; attempt <= t.retries && retryable(req, resp, err); attempt++ {
+		if resp != nil {
+			resp.Body.Close()
+		}
+
+		select {
+		case <-req.Context().Done():
+			return nil, req.Context().Err()
+		case <-time.After(time.Duration(attempt) * 100 * time.Millisecond):
+		}
+
+		utils.ComponentLogger(utils.ComponentHandlers).DebugContext(req.Context(), "proxy_retry", "attempt", attempt, "url", req.URL.String())
+
+		retry := req.Clone(req.Context())
+		if req.GetBody != nil {
+			if retry.Body, err = req.GetBody(); err != nil {
+				return nil, err
+			}
+		}
+		resp, err = t.next.RoundTrip(retry)
+	}
+
+	return resp, err
+}
+
+// retryable reports whether a failed attempt can safely be repeated
+func retryable(req *http.Request, resp *http.Response, err error) bool {
+	if req.Body != nil && req.Body != http.NoBody && req.GetBody == nil {
+		return false
+	}
+
+	switch req.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodPut, http.MethodDelete:
+	default:
+		return false
+	}
+
+	if err != nil {
+		return true
+	}
+
+	switch resp.StatusCode {
+	case http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+
+	return false
+}
@@ -0,0 +1,142 @@
+package common
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseProxyRoutes(t *testing.T) {
+	t.Run("Longest prefix first", func(t *testing.T) {
+		routes, err := ParseProxyRoutes([]string{"/api=http://a:8080", "/api/v2/=https://b"})
+		require.NoError(t, err)
+		require.Len(t, routes, 2)
+		assert.Equal(t, "/api/v2", routes[0].Prefix)
+		assert.Equal(t, "b", routes[0].Target.Host)
+		assert.Equal(t, "/api", routes[1].Prefix)
+	})
+
+	t.Run("Root prefix", func(t *testing.T) {
+		_, err := ParseProxyRoutes([]string{"/=http://a"})
+		assert.Error(t, err)
+	})
+
+	t.Run("Invalid target", func(t *testing.T) {
+		_, err := ParseProxyRoutes([]string{"/api=ftp://a"})
+		assert.Error(t, err)
+	})
+}
+
+func TestParseHeaderAssignments(t *testing.T) {
+	headers, err := ParseHeaderAssignments([]string{"x-env = test", "Authorization=Bearer abc"})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"X-Env": "test", "Authorization": "Bearer abc"}, headers)
+
+	_, err = ParseHeaderAssignments([]string{"missing"})
+	assert.Error(t, err)
+}
+
+func TestProxyHandler(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Upstream-Path", r.URL.RequestURI())
+		w.Header().Set("X-Upstream-Env", r.Header.Get("X-Env"))
+		w.Header().Set("X-Upstream-Cookie", r.Header.Get("Cookie"))
+		w.Header().Set("X-Upstream-Forwarded", r.Header.Get("X-Forwarded-Host"))
+	}))
+	defer upstream.Close()
+
+	routes, err := ParseProxyRoutes([]string{"/api=" + upstream.URL + "/base"})
+	require.NoError(t, err)
+
+	config := ProxyConfig{
+		Routes:       routes,
+		StripPrefix:  true,
+		SetHeaders:   map[string]string{"X-Env": "test"},
+		StripHeaders: []string{"Cookie"},
+	}
+	handler := NewProxyHandler(config, TracingConfig{}, ProblemConfig{})
+
+	t.Run("Rewrite", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "http://proxy.local/api/users?id=1", nil)
+		req.Header.Set("Cookie", "session=secret")
+		rec := httptest.NewRecorder()
+
+		handler.ServeHTTP(rec, req)
+
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, "/base/users?id=1", rec.Header().Get("X-Upstream-Path"))
+		assert.Equal(t, "test", rec.Header().Get("X-Upstream-Env"))
+		assert.Empty(t, rec.Header().Get("X-Upstream-Cookie"))
+		assert.Equal(t, "proxy.local", rec.Header().Get("X-Upstream-Forwarded"))
+	})
+
+	t.Run("Keep prefix", func(t *testing.T) {
+		keep := config
+		keep.StripPrefix = false
+		rec := httptest.NewRecorder()
+
+		NewProxyHandler(keep, TracingConfig{}, ProblemConfig{}).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/users", nil))
+
+		assert.Equal(t, "/base/api/users", rec.Header().Get("X-Upstream-Path"))
+	})
+
+	t.Run("Unmatched path", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/apix", nil))
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+	})
+}
+
+func TestProxyHandlerRetries(t *testing.T) {
+	var calls atomic.Int32
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		_, _ = io.WriteString(w, "ok")
+	}))
+	defer upstream.Close()
+
+	routes, err := ParseProxyRoutes([]string{"/api=" + upstream.URL})
+	require.NoError(t, err)
+
+	t.Run("Idempotent request", func(t *testing.T) {
+		calls.Store(0)
+		rec := httptest.NewRecorder()
+
+		NewProxyHandler(ProxyConfig{Routes: routes, Retries: 2}, TracingConfig{}, ProblemConfig{}).
+			ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api", nil))
+
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, "ok", rec.Body.String())
+		assert.Equal(t, int32(2), calls.Load())
+	})
+
+	t.Run("Non idempotent request", func(t *testing.T) {
+		calls.Store(0)
+		rec := httptest.NewRecorder()
+
+		NewProxyHandler(ProxyConfig{Routes: routes, Retries: 2}, TracingConfig{}, ProblemConfig{}).
+			ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/api", nil))
+
+		assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+		assert.Equal(t, int32(1), calls.Load())
+	})
+
+	t.Run("Unreachable upstream", func(t *testing.T) {
+		down, err := ParseProxyRoutes([]string{"/api=http://127.0.0.1:1"})
+		require.NoError(t, err)
+		rec := httptest.NewRecorder()
+
+		NewProxyHandler(ProxyConfig{Routes: down}, TracingConfig{}, ProblemConfig{}).
+			ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api", nil))
+
+		assert.Equal(t, http.StatusBadGateway, rec.Code)
+	})
+}
@@ -0,0 +1,459 @@
+package common
+
+import (
+	"context"
+	"math"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+
+	"github.com/wasilak/go-hello-world/utils"
+)
+
+const (
+	// RateLimitKeyIP limits every client IP separately
+	RateLimitKeyIP = "ip"
+
+	// RateLimitKeyRoute limits every rule separately, shared by all clients: each route
+	// prefix has one bucket and all paths without a route limit share the default one
+	RateLimitKeyRoute = "route"
+
+	// RateLimitKeyHeaderPrefix limits every value of a header separately, e.g.
+	// header:X-API-Key, requests without the header are keyed by client IP
+	RateLimitKeyHeaderPrefix = "header:"
+
+	// RateLimitScopeGlobal labels the limit shared by all requests
+	RateLimitScopeGlobal = "global"
+
+	// RateLimitScopeClient labels the per-client limits
+	RateLimitScopeClient = "client"
+
+	// defaultRateLimitRule labels the per-client limit of paths without a route limit
+	defaultRateLimitRule = "default"
+
+	// rateLimitPruneInterval is how often idle, full buckets are forgotten
+	rateLimitPruneInterval = time.Minute
+)
+
+// RateLimitedCounterVec counts the requests rejected by the rate limiter by scope and rule,
+// the rule is the configured path prefix, "default" or "global"
+var RateLimitedCounterVec = promauto.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: "http_rate_limited_requests_total",
+		Help: "Total number of requests rejected by the rate limiter by scope and rule",
+	},
+	[]string{"scope", "rule"},
+)
+
+// RateLimit is a token bucket refilled with Rate tokens per second up to Burst tokens
+type RateLimit struct {
+	Rate  float64 `json:"rate"`
+	Burst int     `json:"burst"`
+}
+
+// RouteRateLimit is the per-client limit of the paths below Path
+type RouteRateLimit struct {
+	Path string `json:"path"`
+	RateLimit
+}
+
+// RateLimitConfig configures the rate limiter, which is disabled without limits
+type RateLimitConfig struct {
+	// Key selects what the per-client limits apply to: ip, route or header:<name>
+	Key string `json:"key"`
+
+	// Global limits all requests together
+	Global *RateLimit `json:"global,omitempty"`
+
+	// Default is the per-client limit of paths without a route limit
+	Default *RateLimit `json:"default,omitempty"`
+
+	// Routes are per-client limits of path prefixes, the longest prefix wins
+	Routes []RouteRateLimit `json:"routes,omitempty"`
+
+	// ExcludePaths are never limited, an entry also excludes the paths below it
+	ExcludePaths []string `json:"exclude_paths,omitempty"`
+}
+
+// Enabled reports whether any limit is configured
+func (c RateLimitConfig) Enabled() bool {
+	return c.Global != nil || c.Default != nil || len(c.Routes) > 0
+}
+
+// Validate checks the rate limit configuration
+func (c RateLimitConfig) Validate() *utils.AppError {
+	if c.Key != "" && c.Key != RateLimitKeyIP && c.Key != RateLimitKeyRoute &&
+		(!strings.HasPrefix(c.Key, RateLimitKeyHeaderPrefix) || strings.TrimPrefix(c.Key, RateLimitKeyHeaderPrefix) == "") {
+		return utils.NewAppError(utils.ValidationError, "rate limit key must be ip, route or header:<name>", nil).
+			WithCode("validation.rate_limit_key").
+			AddContext("key", c.Key)
+	}
+
+	limits := make([]RateLimit, 0, len(c.Routes)+2)
+	if c.Global != nil {
+		limits = append(limits, *c.Global)
+	}
+	if c.Default != nil {
+		limits = append(limits, *c.Default)
+	}
+	for _, route := range c.Routes {
+		if !strings.HasPrefix(route.Path, "/") {
+			return utils.NewAppError(utils.ValidationError, "rate limit paths must start with /", nil).
+				WithCode("validation.rate_limit_path").
+				AddContext(utils.ContextPath, route.Path)
+		}
+		limits = append(limits, route.RateLimit)
+	}
+	for _, limit := range limits {
+		if limit.Rate <= 0 || limit.Burst < 1 {
+			return utils.NewAppError(utils.ValidationError, "rate limits need a positive rate and a burst of at least 1", nil).
+				WithCode("validation.rate_limit")
+		}
+	}
+	return nil
+}
+
+// ParseRateLimit parses a "rate:burst" limit, the burst defaults to the rate rounded up
+func ParseRateLimit(value string) (RateLimit, *utils.AppError) {
+	rate, burst, hasBurst := strings.Cut(strings.TrimSpace(value), ":")
+
+	limit := RateLimit{}
+	parsedRate, err := strconv.ParseFloat(rate, 64)
+	if err == nil {
+		limit.Rate = parsedRate
+		limit.Burst = max(1, int(math.Ceil(parsedRate)))
+		if hasBurst {
+			limit.Burst, err = strconv.Atoi(burst)
+		}
+	}
+	if err != nil {
+		return RateLimit{}, utils.WrapError(err, utils.ValidationError, "rate limits must be rate or rate:burst").
+			WithCode("validation.rate_limit").
+			AddContext("limit", value)
+	}
+	return limit, nil
+}
+
+// ParseRateLimitConfig builds and validates the configuration from the command line
+// values, empty global and default limits are not applied
+func ParseRateLimitConfig(key, global, perClient string, routes, excludePaths []string) (RateLimitConfig, *utils.AppError) {
+	config := RateLimitConfig{Key: key, ExcludePaths: excludePaths}
+
+	for _, limit := range []struct {
+		value  string
+		target **RateLimit
+	}{{global, &config.Global}, {perClient, &config.Default}} {
+		if limit.value == "" {
+			continue
+		}
+		parsed, appErr := ParseRateLimit(limit.value)
+		if appErr != nil {
+			return RateLimitConfig{}, appErr
+		}
+		*limit.target = &parsed
+	}
+
+	parsedRoutes, appErr := parseRouteRateLimits(routes)
+	if appErr != nil {
+		return RateLimitConfig{}, appErr
+	}
+	config.Routes = parsedRoutes
+
+	return config, config.Validate()
+}
+
+// parseRouteRateLimits parses "path=rate:burst" route limits
+func parseRouteRateLimits(values []string) ([]RouteRateLimit, *utils.AppError) {
+	routes := make([]RouteRateLimit, 0, len(values))
+	for _, value := range values {
+		path, rawLimit, found := strings.Cut(value, "=")
+		if !found {
+			return nil, utils.NewAppError(utils.ValidationError, "route rate limits must be path=rate:burst", nil).
+				WithCode("validation.rate_limit").
+				AddContext("limit", value)
+		}
+		limit, appErr := ParseRateLimit(rawLimit)
+		if appErr != nil {
+			return nil, appErr
+		}
+		routes = append(routes, RouteRateLimit{Path: strings.TrimSpace(path), RateLimit: limit})
+	}
+	return routes, nil
+}
+
+// RateLimitRequest describes a request to the rate limiter, so frameworks without
+// net/http requests can use it
+type RateLimitRequest struct {
+	Path     string
+	ClientIP string
+	Header   func(name string) string
+}
+
+// RateLimitDecision is the outcome of a request, with the state of the most restrictive
+// bucket, or of the bucket which rejected the request
+type RateLimitDecision struct {
+	Allowed    bool
+	Scope      string
+	Rule       string
+	Limit      int
+	Remaining  int
+	Reset      time.Duration
+	RetryAfter time.Duration
+}
+
+// SetHeaders sets the RateLimit-* response headers, and Retry-After for rejected
+// requests, through set so every framework can use it
+func (d RateLimitDecision) SetHeaders(set func(name, value string)) {
+	if d.Limit == 0 {
+		return
+	}
+	set("RateLimit-Limit", strconv.Itoa(d.Limit))
+	set("RateLimit-Remaining", strconv.Itoa(d.Remaining))
+	set("RateLimit-Reset", strconv.Itoa(ceilSeconds(d.Reset)))
+	if !d.Allowed {
+		set("Retry-After", strconv.Itoa(max(1, ceilSeconds(d.RetryAfter))))
+	}
+}
+
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
+
+// tokenBucket holds the tokens of a limit, refilled lazily when it is used
+type tokenBucket struct {
+	limit   RateLimit
+	tokens  float64
+	updated time.Time
+}
+
+func newTokenBucket(limit RateLimit, now time.Time) *tokenBucket {
+	return &tokenBucket{limit: limit, tokens: float64(limit.Burst), updated: now}
+}
+
+func (b *tokenBucket) refill(now time.Time) {
+	b.tokens = min(float64(b.limit.Burst), b.tokens+now.Sub(b.updated).Seconds()*b.limit.Rate)
+	b.updated = now
+}
+
+// decision describes the bucket state, allowed reports whether a token was available
+func (b *tokenBucket) decision(scope, rule string, allowed bool) RateLimitDecision {
+	decision := RateLimitDecision{
+		Allowed:   allowed,
+		Scope:     scope,
+		Rule:      rule,
+		Limit:     b.limit.Burst,
+		Remaining: max(0, int(b.tokens)),
+		Reset:     time.Duration((float64(b.limit.Burst) - b.tokens) / b.limit.Rate * float64(time.Second)),
+	}
+	if !allowed {
+		decision.RetryAfter = time.Duration((1 - b.tokens) / b.limit.Rate * float64(time.Second))
+	}
+	return decision
+}
+
+// RateLimiter applies the global and per-client token bucket limits. It outlives framework
+// switches, its configuration can be replaced at runtime, and a nil limiter allows everything.
+type RateLimiter struct {
+	mu        sync.Mutex
+	config    RateLimitConfig
+	global    *tokenBucket
+	buckets   map[string]*tokenBucket
+	lastPrune time.Time
+	now       func() time.Time
+}
+
+// NewRateLimiter creates the rate limiter, the configuration must be valid
+func NewRateLimiter(config RateLimitConfig) *RateLimiter {
+	l := &RateLimiter{now: time.Now}
+	l.reset(config)
+	return l
+}
+
+// reset replaces the configuration and forgets the buckets, the caller holds the lock
+// unless the limiter is not shared yet
+func (l *RateLimiter) reset(config RateLimitConfig) {
+	now := l.now()
+
+	l.config = config
+	l.global = nil
+	if config.Global != nil {
+		l.global = newTokenBucket(*config.Global, now)
+	}
+	l.buckets = make(map[string]*tokenBucket)
+	l.lastPrune = now
+}
+
+// Config returns the active configuration
+func (l *RateLimiter) Config() RateLimitConfig {
+	if l == nil {
+		return RateLimitConfig{}
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.config
+}
+
+// SetConfig validates and applies a new configuration, starting with full buckets
+func (l *RateLimiter) SetConfig(config RateLimitConfig) *utils.AppError {
+	if appErr := config.Validate(); appErr != nil {
+		return appErr
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.reset(config)
+	return nil
+}
+
+// Excluded reports whether requests for path are never limited
+func (l *RateLimiter) Excluded(path string) bool {
+	if l == nil {
+		return true
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return !l.config.Enabled() || slices.ContainsFunc(l.config.ExcludePaths, func(excluded string) bool {
+		return underPath(path, excluded)
+	})
+}
+
+// Allow takes a token from the global bucket and the bucket of the client. A request
+// is only charged when both buckets have a token, rejected requests are counted.
+func (l *RateLimiter) Allow(req RateLimitRequest) RateLimitDecision {
+	if l.Excluded(req.Path) {
+		return RateLimitDecision{Allowed: true}
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	l.prune(now)
+
+	var client *tokenBucket
+	rule, limit := l.config.rule(req.Path)
+	if limit != nil {
+		key := rule + "|" + l.config.clientKey(req)
+		client = l.buckets[key]
+		if client == nil {
+			client = newTokenBucket(*limit, now)
+			l.buckets[strings.Clone(key)] = client
+		}
+		client.refill(now)
+	}
+	if l.global == nil && client == nil {
+		return RateLimitDecision{Allowed: true}
+	}
+	if l.global != nil {
+		l.global.refill(now)
+	}
+
+	if l.global != nil && l.global.tokens < 1 {
+		RateLimitedCounterVec.WithLabelValues(RateLimitScopeGlobal, RateLimitScopeGlobal).Inc()
+		return l.global.decision(RateLimitScopeGlobal, RateLimitScopeGlobal, false)
+	}
+	if client != nil && client.tokens < 1 {
+		RateLimitedCounterVec.WithLabelValues(RateLimitScopeClient, rule).Inc()
+		return client.decision(RateLimitScopeClient, rule, false)
+	}
+
+	if l.global != nil {
+		l.global.tokens--
+	}
+	if client != nil {
+		client.tokens--
+	}
+
+	switch {
+	case client == nil:
+		return l.global.decision(RateLimitScopeGlobal, RateLimitScopeGlobal, true)
+	case l.global != nil && l.global.tokens < client.tokens:
+		return l.global.decision(RateLimitScopeGlobal, RateLimitScopeGlobal, true)
+	default:
+		return client.decision(RateLimitScopeClient, rule, true)
+	}
+}
+
+// prune forgets the buckets which refilled completely, so idle clients don't accumulate
+func (l *RateLimiter) prune(now time.Time) {
+	if now.Sub(l.lastPrune) < rateLimitPruneInterval {
+		return
+	}
+	l.lastPrune = now
+
+	for key, bucket := range l.buckets {
+		bucket.refill(now)
+		if bucket.tokens >= float64(bucket.limit.Burst) {
+			delete(l.buckets, key)
+		}
+	}
+}
+
+// rule returns the per-client limit of path and its label, nil when clients are not limited
+func (c RateLimitConfig) rule(path string) (string, *RateLimit) {
+	var match *RouteRateLimit
+	for i, route := range c.Routes {
+		if underPath(path, route.Path) && (match == nil || len(route.Path) > len(match.Path)) {
+			match = &c.Routes[i]
+		}
+	}
+	if match != nil {
+		return match.Path, &match.RateLimit
+	}
+	return defaultRateLimitRule, c.Default
+}
+
+// clientKey identifies the client of a request according to the configured key. Buckets
+// are already per rule, so routes share a single key and arbitrary paths, e.g. unknown
+// routes, can't create buckets.
+func (c RateLimitConfig) clientKey(req RateLimitRequest) string {
+	switch {
+	case c.Key == RateLimitKeyRoute:
+		return ""
+	case strings.HasPrefix(c.Key, RateLimitKeyHeaderPrefix) && req.Header != nil:
+		if value := req.Header(strings.TrimPrefix(c.Key, RateLimitKeyHeaderPrefix)); value != "" {
+			return value
+		}
+	}
+	return req.ClientIP
+}
+
+// underPath reports whether path is prefix or below it
+func underPath(path, prefix string) bool {
+	return path == prefix || strings.HasPrefix(path, strings.TrimSuffix(prefix, "/")+"/")
+}
+
+// RateLimitProblem returns the problem rendered for a rejected request
+func (c ProblemConfig) RateLimitProblem(ctx context.Context, decision RateLimitDecision, path string) Problem {
+	appErr := utils.NewAppError(utils.RateLimitError, "rate limit exceeded", nil).
+		WithCode("rate_limit.exceeded").
+		WithRetryable(true).
+		AddContext(utils.ContextScope, decision.Scope).
+		AddContext(utils.ContextRule, decision.Rule)
+	return c.NewProblem(ctx, appErr, path)
+}
+
+// Middleware returns net/http middleware applying the limits, rejected requests get a 429
+// problem response
+func (l *RateLimiter) Middleware(problems ProblemConfig) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			decision := l.Allow(RateLimitRequest{Path: r.URL.Path, ClientIP: ClientIP(r.RemoteAddr), Header: r.Header.Get})
+			decision.SetHeaders(w.Header().Set)
+
+			if !decision.Allowed {
+				WriteProblem(w, problems.RateLimitProblem(r.Context(), decision, r.URL.Path))
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
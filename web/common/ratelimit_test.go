@@ -0,0 +1,160 @@
+package common
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestRateLimiter returns a limiter with a clock advanced by the returned function
+func newTestRateLimiter(t *testing.T, config RateLimitConfig) (*RateLimiter, func(time.Duration)) {
+	t.Helper()
+	require.Nil(t, config.Validate())
+
+	now := time.Unix(1_700_000_000, 0)
+	limiter := &RateLimiter{now: func() time.Time { return now }}
+	limiter.reset(config)
+	return limiter, func(d time.Duration) { now = now.Add(d) }
+}
+
+func TestParseRateLimitConfig(t *testing.T) {
+	config, appErr := ParseRateLimitConfig("ip", "100:200", "2.5", []string{"/chain=1:2"}, []string{"/health"})
+	require.Nil(t, appErr)
+	assert.Equal(t, &RateLimit{Rate: 100, Burst: 200}, config.Global)
+	assert.Equal(t, &RateLimit{Rate: 2.5, Burst: 3}, config.Default)
+	assert.Equal(t, []RouteRateLimit{{Path: "/chain", RateLimit: RateLimit{Rate: 1, Burst: 2}}}, config.Routes)
+
+	config, appErr = ParseRateLimitConfig("ip", "", "", nil, nil)
+	require.Nil(t, appErr)
+	assert.False(t, config.Enabled())
+
+	for _, tc := range []struct {
+		name, key, limit string
+		routes           []string
+	}{
+		{"Unknown key", "cookie", "1", nil},
+		{"Empty header", "header:", "1", nil},
+		{"Invalid limit", "ip", "fast", nil},
+		{"Zero rate", "ip", "0:1", nil},
+		{"Route without limit", "ip", "", []string{"/chain"}},
+		{"Relative route", "ip", "", []string{"chain=1"}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			_, appErr := ParseRateLimitConfig(tc.key, "", tc.limit, tc.routes, nil)
+			assert.NotNil(t, appErr)
+		})
+	}
+}
+
+func TestRateLimiterClientBuckets(t *testing.T) {
+	limiter, advance := newTestRateLimiter(t, RateLimitConfig{
+		Key:          RateLimitKeyIP,
+		Default:      &RateLimit{Rate: 1, Burst: 2},
+		Routes:       []RouteRateLimit{{Path: "/chain", RateLimit: RateLimit{Rate: 10, Burst: 1}}},
+		ExcludePaths: []string{"/health"},
+	})
+	alice := RateLimitRequest{Path: "/", ClientIP: "10.0.0.1"}
+	bob := RateLimitRequest{Path: "/", ClientIP: "10.0.0.2"}
+
+	first := limiter.Allow(alice)
+	assert.True(t, first.Allowed)
+	assert.Equal(t, 2, first.Limit)
+	assert.Equal(t, 1, first.Remaining)
+	assert.True(t, limiter.Allow(alice).Allowed)
+
+	denied := limiter.Allow(alice)
+	assert.False(t, denied.Allowed)
+	assert.Equal(t, RateLimitScopeClient, denied.Scope)
+	assert.Equal(t, "default", denied.Rule)
+	assert.Equal(t, time.Second, denied.RetryAfter)
+
+	assert.True(t, limiter.Allow(bob).Allowed, "Expected clients to be limited separately")
+	assert.True(t, limiter.Allow(RateLimitRequest{Path: "/chain", ClientIP: "10.0.0.1"}).Allowed, "Expected the route limit to have its own bucket")
+	assert.True(t, limiter.Allow(RateLimitRequest{Path: "/health", ClientIP: "10.0.0.1"}).Allowed)
+
+	advance(time.Second)
+	assert.True(t, limiter.Allow(alice).Allowed, "Expected a token after refilling")
+	assert.False(t, limiter.Allow(alice).Allowed)
+}
+
+func TestRateLimiterGlobalAndKeys(t *testing.T) {
+	limiter, _ := newTestRateLimiter(t, RateLimitConfig{
+		Key:     RateLimitKeyHeaderPrefix + "X-API-Key",
+		Global:  &RateLimit{Rate: 1, Burst: 3},
+		Default: &RateLimit{Rate: 1, Burst: 2},
+	})
+	header := func(key string) func(string) string {
+		return func(name string) string {
+			if name == "X-API-Key" {
+				return key
+			}
+			return ""
+		}
+	}
+
+	assert.True(t, limiter.Allow(RateLimitRequest{Path: "/", ClientIP: "10.0.0.1", Header: header("a")}).Allowed)
+	assert.True(t, limiter.Allow(RateLimitRequest{Path: "/", ClientIP: "10.0.0.1", Header: header("a")}).Allowed)
+	assert.False(t, limiter.Allow(RateLimitRequest{Path: "/", ClientIP: "10.0.0.1", Header: header("a")}).Allowed)
+
+	decision := limiter.Allow(RateLimitRequest{Path: "/", ClientIP: "10.0.0.1", Header: header("b")})
+	assert.True(t, decision.Allowed, "Expected header values to be limited separately")
+	assert.Equal(t, RateLimitScopeGlobal, decision.Scope, "Expected the most restrictive bucket to be reported")
+
+	decision = limiter.Allow(RateLimitRequest{Path: "/", ClientIP: "10.0.0.2"})
+	assert.False(t, decision.Allowed)
+	assert.Equal(t, RateLimitScopeGlobal, decision.Scope)
+
+	require.Nil(t, limiter.SetConfig(RateLimitConfig{
+		Key:     RateLimitKeyRoute,
+		Default: &RateLimit{Rate: 1, Burst: 1},
+		Routes:  []RouteRateLimit{{Path: "/a", RateLimit: RateLimit{Rate: 1, Burst: 1}}},
+	}))
+	assert.True(t, limiter.Allow(RateLimitRequest{Path: "/a", ClientIP: "10.0.0.1"}).Allowed)
+	assert.False(t, limiter.Allow(RateLimitRequest{Path: "/a/1", ClientIP: "10.0.0.2"}).Allowed, "Expected clients to share route buckets")
+	assert.True(t, limiter.Allow(RateLimitRequest{Path: "/b", ClientIP: "10.0.0.1"}).Allowed)
+	assert.False(t, limiter.Allow(RateLimitRequest{Path: "/random-1", ClientIP: "10.0.0.1"}).Allowed, "Expected paths without a route limit to share a bucket")
+	assert.Len(t, limiter.buckets, 2)
+
+	var nilLimiter *RateLimiter
+	assert.True(t, nilLimiter.Allow(RateLimitRequest{Path: "/"}).Allowed)
+}
+
+func TestRateLimiterPrune(t *testing.T) {
+	limiter, advance := newTestRateLimiter(t, RateLimitConfig{Default: &RateLimit{Rate: 1, Burst: 1}})
+
+	limiter.Allow(RateLimitRequest{Path: "/", ClientIP: "10.0.0.1"})
+	require.Len(t, limiter.buckets, 1)
+
+	advance(2 * rateLimitPruneInterval)
+	limiter.Allow(RateLimitRequest{Path: "/", ClientIP: "10.0.0.2"})
+	assert.Len(t, limiter.buckets, 1, "Expected the refilled bucket to be forgotten")
+}
+
+func TestRateLimiterMiddleware(t *testing.T) {
+	limiter, _ := newTestRateLimiter(t, RateLimitConfig{Default: &RateLimit{Rate: 0.5, Burst: 1}})
+	handler := limiter.Middleware(ProblemConfig{ContextAllowlist: []string{"scope", "rule"}})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "1", rec.Header().Get("RateLimit-Limit"))
+	assert.Equal(t, "0", rec.Header().Get("RateLimit-Remaining"))
+	assert.Equal(t, "2", rec.Header().Get("RateLimit-Reset"))
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	assert.Equal(t, http.StatusTooManyRequests, rec.Code)
+	assert.Equal(t, "2", rec.Header().Get("Retry-After"))
+	assert.Equal(t, ProblemContentType, rec.Header().Get("Content-Type"))
+
+	var problem Problem
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &problem))
+	assert.Equal(t, "rate_limit.exceeded", problem.Code)
+	assert.Equal(t, "Too many requests", problem.Title)
+	assert.Equal(t, map[string]interface{}{"scope": "client", "rule": "default"}, problem.Context)
+}
@@ -0,0 +1,70 @@
+package common
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+)
+
+// ResponseRecorder wraps an http.ResponseWriter and remembers the status code
+// and the number of body bytes written, so middleware can report on the
+// response after the handler has returned.
+type ResponseRecorder struct {
+	http.ResponseWriter
+	Status       int
+	BytesWritten int64
+	wroteHeader  bool
+
+	// Body, when set, captures the written body
+	Body io.Writer
+}
+
+// NewResponseRecorder wraps w, defaulting the status to 200 like net/http does
+func NewResponseRecorder(w http.ResponseWriter) *ResponseRecorder {
+	return &ResponseRecorder{
+		ResponseWriter: w,
+		Status:         http.StatusOK,
+	}
+}
+
+// WriteHeader records the status code and forwards it
+func (r *ResponseRecorder) WriteHeader(statusCode int) {
+	if !r.wroteHeader {
+		r.Status = statusCode
+		r.wroteHeader = true
+	}
+	r.ResponseWriter.WriteHeader(statusCode)
+}
+
+// Write counts the written bytes and forwards them
+func (r *ResponseRecorder) Write(b []byte) (int, error) {
+	r.wroteHeader = true
+	n, err := r.ResponseWriter.Write(b)
+	r.BytesWritten += int64(n)
+	if r.Body != nil {
+		_, _ = r.Body.Write(b[:n])
+	}
+	return n, err
+}
+
+// Flush implements http.Flusher when the wrapped writer supports it
+func (r *ResponseRecorder) Flush() {
+	if f, ok := r.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Hijack implements http.Hijacker, needed e.g. by the statsviz websocket
+func (r *ResponseRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	if h, ok := r.ResponseWriter.(http.Hijacker); ok {
+		return h.Hijack()
+	}
+	return nil, nil, errors.New("underlying ResponseWriter does not implement http.Hijacker")
+}
+
+// Unwrap exposes the wrapped writer to http.ResponseController
+func (r *ResponseRecorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
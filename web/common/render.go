@@ -0,0 +1,347 @@
+package common
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"net/http"
+	"strings"
+
+	"github.com/munnerz/goautoneg"
+	"github.com/wasilak/go-hello-world/utils"
+	"gopkg.in/yaml.v3"
+)
+
+// Response formats of the info routes, negotiated from the Accept header or selected with
+// the format query parameter
+const (
+	FormatJSON       = "json"
+	FormatPrettyJSON = "pretty"
+	FormatYAML       = "yaml"
+	FormatText       = "text"
+	FormatHTML       = "html"
+
+	// FormatQueryParam overrides the Accept header
+	FormatQueryParam = "format"
+)
+
+// formatContentTypes maps the formats to the content type they are served with
+var formatContentTypes = map[string]string{
+	FormatJSON:       "application/json",
+	FormatPrettyJSON: "application/json",
+	FormatYAML:       "application/yaml",
+	FormatText:       "text/plain; charset=utf-8",
+	FormatHTML:       "text/html; charset=utf-8",
+}
+
+// negotiatedMediaTypes are the media types accepted in the Accept header, the first one
+// wins for wildcards
+var negotiatedMediaTypes = []string{
+	"application/json",
+	"application/yaml",
+	"application/x-yaml",
+	"text/yaml",
+	"text/plain",
+	"text/html",
+}
+
+// mediaTypeFormats maps the negotiated media types to their format
+var mediaTypeFormats = map[string]string{
+	"application/json":   FormatJSON,
+	"application/yaml":   FormatYAML,
+	"application/x-yaml": FormatYAML,
+	"text/yaml":          FormatYAML,
+	"text/plain":         FormatText,
+	"text/html":          FormatHTML,
+}
+
+// ResponseFormats returns the formats accepted by the format query parameter
+func ResponseFormats() []string {
+	return []string{FormatJSON, FormatPrettyJSON, FormatYAML, FormatText, FormatHTML}
+}
+
+// Titled responses provide the heading of their HTML page
+type Titled interface {
+	Title() string
+}
+
+// Title returns the hostname and framework serving the request
+func (r APIResponse) Title() string {
+	return fmt.Sprintf("%s on %s", r.Host, r.Framework)
+}
+
+// Title returns the health status
+func (r HealthResponse) Title() string {
+	return "Health: " + r.Status
+}
+
+// Title returns the application version
+func (r VersionResponse) Title() string {
+	return fmt.Sprintf("%s %s", utils.GetAppName(), r.Version)
+}
+
+// NegotiateFormat returns the response format of the request. The format query parameter
+// takes precedence over the Accept header, and JSON is served when nothing else is
+// acceptable.
+func NegotiateFormat(r *http.Request) (string, *utils.AppError) {
+	if format := r.URL.Query().Get(FormatQueryParam); format != "" {
+		format = strings.ToLower(format)
+		if _, ok := formatContentTypes[format]; !ok {
+			return "", utils.NewAppError(utils.ValidationError, fmt.Sprintf("unsupported response format, expected one of %s", strings.Join(ResponseFormats(), ", ")), nil).
+				WithCode("validation.response_format").
+				AddContext(utils.ContextFormat, format)
+		}
+		return format, nil
+	}
+
+	accept := r.Header.Get("Accept")
+	if accept == "" {
+		return FormatJSON, nil
+	}
+	if format, ok := mediaTypeFormats[goautoneg.Negotiate(accept, negotiatedMediaTypes)]; ok {
+		return format, nil
+	}
+	return FormatJSON, nil
+}
+
+// RenderResponse renders data in the format negotiated for the request and returns the
+// body with its content type. Unsupported format parameters are validation errors.
+func RenderResponse(r *http.Request, data any) ([]byte, string, error) {
+	format, appErr := NegotiateFormat(r)
+	if appErr != nil {
+		return nil, "", appErr
+	}
+
+	body, err := Render(format, data)
+	if err != nil {
+		return nil, "", utils.WrapError(err, utils.RuntimeError, "failed to render response").
+			WithCode("runtime.render").
+			AddContext(utils.ContextFormat, format)
+	}
+	return body, formatContentTypes[format], nil
+}
+
+// WriteResponse renders data in the negotiated format, failures are written as problems
+func WriteResponse(w http.ResponseWriter, r *http.Request, problems ProblemConfig, status int, data any) {
+	body, contentType, err := RenderResponse(r, data)
+	if err != nil {
+		if appErr, ok := utils.AsAppError(err); ok {
+			appErr.AddContext(utils.ContextPath, r.URL.Path)
+			appErr.LogError(r.Context())
+		}
+		problems.Write(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Add("Vary", "Accept")
+	w.WriteHeader(status)
+	_, _ = w.Write(body)
+}
+
+// Render encodes data in format. YAML, text and HTML are rendered from the JSON encoding,
+// so every format shows the same fields in the same order.
+func Render(format string, data any) ([]byte, error) {
+	switch format {
+	case FormatJSON:
+		body, err := json.Marshal(data)
+		return append(body, '\n'), err
+	case FormatPrettyJSON:
+		body, err := json.MarshalIndent(data, "", "  ")
+		return append(body, '\n'), err
+	}
+
+	node, err := toNode(data)
+	if err != nil {
+		return nil, err
+	}
+
+	var out bytes.Buffer
+	switch format {
+	case FormatYAML:
+		encoder := yaml.NewEncoder(&out)
+		encoder.SetIndent(2)
+		if err := encoder.Encode(node); err != nil {
+			return nil, err
+		}
+		err = encoder.Close()
+	case FormatText:
+		for _, field := range flattenNode("", node) {
+			fmt.Fprintf(&out, "%s: %s\n", field.Name, field.Value)
+		}
+	case FormatHTML:
+		err = htmlPage.Execute(&out, newHTMLView(data, node))
+	default:
+		err = fmt.Errorf("unknown response format %q", format)
+	}
+	return out.Bytes(), err
+}
+
+// toNode converts data to a YAML node tree through its JSON encoding, keeping the field
+// order and names of the JSON responses
+func toNode(data any) (*yaml.Node, error) {
+	body, err := json.Marshal(data)
+	if err != nil {
+		return nil, err
+	}
+
+	var document yaml.Node
+	if err := yaml.Unmarshal(body, &document); err != nil {
+		return nil, err
+	}
+	if len(document.Content) == 0 {
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!null", Value: "null"}, nil
+	}
+
+	node := document.Content[0]
+	resetStyle(node)
+	return node, nil
+}
+
+// resetStyle switches the JSON flow style and quoting to the YAML block style
+func resetStyle(node *yaml.Node) {
+	node.Style = 0
+	for _, child := range node.Content {
+		resetStyle(child)
+	}
+}
+
+// renderField is a flattened field, nested names are joined with dots
+type renderField struct {
+	Name  string
+	Value string
+}
+
+// flattenNode returns the scalar fields below node in document order. Lists of scalars,
+// e.g. header values, are joined with commas.
+func flattenNode(prefix string, node *yaml.Node) []renderField {
+	switch node.Kind {
+	case yaml.MappingNode:
+		fields := []renderField{}
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			fields = append(fields, flattenNode(joinFieldName(prefix, node.Content[i].Value), node.Content[i+1])...)
+		}
+		return fields
+
+	case yaml.SequenceNode:
+		values := []string{}
+		for _, item := range node.Content {
+			if item.Kind != yaml.ScalarNode {
+				values = nil
+				break
+			}
+			values = append(values, item.Value)
+		}
+		if values != nil {
+			return []renderField{{Name: prefix, Value: strings.Join(values, ", ")}}
+		}
+
+		fields := []renderField{}
+		for i, item := range node.Content {
+			fields = append(fields, flattenNode(joinFieldName(prefix, fmt.Sprint(i)), item)...)
+		}
+		return fields
+
+	default:
+		return []renderField{{Name: prefix, Value: node.Value}}
+	}
+}
+
+func joinFieldName(prefix, name string) string {
+	if prefix == "" {
+		return name
+	}
+	return prefix + "." + name
+}
+
+// htmlSection is a table of the HTML page, the top level fields followed by one section
+// per nested object
+type htmlSection struct {
+	Name   string
+	Fields []renderField
+}
+
+type htmlView struct {
+	Title    string
+	Sections []htmlSection
+}
+
+func newHTMLView(data any, node *yaml.Node) htmlView {
+	view := htmlView{Title: utils.GetAppName()}
+	if titled, ok := data.(Titled); ok {
+		view.Title = titled.Title()
+	}
+
+	if node.Kind != yaml.MappingNode {
+		view.Sections = []htmlSection{{Fields: flattenNode("value", node)}}
+		return view
+	}
+
+	summary := htmlSection{}
+	nested := []htmlSection{}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		name, value := node.Content[i].Value, node.Content[i+1]
+		if value.Kind == yaml.MappingNode {
+			nested = append(nested, htmlSection{Name: name, Fields: flattenNode("", value)})
+			continue
+		}
+		summary.Fields = append(summary.Fields, flattenNode(name, value)...)
+	}
+
+	if len(summary.Fields) > 0 {
+		view.Sections = append(view.Sections, summary)
+	}
+	view.Sections = append(view.Sections, nested...)
+	return view
+}
+
+// htmlPageStyle is the inline stylesheet of the HTML page
+const htmlPageStyle = `
+body { margin: 0; font-family: system-ui, -apple-system, "Segoe UI", sans-serif; background: #f4f5f7; color: #1f2328; }
+main { max-width: 960px; margin: 0 auto; padding: 2rem 1rem; }
+h1 { margin: 0 0 1.5rem; font-size: 1.6rem; }
+section { background: #fff; border-radius: 8px; box-shadow: 0 1px 3px rgba(0, 0, 0, .12); margin-bottom: 1.5rem; overflow: hidden; }
+h2 { margin: 0; padding: .75rem 1rem; font-size: 1rem; background: #0b5cad; color: #fff; text-transform: capitalize; }
+table { width: 100%; border-collapse: collapse; }
+th, td { padding: .5rem 1rem; text-align: left; vertical-align: top; border-top: 1px solid #eaecef; font-size: .9rem; }
+th { width: 30%; font-weight: 600; color: #57606a; }
+td { font-family: ui-monospace, SFMono-Regular, Menlo, monospace; word-break: break-all; }
+`
+
+// htmlPageStyleSource is the Content-Security-Policy source allowing the inline
+// stylesheet of the HTML page, by its hash
+var htmlPageStyleSource = func() string {
+	sum := sha256.Sum256([]byte(htmlPageStyle))
+	return "'sha256-" + base64.StdEncoding.EncodeToString(sum[:]) + "'"
+}()
+
+var htmlPage = template.Must(template.New("response").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>{{.Title}}</title>
+<style>` + htmlPageStyle + `</style>
+</head>
+<body>
+<main>
+<h1>{{.Title}}</h1>
+{{- range .Sections}}
+<section>
+{{- if .Name}}
+<h2>{{.Name}}</h2>
+{{- end}}
+<table>
+{{- range .Fields}}
+<tr><th>{{.Name}}</th><td>{{.Value}}</td></tr>
+{{- end}}
+</table>
+</section>
+{{- end}}
+</main>
+</body>
+</html>
+`))
@@ -0,0 +1,120 @@
+package common
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/wasilak/go-hello-world/utils"
+)
+
+func TestNegotiateFormat(t *testing.T) {
+	tests := []struct {
+		name   string
+		target string
+		accept string
+		want   string
+	}{
+		{name: "no accept header", target: "/", want: FormatJSON},
+		{name: "wildcard", target: "/", accept: "*/*", want: FormatJSON},
+		{name: "browser", target: "/", accept: "text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8", want: FormatHTML},
+		{name: "yaml", target: "/", accept: "application/x-yaml", want: FormatYAML},
+		{name: "quality", target: "/", accept: "application/json;q=0.5, text/plain", want: FormatText},
+		{name: "unsupported falls back to json", target: "/", accept: "application/xml", want: FormatJSON},
+		{name: "query overrides accept", target: "/?format=Pretty", accept: "text/html", want: FormatPrettyJSON},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, tt.target, nil)
+			if tt.accept != "" {
+				r.Header.Set("Accept", tt.accept)
+			}
+
+			format, appErr := NegotiateFormat(r)
+			require.Nil(t, appErr)
+			assert.Equal(t, tt.want, format)
+		})
+	}
+
+	t.Run("unsupported format parameter", func(t *testing.T) {
+		_, appErr := NegotiateFormat(httptest.NewRequest(http.MethodGet, "/?format=xml", nil))
+		require.NotNil(t, appErr)
+		assert.True(t, utils.IsValidationError(appErr))
+		assert.Equal(t, utils.ErrorCode("validation.response_format"), appErr.Code)
+	})
+}
+
+func TestRender(t *testing.T) {
+	response := APIResponse{
+		Host:      "web-0",
+		Framework: "chi",
+		Version:   "v1.9.0",
+		Request: APIResponseRequest{
+			Method:  http.MethodGet,
+			Headers: http.Header{"Accept": {"text/plain"}, "X-Forwarded-For": {"10.0.0.1", "10.0.0.2"}},
+		},
+	}
+
+	t.Run("yaml keeps the json field order", func(t *testing.T) {
+		body, err := Render(FormatYAML, HealthResponse{Status: "ok"})
+		require.NoError(t, err)
+		assert.Equal(t, "status: ok\n", string(body))
+
+		body, err = Render(FormatYAML, response)
+		require.NoError(t, err)
+		assert.Regexp(t, `(?s)^host: web-0\nframework: chi\nversion: v1\.9\.0\n.*request:\n  host: ""\n`, string(body))
+	})
+
+	t.Run("yaml quotes strings which look like other types", func(t *testing.T) {
+		body, err := Render(FormatYAML, map[string]string{"version": "1.10", "enabled": "true"})
+		require.NoError(t, err)
+		assert.Equal(t, "enabled: \"true\"\nversion: \"1.10\"\n", string(body))
+	})
+
+	t.Run("text", func(t *testing.T) {
+		body, err := Render(FormatText, response)
+		require.NoError(t, err)
+		assert.Contains(t, string(body), "host: web-0\nframework: chi\n")
+		assert.Contains(t, string(body), "request.method: GET\n")
+		assert.Contains(t, string(body), "request.headers.X-Forwarded-For: 10.0.0.1, 10.0.0.2\n")
+	})
+
+	t.Run("pretty json", func(t *testing.T) {
+		body, err := Render(FormatPrettyJSON, HealthResponse{Status: "ok"})
+		require.NoError(t, err)
+		assert.Equal(t, "{\n  \"status\": \"ok\"\n}\n", string(body))
+	})
+
+	t.Run("html", func(t *testing.T) {
+		response := response
+		response.Request.UserAgent = "<script>alert(1)</script>"
+
+		body, err := Render(FormatHTML, response)
+		require.NoError(t, err)
+		assert.Contains(t, string(body), "<h1>web-0 on chi</h1>")
+		assert.Contains(t, string(body), "<h2>request</h2>")
+		assert.Contains(t, string(body), "<tr><th>headers.Accept</th><td>text/plain</td></tr>")
+		assert.NotContains(t, string(body), "<script>")
+	})
+}
+
+func TestWriteResponse(t *testing.T) {
+	r := httptest.NewRequest(http.MethodGet, "/?format=yaml", nil)
+	w := httptest.NewRecorder()
+	WriteResponse(w, r, ProblemConfig{}, http.StatusOK, HealthResponse{Status: "ok"})
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "application/yaml", w.Header().Get("Content-Type"))
+	assert.Equal(t, "Accept", w.Header().Get("Vary"))
+	assert.Equal(t, "status: ok\n", w.Body.String())
+
+	r = httptest.NewRequest(http.MethodGet, "/?format=xml", nil)
+	w = httptest.NewRecorder()
+	WriteResponse(w, r, ProblemConfig{}, http.StatusOK, HealthResponse{Status: "ok"})
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Equal(t, ProblemContentType, w.Header().Get("Content-Type"))
+}
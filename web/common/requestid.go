@@ -0,0 +1,151 @@
+package common
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/binary"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/wasilak/go-hello-world/utils"
+)
+
+const (
+	// DefaultRequestIDHeader is the header request IDs are read from and returned in
+	DefaultRequestIDHeader = "X-Request-ID"
+
+	// RequestIDFormatUUIDv7 generates time ordered UUIDs (RFC 9562)
+	RequestIDFormatUUIDv7 = "uuidv7"
+
+	// RequestIDFormatULID generates ULIDs, time ordered and 26 characters long
+	RequestIDFormatULID = "ulid"
+
+	// RequestIDAttribute is the span attribute of the request ID
+	RequestIDAttribute = "http.request.id"
+
+	// maxRequestIDLength bounds incoming request IDs, longer ones are replaced
+	maxRequestIDLength = 128
+)
+
+// RequestIDFormats lists the supported request ID formats
+func RequestIDFormats() []string {
+	return []string{RequestIDFormatUUIDv7, RequestIDFormatULID}
+}
+
+// RequestIDConfig configures how request IDs are accepted and generated
+type RequestIDConfig struct {
+	// Header carries the request ID, DefaultRequestIDHeader when empty
+	Header string
+
+	// Format of generated request IDs, uuidv7 when empty
+	Format string
+}
+
+// Validate checks the request ID configuration
+func (c RequestIDConfig) Validate() *utils.AppError {
+	if c.Format != "" && !slices.Contains(RequestIDFormats(), c.Format) {
+		return utils.NewAppError(utils.ValidationError, "unknown request ID format", nil).
+			WithCode("validation.request_id_format").
+			AddContext(utils.ContextFormat, c.Format)
+	}
+	if strings.ContainsFunc(c.Header, func(r rune) bool { return !isTokenChar(r) }) {
+		return utils.NewAppError(utils.ValidationError, "invalid request ID header name", nil).
+			WithCode("validation.request_id_header").
+			AddContext("header", c.Header)
+	}
+	return nil
+}
+
+// HeaderName returns the request ID header
+func (c RequestIDConfig) HeaderName() string {
+	if c.Header == "" {
+		return DefaultRequestIDHeader
+	}
+	return c.Header
+}
+
+// Generate returns a new request ID in the configured format
+func (c RequestIDConfig) Generate() string {
+	if c.Format == RequestIDFormatULID {
+		return newULID(time.Now())
+	}
+	id, err := uuid.NewV7()
+	if err != nil {
+		return newULID(time.Now())
+	}
+	return id.String()
+}
+
+// Resolve returns the incoming request ID when it is usable, otherwise a new one.
+// Incoming IDs are limited to printable ASCII without spaces, so they can't break
+// log lines or headers.
+func (c RequestIDConfig) Resolve(incoming string) string {
+	if incoming == "" || len(incoming) > maxRequestIDLength {
+		return c.Generate()
+	}
+	for i := 0; i < len(incoming); i++ {
+		if incoming[i] <= ' ' || incoming[i] > '~' {
+			return c.Generate()
+		}
+	}
+	return incoming
+}
+
+// Start stores the request ID in the context and records it on the active span
+func (c RequestIDConfig) Start(ctx context.Context, id string) context.Context {
+	trace.SpanFromContext(ctx).SetAttributes(attribute.String(RequestIDAttribute, id))
+	return utils.WithRequestID(ctx, id)
+}
+
+// Middleware returns net/http middleware resolving the request ID, storing it in the
+// request context and returning it in the response header. The request header is set
+// too, so proxied requests carry the ID upstream.
+func (c RequestIDConfig) Middleware() func(http.Handler) http.Handler {
+	header := c.HeaderName()
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			id := c.Resolve(r.Header.Get(header))
+			r.Header.Set(header, id)
+			w.Header().Set(header, id)
+			next.ServeHTTP(w, r.WithContext(c.Start(r.Context(), id)))
+		})
+	}
+}
+
+// isTokenChar reports whether r may appear in a header name (RFC 9110 token)
+func isTokenChar(r rune) bool {
+	if r >= '0' && r <= '9' || r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' {
+		return true
+	}
+	return strings.ContainsRune("!#$%&'*+-.^_`|~", r)
+}
+
+// crockfordAlphabet is the Crockford base32 alphabet of ULIDs
+const crockfordAlphabet = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+
+// newULID returns a ULID, a 48 bit millisecond timestamp followed by 80 random bits,
+// encoded as 26 Crockford base32 characters
+func newULID(now time.Time) string {
+	var raw [16]byte
+	binary.BigEndian.PutUint64(raw[:8], uint64(now.UnixMilli())<<16)
+	_, _ = rand.Read(raw[6:])
+
+	// 128 bits are encoded as 26 characters of 5 bits, the first holding the top 3 bits
+	hi := binary.BigEndian.Uint64(raw[:8])
+	lo := binary.BigEndian.Uint64(raw[8:])
+
+	var out [26]byte
+	for i := 25; i >= 0; i-- {
+		out[i] = crockfordAlphabet[lo&0x1f]
+		lo = lo>>5 | hi<<59
+		hi >>= 5
+	}
+	return string(out[:])
+}
@@ -0,0 +1,73 @@
+package common
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+
+	"github.com/wasilak/go-hello-world/utils"
+)
+
+func TestRequestIDConfigValidate(t *testing.T) {
+	assert.Nil(t, RequestIDConfig{}.Validate())
+	assert.Nil(t, RequestIDConfig{Header: "X-Correlation-ID", Format: RequestIDFormatULID}.Validate())
+	assert.NotNil(t, RequestIDConfig{Format: "uuidv4"}.Validate())
+	assert.NotNil(t, RequestIDConfig{Header: "X Request ID"}.Validate())
+}
+
+func TestRequestIDGenerate(t *testing.T) {
+	id, err := uuid.Parse(RequestIDConfig{}.Generate())
+	require.NoError(t, err)
+	assert.Equal(t, uuid.Version(7), id.Version())
+
+	ulid := RequestIDConfig{Format: RequestIDFormatULID}.Generate()
+	assert.Regexp(t, `^[0-7][0-9A-HJKMNP-TV-Z]{25}$`, ulid)
+
+	earlier := newULID(time.UnixMilli(1_000))
+	later := newULID(time.UnixMilli(2_000))
+	assert.Less(t, earlier, later, "Expected ULIDs to sort by time")
+	assert.Equal(t, "0000000", earlier[:7])
+}
+
+func TestRequestIDResolve(t *testing.T) {
+	config := RequestIDConfig{}
+
+	assert.Equal(t, "abc-123", config.Resolve("abc-123"))
+	assert.NotEqual(t, "bad id", config.Resolve("bad id"), "Expected IDs with spaces to be replaced")
+	assert.NotEqual(t, "x\ny", config.Resolve("x\ny"))
+	assert.Len(t, config.Resolve(strings.Repeat("a", 200)), 36)
+	assert.Len(t, config.Resolve(""), 36)
+}
+
+func TestRequestIDMiddleware(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+
+	var seen string
+	handler := RequestIDConfig{}.Middleware()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seen = utils.RequestIDFromContext(r.Context())
+		assert.Equal(t, seen, r.Header.Get(DefaultRequestIDHeader), "Expected the request header to carry the ID upstream")
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	ctx, span := provider.Tracer("test").Start(req.Context(), "server")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req.WithContext(ctx))
+	span.End()
+
+	require.NotEmpty(t, seen)
+	assert.Equal(t, seen, rec.Header().Get(DefaultRequestIDHeader))
+
+	spans := exporter.GetSpans()
+	require.Len(t, spans, 1)
+	assert.Contains(t, spans[0].Attributes, attribute.String(RequestIDAttribute, seen))
+}
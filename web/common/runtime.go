@@ -0,0 +1,213 @@
+package common
+
+import (
+	"context"
+	"encoding/json"
+	"math/rand/v2"
+	"net/http"
+	"slices"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/wasilak/go-hello-world/utils"
+)
+
+// SupportedFrameworks lists the web frameworks the application can run
+func SupportedFrameworks() []string {
+	return []string{"gorilla", "chi", "gin", "echo", "fiber"}
+}
+
+// AdminConfig configures the admin API listener and its authentication, a bearer token,
+// mTLS (client certificates signed by ClientCAFile) or both
+type AdminConfig struct {
+	ListenAddr   string
+	Token        string
+	TLSCertFile  string
+	TLSKeyFile   string
+	ClientCAFile string
+}
+
+// Enabled reports whether the admin API is served
+func (c AdminConfig) Enabled() bool {
+	return c.ListenAddr != ""
+}
+
+// FaultConfig describes the faults injected into matching requests
+type FaultConfig struct {
+	LatencyMS  int      `json:"latency_ms"`
+	ErrorRate  float64  `json:"error_rate"`
+	StatusCode int      `json:"status_code"`
+	Paths      []string `json:"paths,omitempty"`
+}
+
+// Validate checks the fault configuration
+func (c FaultConfig) Validate() *utils.AppError {
+	if c.LatencyMS < 0 || c.LatencyMS > 60000 {
+		return utils.NewAppError(utils.ValidationError, "latency_ms must be between 0 and 60000", nil).WithCode("validation.fault_latency")
+	}
+	if c.ErrorRate < 0 || c.ErrorRate > 1 {
+		return utils.NewAppError(utils.ValidationError, "error_rate must be between 0 and 1", nil).WithCode("validation.fault_error_rate")
+	}
+	if c.ErrorRate > 0 && (c.StatusCode < 400 || c.StatusCode > 599) {
+		return utils.NewAppError(utils.ValidationError, "status_code must be a 4xx or 5xx status", nil).WithCode("validation.fault_status_code")
+	}
+	return nil
+}
+
+// matches reports whether faults apply to path, all paths match when none are configured
+func (c FaultConfig) matches(path string) bool {
+	if len(c.Paths) == 0 {
+		return true
+	}
+	return slices.ContainsFunc(c.Paths, func(prefix string) bool {
+		return strings.HasPrefix(path, prefix)
+	})
+}
+
+// RuntimeState is the process state shared by the frameworks and the admin API. It
+// outlives framework switches, and a nil state reports ready without faults.
+type RuntimeState struct {
+	framework atomic.Value
+	notReady  atomic.Bool
+	faults    atomic.Pointer[FaultConfig]
+	requests  atomic.Uint64
+	started   time.Time
+}
+
+// NewRuntimeState creates the shared runtime state
+func NewRuntimeState() *RuntimeState {
+	return &RuntimeState{started: time.Now()}
+}
+
+// Uptime returns the time since the runtime state was created at startup
+func (s *RuntimeState) Uptime() time.Duration {
+	if s == nil {
+		return 0
+	}
+	return time.Since(s.started)
+}
+
+// CountRequest counts a request received by the web server, for the dashboard request rate
+func (s *RuntimeState) CountRequest() {
+	if s != nil {
+		s.requests.Add(1)
+	}
+}
+
+// Requests returns the number of counted requests, across framework switches
+func (s *RuntimeState) Requests() uint64 {
+	if s == nil {
+		return 0
+	}
+	return s.requests.Load()
+}
+
+// Framework returns the framework currently running
+func (s *RuntimeState) Framework() string {
+	if s == nil {
+		return ""
+	}
+	framework, _ := s.framework.Load().(string)
+	return framework
+}
+
+// SetFramework records the framework currently running
+func (s *RuntimeState) SetFramework(framework string) {
+	if s != nil {
+		s.framework.Store(framework)
+	}
+}
+
+// Ready reports whether the instance should receive traffic
+func (s *RuntimeState) Ready() bool {
+	return s == nil || !s.notReady.Load()
+}
+
+// SetReady flips the readiness reported by /ready
+func (s *RuntimeState) SetReady(ready bool) {
+	if s != nil {
+		s.notReady.Store(!ready)
+	}
+}
+
+// Faults returns the active fault configuration, if any
+func (s *RuntimeState) Faults() *FaultConfig {
+	if s == nil {
+		return nil
+	}
+	return s.faults.Load()
+}
+
+// SetFaults replaces the fault configuration, nil disables fault injection
+func (s *RuntimeState) SetFaults(faults *FaultConfig) {
+	s.faults.Store(faults)
+}
+
+// InjectFault applies the configured latency to a request for path and returns the
+// status code of an injected error, or 0 when the request should proceed
+func (s *RuntimeState) InjectFault(ctx context.Context, path string) int {
+	faults := s.Faults()
+	if faults == nil || !faults.matches(path) {
+		return 0
+	}
+
+	if faults.LatencyMS > 0 {
+		select {
+		case <-ctx.Done():
+			return 0
+		case <-time.After(time.Duration(faults.LatencyMS) * time.Millisecond):
+		}
+	}
+
+	if faults.ErrorRate > 0 && rand.Float64() < faults.ErrorRate {
+		return faults.StatusCode
+	}
+	return 0
+}
+
+// FaultProblem returns the problem rendered for an injected error
+func (c ProblemConfig) FaultProblem(ctx context.Context, status int, path string) Problem {
+	problem := c.StatusProblem(ctx, status, "injected fault", path)
+	problem.Code = "fault.injected"
+	return problem
+}
+
+// FaultMiddleware returns net/http middleware injecting the configured faults
+func FaultMiddleware(runtime *RuntimeState, problems ProblemConfig) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if status := runtime.InjectFault(r.Context(), r.URL.Path); status != 0 {
+				WriteProblem(w, problems.FaultProblem(r.Context(), status, r.URL.Path))
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// CountRequests returns net/http middleware counting every request. It wraps the router
+// instead of joining the middleware chain, so the count doesn't depend on the chain.
+func CountRequests(runtime *RuntimeState) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			runtime.CountRequest()
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// ReadinessHandler serves the readiness flipped through the admin API, 503 when not ready
+func ReadinessHandler(runtime *RuntimeState) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		if !runtime.Ready() {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			_ = json.NewEncoder(w).Encode(HealthResponse{Status: "not_ready"})
+			return
+		}
+
+		_ = json.NewEncoder(w).Encode(HealthResponse{Status: "ready"})
+	})
+}
@@ -0,0 +1,184 @@
+package common
+
+import (
+	"fmt"
+	"maps"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/wasilak/go-hello-world/utils"
+)
+
+const (
+	// SecurityHeadersStrict locks responses down for browsers: HSTS with preload, a
+	// same-origin Content-Security-Policy and cross-origin isolation
+	SecurityHeadersStrict = "strict"
+
+	// SecurityHeadersAPI suits responses consumed by scripts and tools: nothing may be
+	// rendered, embedded or framed, and cross-origin reads need CORS
+	SecurityHeadersAPI = "api"
+
+	// SecurityHeadersOff sets no headers
+	SecurityHeadersOff = "off"
+)
+
+// SecurityHeadersPresets lists the supported presets
+func SecurityHeadersPresets() []string {
+	return []string{SecurityHeadersStrict, SecurityHeadersAPI, SecurityHeadersOff}
+}
+
+// securityHeadersPresets are the headers of every preset
+var securityHeadersPresets = map[string]map[string]string{
+	SecurityHeadersStrict: {
+		"Strict-Transport-Security":    "max-age=63072000; includeSubDomains; preload",
+		"Content-Security-Policy":      "default-src 'self'; base-uri 'self'; form-action 'self'; frame-ancestors 'none'; object-src 'none'; img-src 'self' data:; style-src 'self' " + htmlPageStyleSource,
+		"X-Content-Type-Options":       "nosniff",
+		"Referrer-Policy":              "no-referrer",
+		"Permissions-Policy":           "accelerometer=(), camera=(), geolocation=(), gyroscope=(), magnetometer=(), microphone=(), payment=(), usb=()",
+		"Cross-Origin-Opener-Policy":   "same-origin",
+		"Cross-Origin-Embedder-Policy": "require-corp",
+		"Cross-Origin-Resource-Policy": "same-origin",
+	},
+	SecurityHeadersAPI: {
+		"Strict-Transport-Security":    "max-age=31536000; includeSubDomains",
+		"Content-Security-Policy":      "default-src 'none'; frame-ancestors 'none'",
+		"X-Content-Type-Options":       "nosniff",
+		"Referrer-Policy":              "no-referrer",
+		"Permissions-Policy":           "camera=(), geolocation=(), microphone=()",
+		"Cross-Origin-Opener-Policy":   "same-origin",
+		"Cross-Origin-Resource-Policy": "same-site",
+	},
+	SecurityHeadersOff: {},
+}
+
+// SecurityHeadersConfig configures the security headers of every framework
+type SecurityHeadersConfig struct {
+	// Preset is the base set of headers
+	Preset string `json:"preset"`
+
+	// Set overrides or adds headers of the preset, an empty value removes the header
+	Set map[string]string `json:"set,omitempty"`
+}
+
+// Validate checks the security headers configuration
+func (c SecurityHeadersConfig) Validate() *utils.AppError {
+	if _, ok := securityHeadersPresets[c.Preset]; !ok {
+		return utils.NewAppError(utils.ValidationError, fmt.Sprintf("security headers preset must be %s", strings.Join(SecurityHeadersPresets(), ", ")), nil).
+			WithCode("validation.security_headers_preset").
+			AddContext(utils.ContextPreset, c.Preset)
+	}
+	return nil
+}
+
+// ParseSecurityHeadersConfig builds and validates the configuration from the command line
+// values, overrides are "Name=value"
+func ParseSecurityHeadersConfig(preset string, overrides []string) (SecurityHeadersConfig, *utils.AppError) {
+	set, err := ParseHeaderAssignments(overrides)
+	if err != nil {
+		return SecurityHeadersConfig{}, utils.WrapError(err, utils.ValidationError, "security headers must be Name=value").
+			WithCode("validation.security_header")
+	}
+
+	config := SecurityHeadersConfig{Preset: strings.ToLower(preset), Set: set}
+	return config, config.Validate()
+}
+
+// Header returns the headers of the preset with the overrides applied
+func (c SecurityHeadersConfig) Header() http.Header {
+	header := make(http.Header)
+	for name, value := range securityHeadersPresets[c.Preset] {
+		header.Set(name, value)
+	}
+	for name, value := range c.Set {
+		if value == "" {
+			header.Del(name)
+			continue
+		}
+		header.Set(name, value)
+	}
+	return header
+}
+
+// SecurityHeaders sets the configured headers on every response, handlers can still
+// override them. A nil value is left out of the chain.
+type SecurityHeaders struct {
+	config SecurityHeadersConfig
+	names  []string
+	header http.Header
+}
+
+// NewSecurityHeaders creates the security headers, the configuration must be valid. It
+// returns nil when no header is set.
+func NewSecurityHeaders(config SecurityHeadersConfig) *SecurityHeaders {
+	header := config.Header()
+	if len(header) == 0 {
+		return nil
+	}
+	return &SecurityHeaders{config: config, names: slices.Sorted(maps.Keys(header)), header: header}
+}
+
+// Config returns the security headers configuration
+func (h *SecurityHeaders) Config() SecurityHeadersConfig {
+	if h == nil {
+		return SecurityHeadersConfig{Preset: SecurityHeadersOff}
+	}
+	return h.config
+}
+
+// AllowConnect returns the Content-Security-Policy with origins added to the sources of
+// connect-src, which falls back to default-src when it is not set, so a page can call
+// them. It returns "" without a policy.
+func (h *SecurityHeaders) AllowConnect(origins ...string) string {
+	if h == nil || h.header.Get("Content-Security-Policy") == "" {
+		return ""
+	}
+
+	directives := strings.Split(h.header.Get("Content-Security-Policy"), ";")
+	fallback := []string{"*"}
+	connect := -1
+	for i, directive := range directives {
+		fields := strings.Fields(directive)
+		if len(fields) == 0 {
+			continue
+		}
+		switch strings.ToLower(fields[0]) {
+		case "default-src":
+			fallback = fields[1:]
+		case "connect-src":
+			connect = i
+		}
+	}
+
+	sources := fallback
+	if connect >= 0 {
+		sources = strings.Fields(directives[connect])[1:]
+	}
+	// 'none' can't be combined with other sources
+	sources = slices.DeleteFunc(slices.Clone(sources), func(source string) bool { return source == "'none'" })
+	directive := " " + strings.Join(append([]string{"connect-src"}, append(sources, origins...)...), " ")
+
+	if connect >= 0 {
+		directives[connect] = directive
+	} else {
+		directives = append(directives, directive)
+	}
+	return strings.TrimSpace(strings.Join(directives, ";"))
+}
+
+// SetHeaders sets the headers through set, so every framework can use it
+func (h *SecurityHeaders) SetHeaders(set func(name, value string)) {
+	for _, name := range h.names {
+		set(name, h.header.Get(name))
+	}
+}
+
+// Middleware returns net/http middleware setting the headers
+func (h *SecurityHeaders) Middleware() func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			h.SetHeaders(w.Header().Set)
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,93 @@
+package common
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseSecurityHeadersConfig(t *testing.T) {
+	config, appErr := ParseSecurityHeadersConfig("API", []string{"cross-origin-resource-policy=cross-origin", "Strict-Transport-Security=", "X-Frame-Options=DENY"})
+	require.Nil(t, appErr)
+	assert.Equal(t, SecurityHeadersAPI, config.Preset)
+
+	header := config.Header()
+	assert.Equal(t, "cross-origin", header.Get("Cross-Origin-Resource-Policy"))
+	assert.Equal(t, "DENY", header.Get("X-Frame-Options"))
+	assert.Equal(t, "nosniff", header.Get("X-Content-Type-Options"))
+	assert.NotContains(t, header, "Strict-Transport-Security")
+
+	_, appErr = ParseSecurityHeadersConfig("paranoid", nil)
+	require.NotNil(t, appErr)
+	assert.Equal(t, "validation.security_headers_preset", string(appErr.Code))
+
+	_, appErr = ParseSecurityHeadersConfig(SecurityHeadersStrict, []string{"X-Frame-Options"})
+	require.NotNil(t, appErr)
+	assert.Equal(t, "validation.security_header", string(appErr.Code))
+}
+
+func TestSecurityHeadersPresets(t *testing.T) {
+	for _, preset := range []string{SecurityHeadersStrict, SecurityHeadersAPI} {
+		t.Run(preset, func(t *testing.T) {
+			header := SecurityHeadersConfig{Preset: preset}.Header()
+			for _, name := range []string{
+				"Strict-Transport-Security",
+				"Content-Security-Policy",
+				"X-Content-Type-Options",
+				"Referrer-Policy",
+				"Permissions-Policy",
+				"Cross-Origin-Opener-Policy",
+				"Cross-Origin-Resource-Policy",
+			} {
+				assert.NotEmpty(t, header.Get(name), name)
+			}
+		})
+	}
+
+	assert.Nil(t, NewSecurityHeaders(SecurityHeadersConfig{Preset: SecurityHeadersOff}))
+	assert.NotNil(t, NewSecurityHeaders(SecurityHeadersConfig{Preset: SecurityHeadersOff, Set: map[string]string{"X-Frame-Options": "DENY"}}))
+
+	var disabled *SecurityHeaders
+	assert.Equal(t, SecurityHeadersOff, disabled.Config().Preset)
+}
+
+func TestSecurityHeadersAllowConnect(t *testing.T) {
+	for _, tc := range []struct {
+		name, policy, expected string
+	}{
+		{"Default sources", "default-src 'self'; img-src 'self'", "default-src 'self'; img-src 'self'; connect-src 'self' http://a:9090"},
+		{"Connect sources", "default-src 'none'; connect-src https://b", "default-src 'none'; connect-src https://b http://a:9090"},
+		{"None", "default-src 'none'", "default-src 'none'; connect-src http://a:9090"},
+		{"No default sources", "frame-ancestors 'none'", "frame-ancestors 'none'; connect-src * http://a:9090"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			headers := NewSecurityHeaders(SecurityHeadersConfig{Preset: SecurityHeadersOff, Set: map[string]string{"Content-Security-Policy": tc.policy}})
+			assert.Equal(t, tc.expected, headers.AllowConnect("http://a:9090"))
+		})
+	}
+
+	assert.Empty(t, NewSecurityHeaders(SecurityHeadersConfig{Preset: SecurityHeadersOff, Set: map[string]string{"X-Frame-Options": "DENY"}}).AllowConnect("http://a:9090"))
+
+	var disabled *SecurityHeaders
+	assert.Empty(t, disabled.AllowConnect("http://a:9090"))
+}
+
+func TestSecurityHeadersMiddleware(t *testing.T) {
+	headers := NewSecurityHeaders(SecurityHeadersConfig{Preset: SecurityHeadersStrict})
+	require.NotNil(t, headers)
+
+	// handlers can override the headers
+	handler := headers.Middleware()(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Security-Policy", "default-src 'none'")
+	}))
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	assert.Equal(t, "default-src 'none'", rec.Header().Get("Content-Security-Policy"))
+	assert.Equal(t, "require-corp", rec.Header().Get("Cross-Origin-Embedder-Policy"))
+	assert.Equal(t, "max-age=63072000; includeSubDomains; preload", rec.Header().Get("Strict-Transport-Security"))
+}
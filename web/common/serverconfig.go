@@ -0,0 +1,128 @@
+package common
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/wasilak/go-hello-world/utils"
+)
+
+// ServerConfig holds the timeouts and limits applied to the HTTP server of every framework.
+// Zero values keep the framework default, which for net/http means no timeout or limit.
+type ServerConfig struct {
+	// ReadTimeout bounds reading the whole request, including the body
+	ReadTimeout time.Duration
+
+	// ReadHeaderTimeout bounds reading the request headers, it is not supported by fiber
+	ReadHeaderTimeout time.Duration
+
+	// WriteTimeout bounds writing the response, from the end of the request headers
+	WriteTimeout time.Duration
+
+	// IdleTimeout bounds how long a keep-alive connection waits for the next request
+	IdleTimeout time.Duration
+
+	// MaxHeaderBytes limits the request headers size, fiber maps it to its read buffer size
+	MaxHeaderBytes int
+
+	// MaxBodyBytes limits the request body size, larger bodies get a 413 response
+	MaxBodyBytes int64
+
+	// DisableKeepAlive closes the connection after every response
+	DisableKeepAlive bool
+}
+
+// fiberDefaultReadBufferSize is the read buffer size fiber uses without a header limit,
+// which bounds the request headers
+const fiberDefaultReadBufferSize = 4096
+
+// ServerConfigReport is the effective server configuration of a framework, as reported by
+// the config introspection endpoint
+type ServerConfigReport struct {
+	ReadTimeout       string   `json:"read_timeout"`
+	ReadHeaderTimeout string   `json:"read_header_timeout"`
+	WriteTimeout      string   `json:"write_timeout"`
+	IdleTimeout       string   `json:"idle_timeout"`
+	MaxHeaderBytes    int      `json:"max_header_bytes"`
+	MaxBodyBytes      int64    `json:"max_body_bytes"`
+	KeepAlive         bool     `json:"keep_alive"`
+	Unsupported       []string `json:"unsupported,omitempty"`
+}
+
+// Validate checks the server configuration
+func (c ServerConfig) Validate() *utils.AppError {
+	if c.ReadTimeout < 0 || c.ReadHeaderTimeout < 0 || c.WriteTimeout < 0 || c.IdleTimeout < 0 {
+		return utils.NewAppError(utils.ValidationError, "server timeouts must not be negative", nil).WithCode("validation.server_timeout")
+	}
+	if c.ReadTimeout > 0 && c.ReadHeaderTimeout > c.ReadTimeout {
+		return utils.NewAppError(utils.ValidationError, "server read header timeout must not exceed the read timeout", nil).WithCode("validation.server_timeout")
+	}
+	if c.MaxHeaderBytes < 0 || c.MaxBodyBytes < 0 {
+		return utils.NewAppError(utils.ValidationError, "server header and body limits must not be negative", nil).WithCode("validation.server_limit")
+	}
+	return nil
+}
+
+// Apply sets the timeouts and limits on a net/http server
+func (c ServerConfig) Apply(server *http.Server) {
+	server.ReadTimeout = c.ReadTimeout
+	server.ReadHeaderTimeout = c.ReadHeaderTimeout
+	server.WriteTimeout = c.WriteTimeout
+	server.IdleTimeout = c.IdleTimeout
+	server.MaxHeaderBytes = c.MaxHeaderBytes
+	server.SetKeepAlivesEnabled(!c.DisableKeepAlive)
+}
+
+// Report returns the effective configuration of framework, listing the settings it cannot apply
+func (c ServerConfig) Report(framework string) ServerConfigReport {
+	report := ServerConfigReport{
+		ReadTimeout:       c.ReadTimeout.String(),
+		ReadHeaderTimeout: c.ReadHeaderTimeout.String(),
+		WriteTimeout:      c.WriteTimeout.String(),
+		IdleTimeout:       c.IdleTimeout.String(),
+		MaxHeaderBytes:    c.MaxHeaderBytes,
+		MaxBodyBytes:      c.MaxBodyBytes,
+		KeepAlive:         !c.DisableKeepAlive,
+	}
+
+	// without a header limit the framework default applies
+	if c.MaxHeaderBytes == 0 {
+		report.MaxHeaderBytes = http.DefaultMaxHeaderBytes
+		if framework == "fiber" {
+			report.MaxHeaderBytes = fiberDefaultReadBufferSize
+		}
+	}
+
+	if framework == "fiber" && c.ReadHeaderTimeout > 0 {
+		report.Unsupported = append(report.Unsupported, "read_header_timeout")
+	}
+	return report
+}
+
+// BodyTooLargeProblem returns the problem rendered for a request body over the limit
+func (c ProblemConfig) BodyTooLargeProblem(ctx context.Context, path string) Problem {
+	problem := c.StatusProblem(ctx, http.StatusRequestEntityTooLarge, "request body too large", path)
+	problem.Code = "request.body_too_large"
+	return problem
+}
+
+// BodyLimitMiddleware returns net/http middleware rejecting requests declaring a body over
+// the limit and capping the body of the others, a zero limit disables it
+func (c ServerConfig) BodyLimitMiddleware(problems ProblemConfig) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		if c.MaxBodyBytes == 0 {
+			return next
+		}
+
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.ContentLength > c.MaxBodyBytes {
+				WriteProblem(w, problems.BodyTooLargeProblem(r.Context(), r.URL.Path))
+				return
+			}
+			r.Body = http.MaxBytesReader(w, r.Body, c.MaxBodyBytes)
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,70 @@
+package common
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestServerConfigValidate(t *testing.T) {
+	assert.Nil(t, ServerConfig{}.Validate())
+	assert.Nil(t, ServerConfig{ReadTimeout: 30 * time.Second, ReadHeaderTimeout: 10 * time.Second, MaxBodyBytes: 1024}.Validate())
+	assert.NotNil(t, ServerConfig{WriteTimeout: -time.Second}.Validate())
+	assert.NotNil(t, ServerConfig{ReadTimeout: time.Second, ReadHeaderTimeout: 10 * time.Second}.Validate())
+	assert.NotNil(t, ServerConfig{MaxHeaderBytes: -1}.Validate())
+}
+
+func TestServerConfigApplyAndReport(t *testing.T) {
+	config := ServerConfig{ReadTimeout: 30 * time.Second, ReadHeaderTimeout: 10 * time.Second, IdleTimeout: time.Minute, MaxHeaderBytes: 8192}
+
+	server := &http.Server{}
+	config.Apply(server)
+	assert.Equal(t, 10*time.Second, server.ReadHeaderTimeout)
+	assert.Equal(t, 8192, server.MaxHeaderBytes)
+
+	report := config.Report("gorilla")
+	assert.Equal(t, "30s", report.ReadTimeout)
+	assert.Equal(t, "0s", report.WriteTimeout)
+	assert.True(t, report.KeepAlive)
+	assert.Empty(t, report.Unsupported)
+
+	assert.Equal(t, 8192, report.MaxHeaderBytes)
+
+	assert.Equal(t, []string{"read_header_timeout"}, config.Report("fiber").Unsupported)
+
+	// without a header limit the report has the framework default
+	assert.Equal(t, http.DefaultMaxHeaderBytes, ServerConfig{}.Report("gorilla").MaxHeaderBytes)
+	assert.Equal(t, 4096, ServerConfig{}.Report("fiber").MaxHeaderBytes)
+}
+
+func TestBodyLimitMiddleware(t *testing.T) {
+	handler := ServerConfig{MaxBodyBytes: 4}.BodyLimitMiddleware(ProblemConfig{})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, err := io.ReadAll(r.Body); err != nil {
+			w.WriteHeader(http.StatusRequestEntityTooLarge)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/", strings.NewReader("1234")))
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/", strings.NewReader("12345")))
+	assert.Equal(t, http.StatusRequestEntityTooLarge, rec.Code)
+	assert.Equal(t, ProblemContentType, rec.Header().Get("Content-Type"))
+	assert.Contains(t, rec.Body.String(), `"code":"request.body_too_large"`)
+
+	// bodies of unknown length are capped while read
+	req := httptest.NewRequest(http.MethodPost, "/", io.NopCloser(strings.NewReader("12345")))
+	req.ContentLength = -1
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusRequestEntityTooLarge, rec.Code)
+}
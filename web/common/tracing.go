@@ -0,0 +1,95 @@
+package common
+
+import (
+	"net/http"
+	"strings"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/wasilak/go-hello-world/utils"
+)
+
+// TracingConfig is the tracing configuration applied uniformly to every framework's
+// OpenTelemetry middleware
+type TracingConfig struct {
+	TracerProvider          trace.TracerProvider
+	Propagators             propagation.TextMapPropagator
+	FilteredPaths           []string
+	CapturedRequestHeaders  []string
+	CapturedResponseHeaders []string
+
+	// Redaction redacts the values of sensitive captured headers
+	Redaction *utils.Redactor
+}
+
+// GetPropagators returns the configured propagators, falling back to the global ones
+func (c TracingConfig) GetPropagators() propagation.TextMapPropagator {
+	if c.Propagators != nil {
+		return c.Propagators
+	}
+	return otel.GetTextMapPropagator()
+}
+
+// SpanName formats server span names the same way for every framework, e.g. "GET /health"
+func (c TracingConfig) SpanName(method, route string) string {
+	if route == "" {
+		return "HTTP " + strings.ToUpper(method) + " route not found"
+	}
+	return strings.ToUpper(method) + " " + route
+}
+
+// Traced reports whether a request path should be traced, i.e. it does not start
+// with any of the filtered paths
+func (c TracingConfig) Traced(path string) bool {
+	for _, filtered := range c.FilteredPaths {
+		if filtered != "" && strings.HasPrefix(path, filtered) {
+			return false
+		}
+	}
+	return true
+}
+
+// RecordRequestHeaders adds the captured request headers to the span as
+// http.request.header.<name> attributes, sensitive values redacted
+func (c TracingConfig) RecordRequestHeaders(span trace.Span, headers http.Header) {
+	span.SetAttributes(headerAttributes("http.request.header.", c.CapturedRequestHeaders, c.Redaction.Headers(headers))...)
+}
+
+// RecordResponseHeaders adds the captured response headers to the span as
+// http.response.header.<name> attributes, sensitive values redacted
+func (c TracingConfig) RecordResponseHeaders(span trace.Span, headers http.Header) {
+	span.SetAttributes(headerAttributes("http.response.header.", c.CapturedResponseHeaders, c.Redaction.Headers(headers))...)
+}
+
+// HeaderCaptureMiddleware returns net/http middleware recording the captured headers on
+// the span started by the framework's OpenTelemetry middleware
+func (c TracingConfig) HeaderCaptureMiddleware() func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			span := trace.SpanFromContext(r.Context())
+			c.RecordRequestHeaders(span, r.Header)
+
+			next.ServeHTTP(w, r)
+
+			c.RecordResponseHeaders(span, w.Header())
+		})
+	}
+}
+
+// headerAttributes builds semantic convention header attributes, keys are lowercased
+// and dashes replaced with underscores
+func headerAttributes(prefix string, names []string, headers http.Header) []attribute.KeyValue {
+	var attrs []attribute.KeyValue
+	for _, name := range names {
+		values := headers.Values(name)
+		if len(values) == 0 {
+			continue
+		}
+		key := prefix + strings.ReplaceAll(strings.ToLower(strings.TrimSpace(name)), "-", "_")
+		attrs = append(attrs, attribute.StringSlice(key, values))
+	}
+	return attrs
+}
@@ -0,0 +1,53 @@
+package common
+
+import (
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/wasilak/go-hello-world/utils"
+)
+
+// BuildInfoGauge is always 1, the build metadata is in its labels
+var BuildInfoGauge = promauto.NewGaugeFunc(
+	prometheus.GaugeOpts{
+		Name: "build_info",
+		Help: "Build information of the running binary, always 1",
+		ConstLabels: prometheus.Labels{
+			"version":    utils.GetBuildInfo().Version,
+			"commit":     utils.GetBuildInfo().Commit,
+			"build_date": utils.GetBuildInfo().BuildDate,
+			"go_version": utils.GetBuildInfo().GoVersion,
+		},
+	},
+	func() float64 { return 1 },
+)
+
+// VersionResponse type
+type VersionResponse struct {
+	utils.BuildInfo
+	Features map[string]bool `json:"features"`
+}
+
+// Features reports which optional features the options enable
+func (o FrameworkOptions) Features() map[string]bool {
+	return map[string]bool{
+		"otel":              o.OtelEnabled,
+		"http_metrics":      o.HTTPMetrics != nil,
+		"statsviz":          o.StatsvizEnabled,
+		"admin":             o.Admin.Enabled(),
+		"proxy":             o.Proxy.Enabled(),
+		"chain":             len(o.Chain.Hops) > 0,
+		"access_log":        o.AccessLog != nil,
+		"rate_limit":        o.RateLimit != nil && o.RateLimit.Config().Enabled(),
+		"concurrency_limit": o.Concurrency != nil,
+		"instance_info":     o.Instance != nil,
+	}
+}
+
+// VersionHandler serves the build metadata and the enabled features in the negotiated format
+func VersionHandler(options FrameworkOptions) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		WriteResponse(w, r, options.Problems, http.StatusOK, VersionResponse{BuildInfo: utils.GetBuildInfo(), Features: options.Features()})
+	})
+}
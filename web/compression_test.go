@@ -0,0 +1,93 @@
+package web
+
+import (
+	"compress/gzip"
+	"compress/zlib"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/andybalholm/brotli"
+	"github.com/klauspost/compress/zstd"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/wasilak/go-hello-world/web/common"
+)
+
+// newDecoder returns a reader decompressing body encoded with encoding
+func newDecoder(t *testing.T, encoding string, body io.Reader) io.Reader {
+	t.Helper()
+
+	switch encoding {
+	case common.EncodingGzip:
+		r, err := gzip.NewReader(body)
+		require.NoError(t, err)
+		return r
+	case common.EncodingDeflate:
+		r, err := zlib.NewReader(body)
+		require.NoError(t, err)
+		return r
+	case common.EncodingBrotli:
+		return brotli.NewReader(body)
+	case common.EncodingZstd:
+		r, err := zstd.NewReader(body)
+		require.NoError(t, err)
+		t.Cleanup(r.Close)
+		return r
+	}
+	return body
+}
+
+func TestCompressionPerFramework(t *testing.T) {
+	for _, framework := range testFrameworks {
+		t.Run(framework, func(t *testing.T) {
+			config, appErr := common.ParseCompressionConfig(common.CompressionEncodings(), nil, 128, common.DefaultCompressionContentTypes())
+			require.Nil(t, appErr)
+			options := testOptions(t)
+			options.Middleware = common.NewMiddlewareRegistry(common.MiddlewareConfig{})
+			options.Compression = common.NewCompressor(config)
+			baseURL := startTestServer(t, context.Background(), framework, options)
+
+			get := func(path, acceptEncoding string) (*http.Response, []byte) {
+				req, err := http.NewRequest(http.MethodGet, baseURL+path, nil)
+				require.NoError(t, err)
+				req.Header.Set("Accept-Encoding", acceptEncoding)
+				resp, err := http.DefaultTransport.RoundTrip(req)
+				require.NoError(t, err)
+				defer resp.Body.Close()
+
+				body, err := io.ReadAll(newDecoder(t, resp.Header.Get("Content-Encoding"), resp.Body))
+				require.NoError(t, err)
+				return resp, body
+			}
+
+			for _, encoding := range common.CompressionEncodings() {
+				resp, body := get("/middleware", "identity;q=0.5, "+encoding)
+				require.Equal(t, http.StatusOK, resp.StatusCode)
+				assert.Equal(t, encoding, resp.Header.Get("Content-Encoding"))
+				assert.Contains(t, strings.Join(resp.Header.Values("Vary"), ", "), "Accept-Encoding")
+
+				var chain common.MiddlewareChain
+				require.NoError(t, json.Unmarshal(body, &chain), encoding)
+				assert.Contains(t, chain.Active, common.MiddlewareCompression)
+			}
+
+			// the best quality wins, the configured order breaks ties
+			resp, _ := get("/middleware", "gzip;q=0.8, deflate;q=0.8, zstd;q=0.5")
+			assert.Equal(t, common.EncodingGzip, resp.Header.Get("Content-Encoding"))
+
+			// responses below the minimum size are sent as they are
+			resp, body := get("/health", "br")
+			require.Equal(t, http.StatusOK, resp.StatusCode)
+			assert.Empty(t, resp.Header.Get("Content-Encoding"))
+			assert.True(t, json.Valid(body))
+
+			resp, body = get("/middleware", "")
+			assert.Empty(t, resp.Header.Get("Content-Encoding"))
+			assert.True(t, json.Valid(body))
+		})
+	}
+}
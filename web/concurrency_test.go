@@ -0,0 +1,88 @@
+package web
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/wasilak/go-hello-world/web/common"
+)
+
+func TestConcurrencyLimitPerFramework(t *testing.T) {
+	for _, framework := range testFrameworks {
+		t.Run(framework, func(t *testing.T) {
+			runtime := common.NewRuntimeState()
+			options := testOptions(t)
+			options.Runtime = runtime
+			options.Concurrency = common.NewConcurrencyLimiter(common.ConcurrencyConfig{Limit: 1, ExcludePaths: []string{"/health"}})
+			baseURL := startTestServer(t, context.Background(), framework, options)
+
+			// injected latency keeps the first request in flight
+			runtime.SetFaults(&common.FaultConfig{LatencyMS: 300, Paths: []string{"/"}})
+			t.Cleanup(func() { runtime.SetFaults(nil) })
+
+			shed := common.ConcurrencyShedCounterVec.WithLabelValues(framework, common.ShedQueueFull)
+			before := testutil.ToFloat64(shed)
+
+			done := make(chan int)
+			go func() {
+				resp, err := http.Get(baseURL + "/")
+				if err != nil {
+					done <- 0
+					return
+				}
+				resp.Body.Close()
+				done <- resp.StatusCode
+			}()
+
+			require.Eventually(t, func() bool {
+				return testutil.ToFloat64(common.ConcurrencyInFlight) == 1
+			}, time.Second, 5*time.Millisecond)
+
+			// the Accept header is set by the client, so it doesn't exempt a request
+			req, err := http.NewRequest(http.MethodGet, baseURL+"/", nil)
+			require.NoError(t, err)
+			req.Header.Set("Accept", common.EventStreamContentType)
+			resp, err := http.DefaultClient.Do(req)
+			require.NoError(t, err)
+			resp.Body.Close()
+
+			assert.Equal(t, http.StatusServiceUnavailable, resp.StatusCode)
+			assert.Equal(t, "1", resp.Header.Get("Retry-After"))
+			assert.Equal(t, before+1, testutil.ToFloat64(shed))
+			assert.Equal(t, http.StatusOK, <-done)
+		})
+	}
+}
+
+func TestConcurrencyEventStreamPerFramework(t *testing.T) {
+	for _, framework := range testFrameworks {
+		t.Run(framework, func(t *testing.T) {
+			options := testOptions(t)
+			options.Server = common.ServerConfig{WriteTimeout: 10 * time.Second}
+			options.Concurrency = common.NewConcurrencyLimiter(common.ConcurrencyConfig{Limit: 1})
+			baseURL := startTestServer(t, context.Background(), framework, options)
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			req, err := http.NewRequestWithContext(ctx, http.MethodGet, baseURL+common.DashboardEventsPath, nil)
+			require.NoError(t, err)
+			stream, err := http.DefaultClient.Do(req)
+			require.NoError(t, err)
+			defer stream.Body.Close()
+			require.Equal(t, http.StatusOK, stream.StatusCode)
+
+			// the open stream holds no slot
+			assert.Zero(t, testutil.ToFloat64(common.ConcurrencyInFlight))
+			resp, err := http.Get(baseURL + "/")
+			require.NoError(t, err)
+			resp.Body.Close()
+			assert.Equal(t, http.StatusOK, resp.StatusCode)
+		})
+	}
+}
@@ -0,0 +1,72 @@
+package web
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/wasilak/go-hello-world/web/common"
+)
+
+func TestCORSPerFramework(t *testing.T) {
+	for _, framework := range testFrameworks {
+		t.Run(framework, func(t *testing.T) {
+			config, appErr := common.ParseCORSConfig([]string{"https://*.example.com"}, common.DefaultCORSMethods(), common.DefaultCORSHeaders(), common.DefaultCORSExposedHeaders(), true, 5*time.Minute)
+			require.Nil(t, appErr)
+			options := testOptions(t)
+			options.RequestID = common.RequestIDConfig{Header: common.DefaultRequestIDHeader, Format: common.RequestIDFormatUUIDv7}
+			options.CORS = common.NewCORSPolicy(config)
+			baseURL := startTestServer(t, context.Background(), framework, options)
+
+			do := func(method, path string, header map[string]string) *http.Response {
+				req, err := http.NewRequest(method, baseURL+path, nil)
+				require.NoError(t, err)
+				for name, value := range header {
+					req.Header.Set(name, value)
+				}
+				resp, err := http.DefaultClient.Do(req)
+				require.NoError(t, err)
+				resp.Body.Close()
+				return resp
+			}
+
+			// preflight requests are answered before routing, also for unknown routes
+			for _, path := range []string{"/", "/missing"} {
+				resp := do(http.MethodOptions, path, map[string]string{
+					"Origin":                         "https://tools.example.com",
+					"Access-Control-Request-Method":  http.MethodPut,
+					"Access-Control-Request-Headers": "Content-Type, X-Request-ID",
+				})
+				assert.Equal(t, http.StatusNoContent, resp.StatusCode, path)
+				assert.Equal(t, "https://tools.example.com", resp.Header.Get("Access-Control-Allow-Origin"))
+				assert.Equal(t, "true", resp.Header.Get("Access-Control-Allow-Credentials"))
+				assert.Contains(t, resp.Header.Get("Access-Control-Allow-Methods"), http.MethodPut)
+				assert.Contains(t, resp.Header.Get("Access-Control-Allow-Headers"), common.DefaultRequestIDHeader)
+				assert.Equal(t, "300", resp.Header.Get("Access-Control-Max-Age"))
+				assert.Contains(t, strings.Join(resp.Header.Values("Vary"), ", "), "Access-Control-Request-Method")
+			}
+
+			resp := do(http.MethodGet, "/health", map[string]string{"Origin": "https://tools.example.com"})
+			assert.Equal(t, http.StatusOK, resp.StatusCode)
+			assert.Equal(t, "https://tools.example.com", resp.Header.Get("Access-Control-Allow-Origin"))
+			assert.Contains(t, resp.Header.Get("Access-Control-Expose-Headers"), common.DefaultRequestIDHeader)
+			assert.Contains(t, strings.Join(resp.Header.Values("Vary"), ", "), "Origin")
+
+			// error responses carry the headers too, so scripts can read the problem
+			resp = do(http.MethodGet, "/missing", map[string]string{"Origin": "https://tools.example.com"})
+			assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+			assert.Equal(t, "https://tools.example.com", resp.Header.Get("Access-Control-Allow-Origin"))
+
+			resp = do(http.MethodOptions, "/", map[string]string{
+				"Origin":                        "https://evil.io",
+				"Access-Control-Request-Method": http.MethodGet,
+			})
+			assert.Equal(t, http.StatusNoContent, resp.StatusCode)
+			assert.Empty(t, resp.Header.Get("Access-Control-Allow-Origin"))
+		})
+	}
+}
@@ -0,0 +1,81 @@
+package web
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/wasilak/go-hello-world/web/common"
+)
+
+func TestDashboardPerFramework(t *testing.T) {
+	for _, framework := range testFrameworks {
+		t.Run(framework, func(t *testing.T) {
+			runtime := common.NewRuntimeState()
+			runtime.SetFramework(framework)
+
+			options := testOptions(t)
+			options.Runtime = runtime
+			options.Server = common.ServerConfig{WriteTimeout: 10 * time.Second}
+			options.Middleware = common.NewMiddlewareRegistry(common.MiddlewareConfig{Disabled: []string{common.MiddlewareFault}})
+			baseURL := startTestServer(t, context.Background(), framework, options)
+
+			// requests are counted whatever the middleware chain, unknown routes included
+			t.Run("Requests", func(t *testing.T) {
+				before := runtime.Requests()
+				for _, path := range []string{"/health", "/missing"} {
+					resp, err := http.Get(baseURL + path)
+					require.NoError(t, err)
+					resp.Body.Close()
+				}
+				assert.Equal(t, before+2, runtime.Requests())
+			})
+
+			t.Run("Page", func(t *testing.T) {
+				for path, content := range map[string]string{"/ui": "dashboard.js", "/ui/": "dashboard.js", "/ui/dashboard.js": "EventSource"} {
+					resp, err := http.Get(baseURL + path)
+					require.NoError(t, err)
+					body, err := io.ReadAll(resp.Body)
+					resp.Body.Close()
+					require.NoError(t, err)
+
+					assert.Equal(t, http.StatusOK, resp.StatusCode, path)
+					assert.Contains(t, string(body), content, path)
+				}
+			})
+
+			t.Run("Events", func(t *testing.T) {
+				ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+				defer cancel()
+
+				req, err := http.NewRequestWithContext(ctx, http.MethodGet, baseURL+"/ui/events", nil)
+				require.NoError(t, err)
+				resp, err := http.DefaultClient.Do(req)
+				require.NoError(t, err)
+				defer resp.Body.Close()
+				assert.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+
+				// the first state is sent right away
+				scanner := bufio.NewScanner(resp.Body)
+				var state common.DashboardState
+				for scanner.Scan() {
+					if data, found := strings.CutPrefix(scanner.Text(), "data: "); found {
+						require.NoError(t, json.Unmarshal([]byte(data), &state))
+						break
+					}
+				}
+				assert.Equal(t, framework, state.Framework)
+				assert.Equal(t, "INFO", state.LogLevel)
+				assert.Positive(t, state.Requests)
+				assert.Len(t, state.Checks, 3)
+			})
+		})
+	}
+}
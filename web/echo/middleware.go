@@ -0,0 +1,171 @@
+package echo
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/wasilak/go-hello-world/utils"
+	"github.com/wasilak/go-hello-world/web/common"
+)
+
+// otelMetricsMiddleware records the OpenTelemetry HTTP server metrics for echo
+func (s *Server) otelMetricsMiddleware() echo.MiddlewareFunc {
+	metrics := s.FrameworkOptions.HTTPMetrics
+
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			req := c.Request()
+			done := metrics.Begin(req.Context(), req.Method, c.Scheme(), s.Framework)
+
+			err := next(c)
+
+			done(c.Path(), responseStatus(c, err), req.ContentLength, c.Response().Size)
+
+			return err
+		}
+	}
+}
+
+// responseStatus resolves the status code echo will send, including for errors
+// that are only rendered by the HTTP error handler after the middleware chain
+func responseStatus(c echo.Context, err error) int {
+	if err == nil || c.Response().Committed {
+		return c.Response().Status
+	}
+
+	if appErr, ok := utils.AsAppError(err); ok {
+		return common.ProblemStatus(appErr.Type)
+	}
+
+	var httpErr *echo.HTTPError
+	if errors.As(err, &httpErr) {
+		return httpErr.Code
+	}
+
+	return http.StatusInternalServerError
+}
+
+// errorHandler renders the errors returned by handlers, including echo's own
+// HTTP errors such as unknown routes, as RFC 9457 problem details
+func (s *Server) errorHandler(err error, c echo.Context) {
+	if c.Response().Committed {
+		return
+	}
+
+	ctx := c.Request().Context()
+	problems := s.FrameworkOptions.Problems
+	problem := problems.FromError(ctx, err, c.Request().URL.Path)
+
+	// AppErrors take precedence over an HTTPError wrapping them
+	var httpErr *echo.HTTPError
+	if _, ok := utils.AsAppError(err); !ok && errors.As(err, &httpErr) {
+		problem = problems.StatusProblem(ctx, httpErr.Code, fmt.Sprint(httpErr.Message), c.Request().URL.Path)
+	}
+
+	c.Response().Header().Set(echo.HeaderContentType, common.ProblemContentType)
+	if c.Request().Method == http.MethodHead {
+		err = c.NoContent(problem.Status)
+	} else {
+		err = c.JSON(problem.Status, problem)
+	}
+	if err != nil {
+		s.Server.Logger.Error(err)
+	}
+}
+
+// errorScopeMiddleware labels errors logged during the request with the framework and route
+func (s *Server) errorScopeMiddleware() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			ctx := utils.WithErrorScope(c.Request().Context(), utils.ErrorScope{Framework: s.Framework, Route: c.Path})
+			c.SetRequest(c.Request().WithContext(ctx))
+			return next(c)
+		}
+	}
+}
+
+// compressionMiddleware compresses the response with the negotiated encoding. Errors are
+// rendered here so problem responses are compressed too.
+func (s *Server) compressionMiddleware() echo.MiddlewareFunc {
+	compressor := s.FrameworkOptions.Compression
+
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			// upgraded connections, e.g. the statsviz websocket, are hijacked
+			if c.Request().Header.Get(echo.HeaderUpgrade) != "" {
+				return next(c)
+			}
+
+			res := c.Response()
+			writer := res.Writer
+			compress := compressor.NewResponseWriter(writer, compressor.Negotiate(c.Request().Header.Get(echo.HeaderAcceptEncoding)))
+			res.Writer = compress
+			defer func() {
+				// a panicking handler leaves the response to the recovery middleware
+				res.Writer = writer
+				if p := recover(); p != nil {
+					panic(p)
+				}
+			}()
+
+			err := next(c)
+			if err != nil {
+				c.Error(err)
+			}
+
+			_ = compress.Close()
+			return err
+		}
+	}
+}
+
+// accessLogMiddleware writes the common access log entries. Errors are rendered here
+// so the entry has the final status and body.
+func (s *Server) accessLogMiddleware() echo.MiddlewareFunc {
+	accessLog := s.FrameworkOptions.AccessLog
+
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			req := c.Request()
+			if accessLog.Excluded(req.URL.Path) {
+				return next(c)
+			}
+
+			start := time.Now()
+
+			entry := common.AccessLogEntry{
+				Method:    req.Method,
+				Path:      req.URL.Path,
+				ClientIP:  common.ClientIP(req.RemoteAddr),
+				UserAgent: req.UserAgent(),
+				Headers:   req.Header,
+			}
+			if limit := accessLog.RequestBodyLimit(); limit > 0 && req.Body != nil {
+				entry.RequestBody = common.NewBodyCapture(limit)
+				req.Body = entry.RequestBody.Tee(req.Body)
+			}
+			if limit := accessLog.ResponseBodyLimit(); limit > 0 {
+				entry.ResponseBody = common.NewBodyCapture(limit)
+				recorder := common.NewResponseRecorder(c.Response().Writer)
+				recorder.Body = entry.ResponseBody
+				c.Response().Writer = recorder
+			}
+
+			err := next(c)
+			if err != nil {
+				c.Error(err)
+			}
+
+			entry.Route = c.Path()
+			entry.Status = c.Response().Status
+			entry.Bytes = c.Response().Size
+			entry.Latency = time.Since(start)
+			accessLog.Log(c.Request().Context(), entry)
+
+			return err
+		}
+	}
+}
@@ -12,25 +12,47 @@ func (s *Server) mainRoute(c echo.Context) error {
 	ctx := c.Request().Context()
 	response := s.SetMainResponse(ctx, c.Request())
 
-	if err := c.JSON(http.StatusOK, response); err != nil {
-		// Use the new standardized error types
-		appErr := utils.WrapError(err, utils.RuntimeError, "failed to encode main response in echo")
-		appErr.AddContext("path", c.Path())
-		appErr.LogError(ctx)
-		// Return the original error to be handled by echo
-		return err
-	}
-	return nil
+	return s.render(c, response)
 }
 
 func (s *Server) healthRoute(c echo.Context) error {
+	return s.render(c, common.HealthResponse{Status: "ok"})
+}
+
+// render writes the response in the format negotiated for the request
+func (s *Server) render(c echo.Context, response any) error {
+	body, contentType, err := common.RenderResponse(c.Request(), response)
+	if err != nil {
+		appErr, _ := utils.AsAppError(err)
+		appErr.AddContext(utils.ContextPath, c.Path())
+		appErr.LogError(c.Request().Context())
+		// Return the error to be handled by echo
+		return appErr
+	}
+
+	c.Response().Header().Add("Vary", "Accept")
+	return c.Blob(http.StatusOK, contentType, body)
+}
+
+func (s *Server) loggerRoute(c echo.Context) error {
 	ctx := c.Request().Context()
-	response := common.HealthResponse{Status: "ok"}
+	levelParam := c.QueryParam("level")
+	response, appErr := s.SetLogLevelResponse(ctx, common.LogLevelRequest{
+		Logger: c.QueryParam("logger"),
+		Level:  levelParam,
+		TTL:    c.QueryParam("ttl"),
+	})
+	if appErr != nil {
+		appErr.AddContext(utils.ContextPath, c.Path())
+		appErr.LogError(ctx)
+		return appErr
+	}
 
 	if err := c.JSON(http.StatusOK, response); err != nil {
 		// Use the new standardized error types
-		appErr := utils.WrapError(err, utils.RuntimeError, "failed to encode health response in echo")
-		appErr.AddContext("path", c.Path())
+		appErr := utils.WrapError(err, utils.RuntimeError, "failed to encode logger response in echo")
+		appErr.AddContext(utils.ContextPath, c.Path())
+		appErr.AddContext(utils.ContextLogLevel, levelParam)
 		appErr.LogError(ctx)
 		// Return the original error to be handled by echo
 		return err
@@ -38,16 +60,21 @@ func (s *Server) healthRoute(c echo.Context) error {
 	return nil
 }
 
-func (s *Server) loggerRoute(c echo.Context) error {
+func (s *Server) switchRoute(c echo.Context) error {
 	ctx := c.Request().Context()
-	levelParam := c.QueryParam("level")
-	response := s.SetLogLevelResponse(ctx, levelParam)
+	nameParam := c.QueryParam("name")
+	response, appErr := s.SetFrameworkResponse(ctx, nameParam)
+	if appErr != nil {
+		appErr.AddContext(utils.ContextPath, c.Path())
+		appErr.LogError(ctx)
+		return appErr
+	}
 
 	if err := c.JSON(http.StatusOK, response); err != nil {
 		// Use the new standardized error types
-		appErr := utils.WrapError(err, utils.RuntimeError, "failed to encode logger response in echo")
-		appErr.AddContext("path", c.Path())
-		appErr.AddContext("log_level", levelParam)
+		appErr := utils.WrapError(err, utils.RuntimeError, "failed to encode switch response in echo")
+		appErr.AddContext(utils.ContextPath, c.Path())
+		appErr.AddContext(utils.ContextFramework, nameParam)
 		appErr.LogError(ctx)
 		// Return the original error to be handled by echo
 		return err
@@ -55,16 +82,21 @@ func (s *Server) loggerRoute(c echo.Context) error {
 	return nil
 }
 
-func (s *Server) switchRoute(c echo.Context) error {
+func (s *Server) chainRoute(c echo.Context) error {
 	ctx := c.Request().Context()
-	nameParam := c.QueryParam("name")
-	response := s.SetFrameworkResponse(ctx, nameParam)
+	request, appErr := s.FrameworkOptions.Chain.ParseChainRequest(c.QueryParams(), c.Request().Header.Get(common.ChainDepthHeader))
+	if appErr != nil {
+		appErr.AddContext(utils.ContextPath, c.Path())
+		appErr.LogError(ctx)
+		return appErr
+	}
+
+	response := s.SetChainResponse(ctx, request)
 
 	if err := c.JSON(http.StatusOK, response); err != nil {
 		// Use the new standardized error types
-		appErr := utils.WrapError(err, utils.RuntimeError, "failed to encode switch response in echo")
-		appErr.AddContext("path", c.Path())
-		appErr.AddContext("framework", nameParam)
+		appErr := utils.WrapError(err, utils.RuntimeError, "failed to encode chain response in echo")
+		appErr.AddContext(utils.ContextPath, c.Path())
 		appErr.LogError(ctx)
 		// Return the original error to be handled by echo
 		return err
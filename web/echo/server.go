@@ -2,7 +2,7 @@ package echo
 
 import (
 	"context"
-	"log/slog"
+	"errors"
 	"net/http"
 	"strings"
 	"time"
@@ -13,7 +13,6 @@ import (
 	"github.com/labstack/echo/v4/middleware"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/collectors"
-	slogecho "github.com/samber/slog-echo"
 	"github.com/wasilak/go-hello-world/utils"
 	"github.com/wasilak/go-hello-world/web/common"
 	"go.opentelemetry.io/contrib/instrumentation/github.com/labstack/echo/otelecho"
@@ -29,6 +28,10 @@ func (s *Server) setup() {
 
 	s.Server.HideBanner = true
 	s.Server.HidePort = true
+	s.Server.HTTPErrorHandler = s.errorHandler
+
+	// Server timeouts and limits, echo serves through its own net/http server
+	s.FrameworkOptions.Server.Apply(s.Server.Server)
 
 	s.Server.Debug = strings.EqualFold(s.FrameworkOptions.LogLevelConfig.Level().String(), "debug")
 
@@ -40,36 +43,89 @@ func (s *Server) setup() {
 	common.RegisterCollectorIfNotRegistered(goCollector)
 	common.RegisterCollectorIfNotRegistered(processCollector)
 
-	if s.FrameworkOptions.OtelEnabled {
-		s.Server.Use(otelecho.Middleware(utils.GetAppName(), otelecho.WithTracerProvider(s.FrameworkOptions.TraceProvider), otelecho.WithSkipper(func(c echo.Context) bool {
-			return strings.Contains(c.Path(), "public/dist") || strings.Contains(c.Path(), "health")
-		})))
+	echoprometheusConfig := echoprometheus.MiddlewareConfig{
+		Subsystem:  strings.ReplaceAll(utils.GetAppName(), "-", "_"),
+		Registerer: prometheus.Registerer(prometheus.NewRegistry()),
+	}
+
+	// Middleware provided by echo, applied in the configured order
+	available := map[string][]echo.MiddlewareFunc{
+		common.MiddlewareRecovery:    {middleware.Recover()},
+		common.MiddlewarePrometheus:  {echoprometheus.NewMiddlewareWithConfig(echoprometheusConfig)},
+		common.MiddlewareRequestID:   {echo.WrapMiddleware(s.FrameworkOptions.RequestID.Middleware())},
+		common.MiddlewareErrorScope:  {s.errorScopeMiddleware()},
+		common.MiddlewareAccessLog:   {s.accessLogMiddleware()},
+		common.MiddlewareBodyLimit:   {echo.WrapMiddleware(s.FrameworkOptions.Server.BodyLimitMiddleware(s.FrameworkOptions.Problems))},
+		common.MiddlewareRateLimit:   {echo.WrapMiddleware(s.FrameworkOptions.RateLimit.Middleware(s.FrameworkOptions.Problems))},
+		common.MiddlewareConcurrency: {echo.WrapMiddleware(s.FrameworkOptions.Concurrency.Middleware(s.Framework, s.FrameworkOptions.Problems))},
+		common.MiddlewareFault:       {echo.WrapMiddleware(common.FaultMiddleware(s.FrameworkOptions.Runtime, s.FrameworkOptions.Problems))},
 	}
 
-	s.Server.Use(slogecho.New(slog.Default()))
+	// CORS headers and preflight responses, shared by all frameworks
+	if s.FrameworkOptions.CORS != nil {
+		available[common.MiddlewareCORS] = []echo.MiddlewareFunc{echo.WrapMiddleware(s.FrameworkOptions.CORS.Middleware())}
+	}
 
-	s.Server.Use(middleware.GzipWithConfig(middleware.GzipConfig{
-		Skipper: func(c echo.Context) bool {
-			return strings.Contains(c.Path(), "metrics")
-		},
-	}))
+	// Security headers, shared by all frameworks
+	if s.FrameworkOptions.SecurityHeaders != nil {
+		available[common.MiddlewareSecurityHeaders] = []echo.MiddlewareFunc{echo.WrapMiddleware(s.FrameworkOptions.SecurityHeaders.Middleware())}
+	}
 
-	echoprometheusConfig := echoprometheus.MiddlewareConfig{
-		Subsystem:  strings.ReplaceAll(utils.GetAppName(), "-", "_"),
-		Registerer: prometheus.Registerer(prometheus.NewRegistry()),
+	// Response compression, shared by all frameworks
+	if s.FrameworkOptions.Compression != nil {
+		available[common.MiddlewareCompression] = []echo.MiddlewareFunc{s.compressionMiddleware()}
 	}
-	s.Server.Use(echoprometheus.NewMiddlewareWithConfig(echoprometheusConfig))
 
-	s.Server.Use(middleware.Recover())
+	if s.FrameworkOptions.OtelEnabled {
+		tracing := s.FrameworkOptions.Tracing
+		available[common.MiddlewareOtel] = []echo.MiddlewareFunc{
+			otelecho.Middleware(utils.GetAppName(),
+				otelecho.WithTracerProvider(tracing.TracerProvider),
+				otelecho.WithPropagators(tracing.GetPropagators()),
+				otelecho.WithSkipper(func(c echo.Context) bool {
+					return !tracing.Traced(c.Request().URL.Path)
+				}),
+			),
+			echo.WrapMiddleware(tracing.HeaderCaptureMiddleware()),
+		}
+	}
+
+	// OpenTelemetry HTTP server metrics
+	if s.FrameworkOptions.HTTPMetrics != nil {
+		available[common.MiddlewareHTTPMetrics] = []echo.MiddlewareFunc{s.otelMetricsMiddleware()}
+	}
+
+	if s.FrameworkOptions.StatsvizEnabled {
+		available[common.MiddlewareStatsviz] = nil
+	}
+
+	chain, chained := common.BuildMiddleware(s.WebServer, available)
+	s.Server.Pre(echo.WrapMiddleware(common.CountRequests(s.FrameworkOptions.Runtime)))
+	s.Server.Use(chained...)
 
 	s.Server.GET("/", s.mainRoute)
 	s.Server.GET("/health", s.healthRoute)
+	s.Server.GET("/ready", echo.WrapHandler(common.ReadinessHandler(s.FrameworkOptions.Runtime)))
+	s.Server.GET("/version", echo.WrapHandler(common.VersionHandler(s.FrameworkOptions)))
+	s.Server.GET(common.DashboardPath, echo.WrapHandler(s.Dashboard().Handler()))
+	s.Server.GET(common.DashboardPath+"/*", echo.WrapHandler(s.Dashboard().Handler()))
 	s.Server.GET("/logger", s.loggerRoute)
 	s.Server.GET("/framework", s.switchRoute)
+	s.Server.GET("/chain", s.chainRoute)
+	s.Server.GET("/middleware", echo.WrapHandler(common.MiddlewareHandler(s.FrameworkOptions)))
+
+	// Reverse proxy routes
+	if s.FrameworkOptions.Proxy.Enabled() {
+		proxy := echo.WrapHandler(common.NewProxyHandler(s.FrameworkOptions.Proxy, s.FrameworkOptions.Tracing, s.FrameworkOptions.Problems))
+		for _, route := range s.FrameworkOptions.Proxy.Routes {
+			s.Server.Any(route.Prefix, proxy)
+			s.Server.Any(route.Prefix+"/*", proxy)
+		}
+	}
 
 	s.Server.GET("/metrics", echoprometheus.NewHandler())
 
-	if s.FrameworkOptions.StatsvizEnabled {
+	if chain.Includes(common.MiddlewareStatsviz) {
 		// Create statsviz server and register the handlers on the router.
 		mux := http.NewServeMux()
 
@@ -83,22 +139,33 @@ func (s *Server) setup() {
 	}
 }
 
-func (s *Server) Start(ctx context.Context) {
+func (s *Server) Start(ctx context.Context) error {
 	s.MU.Lock()
 	defer s.MU.Unlock()
 
 	if s.Running {
-		slog.DebugContext(ctx, "Web server is already running")
-		return
+		utils.ComponentLogger(utils.ComponentServer).DebugContext(ctx, "Web server is already running")
+		return nil
+	}
+
+	s.setup()
+
+	// echo serves on a listener set beforehand instead of binding its own
+	listener, err := s.Listen()
+	if err != nil {
+		return err
 	}
+	s.Server.Listener = listener
 
 	go func() {
-		s.setup()
-		slog.DebugContext(ctx, "Starting server", "address", s.FrameworkOptions.ListenAddr)
-		s.Server.Start(s.FrameworkOptions.ListenAddr)
+		utils.ComponentLogger(utils.ComponentServer).DebugContext(ctx, "Starting server", "address", s.FrameworkOptions.ListenAddr)
+		if err := s.Server.Start(s.FrameworkOptions.ListenAddr); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			s.Fail(err)
+		}
 	}()
 
 	s.Running = true
+	return nil
 }
 
 // Stop gracefully stops the web server.
@@ -107,18 +174,21 @@ func (s *Server) Stop(ctx context.Context) {
 	defer s.MU.Unlock()
 
 	if !s.Running {
-		slog.DebugContext(ctx, "Web server is not running")
+		utils.ComponentLogger(utils.ComponentServer).DebugContext(ctx, "Web server is not running")
 		return
 	}
 
-	slog.InfoContext(ctx, "Stopping web server")
+	utils.ComponentLogger(utils.ComponentServer).InfoContext(ctx, "Stopping web server")
+	// End the dashboard event streams, Shutdown waits for them
+	s.Dashboard().Close()
+
 	shutdownCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
 	defer cancel()
 
 	if err := s.Server.Shutdown(shutdownCtx); err != nil {
-		slog.ErrorContext(ctx, "Error stopping web server", "error", err)
+		utils.ComponentLogger(utils.ComponentServer).ErrorContext(ctx, "Error stopping web server", "error", err)
 	} else {
-		slog.InfoContext(ctx, "Web server stopped successfully")
+		utils.ComponentLogger(utils.ComponentServer).InfoContext(ctx, "Web server stopped successfully")
 	}
 
 	s.Running = false
@@ -0,0 +1,36 @@
+package web
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/wasilak/go-hello-world/utils"
+)
+
+func TestErrorScopePerFramework(t *testing.T) {
+	for _, framework := range testFrameworks {
+		t.Run(framework, func(t *testing.T) {
+			options := testOptions(t)
+			baseURL := startTestServer(t, context.Background(), framework, options)
+
+			counter := utils.ErrorCounterVec.WithLabelValues("validation", "validation.chain_mode", framework, "/chain")
+			before := testutil.ToFloat64(counter)
+
+			resp, err := http.Get(baseURL + "/chain?mode=random")
+			require.NoError(t, err)
+			resp.Body.Close()
+
+			assert.Equal(t, before+1, testutil.ToFloat64(counter))
+
+			records := utils.RecentErrors().Snapshot(1)
+			require.Len(t, records, 1)
+			assert.Equal(t, framework, records[0].Framework)
+			assert.Equal(t, "/chain", records[0].Route)
+			assert.Equal(t, utils.ErrorCode("validation.chain_mode"), records[0].Error.Code)
+		})
+	}
+}
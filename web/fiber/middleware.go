@@ -0,0 +1,289 @@
+package fiber
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/wasilak/go-hello-world/utils"
+	"github.com/wasilak/go-hello-world/web/common"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// otelMetricsMiddleware records the OpenTelemetry HTTP server metrics for fiber
+func (s *Server) otelMetricsMiddleware() fiber.Handler {
+	metrics := s.FrameworkOptions.HTTPMetrics
+
+	return func(c *fiber.Ctx) error {
+		done := metrics.Begin(c.UserContext(), c.Method(), c.Protocol(), s.Framework)
+
+		err := c.Next()
+
+		done(c.Route().Path, responseStatus(c, err), int64(len(c.Request().Body())), responseSize(c))
+
+		return err
+	}
+}
+
+// responseSize returns the size of the response body. Streamed bodies, e.g. the dashboard
+// events, are not read as that would consume the stream; their size is unknown.
+func responseSize(c *fiber.Ctx) int64 {
+	if c.Response().IsBodyStream() {
+		return int64(max(c.Response().Header.ContentLength(), 0))
+	}
+	return int64(len(c.Response().Body()))
+}
+
+// responseStatus resolves the status code fiber will send, including for errors
+// that are only rendered by the error handler after the middleware chain
+func responseStatus(c *fiber.Ctx, err error) int {
+	if err == nil {
+		return c.Response().StatusCode()
+	}
+
+	var fiberErr *fiber.Error
+	if errors.As(err, &fiberErr) {
+		return fiberErr.Code
+	}
+
+	if appErr, ok := utils.AsAppError(err); ok {
+		return common.ProblemStatus(appErr.Type)
+	}
+
+	return fiber.StatusInternalServerError
+}
+
+// corsMiddleware sets the CORS headers and answers preflight requests with 204
+func (s *Server) corsMiddleware() fiber.Handler {
+	policy := s.FrameworkOptions.CORS
+
+	return func(c *fiber.Ctx) error {
+		result := policy.Evaluate(c.Method(), func(name string) string { return c.Get(name) })
+		result.SetHeaders(func(name, value string) { c.Set(name, value) }, func(name string) { c.Vary(name) })
+
+		if result.Preflight {
+			return c.SendStatus(fiber.StatusNoContent)
+		}
+		return c.Next()
+	}
+}
+
+// securityHeadersMiddleware sets the security headers
+func (s *Server) securityHeadersMiddleware() fiber.Handler {
+	headers := s.FrameworkOptions.SecurityHeaders
+
+	return func(c *fiber.Ctx) error {
+		headers.SetHeaders(func(name, value string) { c.Set(name, value) })
+		return c.Next()
+	}
+}
+
+// compressionMiddleware compresses the buffered response with the negotiated encoding.
+// Errors are rendered here so problem responses are compressed too, streamed bodies are
+// left as they are.
+func (s *Server) compressionMiddleware() fiber.Handler {
+	compressor := s.FrameworkOptions.Compression
+
+	return func(c *fiber.Ctx) error {
+		if err := c.Next(); err != nil {
+			if err := c.App().ErrorHandler(c, err); err != nil {
+				_ = c.SendStatus(fiber.StatusInternalServerError)
+			}
+		}
+
+		res := c.Response()
+		if res.IsBodyStream() || !compressor.Eligible(res.StatusCode(), string(res.Header.ContentType()),
+			string(res.Header.Peek(fiber.HeaderContentEncoding)), string(res.Header.Peek(fiber.HeaderContentRange))) {
+			return nil
+		}
+		c.Vary(fiber.HeaderAcceptEncoding)
+
+		body := res.Body()
+		encoding := compressor.Negotiate(c.Get(fiber.HeaderAcceptEncoding))
+		if encoding == "" || len(body) == 0 || len(body) < compressor.Config().MinSize {
+			return nil
+		}
+
+		compressed, err := compressor.Encode(encoding, body)
+		if err != nil {
+			return nil
+		}
+		res.SetBody(compressed)
+		res.Header.Set(fiber.HeaderContentEncoding, encoding)
+		return nil
+	}
+}
+
+// traceHeadersMiddleware records the captured headers on the otelfiber span
+func (s *Server) traceHeadersMiddleware() fiber.Handler {
+	tracing := s.FrameworkOptions.Tracing
+
+	return func(c *fiber.Ctx) error {
+		span := trace.SpanFromContext(c.UserContext())
+		tracing.RecordRequestHeaders(span, toHTTPHeader(c.GetReqHeaders()))
+
+		err := c.Next()
+
+		tracing.RecordResponseHeaders(span, toHTTPHeader(c.GetRespHeaders()))
+
+		return err
+	}
+}
+
+// toHTTPHeader converts fiber's header map into a canonicalized http.Header
+func toHTTPHeader(headers map[string][]string) http.Header {
+	h := make(http.Header, len(headers))
+	for name, values := range headers {
+		for _, value := range values {
+			h.Add(name, value)
+		}
+	}
+	return h
+}
+
+// errorHandler renders the errors returned by handlers, including fiber's own
+// errors such as unknown routes, as RFC 9457 problem details
+func (s *Server) errorHandler(c *fiber.Ctx, err error) error {
+	problems := s.FrameworkOptions.Problems
+	problem := problems.FromError(c.UserContext(), err, c.Path())
+
+	var fiberErr *fiber.Error
+	if errors.As(err, &fiberErr) {
+		problem = problems.StatusProblem(c.UserContext(), fiberErr.Code, fiberErr.Message, c.Path())
+		if fiberErr.Code == http.StatusRequestEntityTooLarge {
+			// fasthttp enforces the body limit before the middleware chain
+			problem = problems.BodyTooLargeProblem(c.UserContext(), c.Path())
+		}
+	}
+
+	return c.Status(problem.Status).JSON(problem, common.ProblemContentType)
+}
+
+// requestIDMiddleware resolves the request ID, stores it in the user context and returns
+// it in the response header. The incoming header is copied, fiber reuses its buffer.
+func (s *Server) requestIDMiddleware() fiber.Handler {
+	requestID := s.FrameworkOptions.RequestID
+	header := requestID.HeaderName()
+
+	return func(c *fiber.Ctx) error {
+		id := requestID.Resolve(strings.Clone(c.Get(header)))
+		c.Request().Header.Set(header, id)
+		c.Set(header, id)
+		c.SetUserContext(requestID.Start(c.UserContext(), id))
+		return c.Next()
+	}
+}
+
+// errorScopeMiddleware labels errors logged during the request with the framework and
+// route, the route is resolved lazily as it is only known once the handler is matched
+func (s *Server) errorScopeMiddleware() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		c.SetUserContext(utils.WithErrorScope(c.UserContext(), utils.ErrorScope{
+			Framework: s.Framework,
+			Route:     func() string { return c.Route().Path },
+		}))
+		return c.Next()
+	}
+}
+
+// countRequestsMiddleware counts every request, it is registered before the middleware
+// chain so the count doesn't depend on the chain
+func (s *Server) countRequestsMiddleware() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		s.FrameworkOptions.Runtime.CountRequest()
+		return c.Next()
+	}
+}
+
+// faultMiddleware injects the faults configured through the admin API
+func (s *Server) faultMiddleware() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if status := s.FrameworkOptions.Runtime.InjectFault(c.UserContext(), c.Path()); status != 0 {
+			problem := s.FrameworkOptions.Problems.FaultProblem(c.UserContext(), status, c.Path())
+			return c.Status(status).JSON(problem, common.ProblemContentType)
+		}
+		return c.Next()
+	}
+}
+
+// rateLimitMiddleware applies the rate limits, rejected requests get a 429 problem response
+func (s *Server) rateLimitMiddleware() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		decision := s.FrameworkOptions.RateLimit.Allow(common.RateLimitRequest{
+			Path:     c.Path(),
+			ClientIP: c.IP(),
+			Header:   func(name string) string { return c.Get(name) },
+		})
+		decision.SetHeaders(func(name, value string) { c.Set(name, value) })
+
+		if !decision.Allowed {
+			problem := s.FrameworkOptions.Problems.RateLimitProblem(c.UserContext(), decision, c.Path())
+			return c.Status(problem.Status).JSON(problem, common.ProblemContentType)
+		}
+		return c.Next()
+	}
+}
+
+// concurrencyMiddleware limits the requests served concurrently, shed requests get a 503
+// problem response
+func (s *Server) concurrencyMiddleware() fiber.Handler {
+	limiter := s.FrameworkOptions.Concurrency
+
+	return func(c *fiber.Ctx) error {
+		release, reason := limiter.Acquire(c.UserContext(), c.Path())
+		if release == nil {
+			limiter.Shed(s.Framework, reason)
+			c.Set(fiber.HeaderRetryAfter, limiter.RetryAfterSeconds())
+			problem := s.FrameworkOptions.Problems.OverloadProblem(c.UserContext(), reason, c.Path())
+			return c.Status(problem.Status).JSON(problem, common.ProblemContentType)
+		}
+		defer release()
+
+		return c.Next()
+	}
+}
+
+// accessLogMiddleware writes the common access log entries. Errors are rendered here
+// so the entry has the final status and body.
+func (s *Server) accessLogMiddleware() fiber.Handler {
+	accessLog := s.FrameworkOptions.AccessLog
+
+	return func(c *fiber.Ctx) error {
+		if accessLog.Excluded(c.Path()) {
+			return c.Next()
+		}
+
+		start := time.Now()
+
+		if err := c.Next(); err != nil {
+			if err := c.App().ErrorHandler(c, err); err != nil {
+				_ = c.SendStatus(fiber.StatusInternalServerError)
+			}
+		}
+
+		entry := common.AccessLogEntry{
+			Method:    c.Method(),
+			Path:      c.Path(),
+			Route:     c.Route().Path,
+			Status:    c.Response().StatusCode(),
+			Bytes:     responseSize(c),
+			Latency:   time.Since(start),
+			ClientIP:  c.IP(),
+			UserAgent: c.Get(fiber.HeaderUserAgent),
+			Headers:   toHTTPHeader(c.GetReqHeaders()),
+		}
+		if limit := accessLog.RequestBodyLimit(); limit > 0 {
+			entry.RequestBody = common.NewBodyCapture(limit)
+			_, _ = entry.RequestBody.Write(c.Body())
+		}
+		if limit := accessLog.ResponseBodyLimit(); limit > 0 && !c.Response().IsBodyStream() {
+			entry.ResponseBody = common.NewBodyCapture(limit)
+			_, _ = entry.ResponseBody.Write(c.Response().Body())
+		}
+		accessLog.Log(c.UserContext(), entry)
+
+		return nil
+	}
+}
@@ -1,8 +1,12 @@
 package fiber
 
 import (
+	"bufio"
+	"context"
 	"net/http"
+	"net/url"
 
+	"github.com/gofiber/adaptor/v2"
 	"github.com/gofiber/fiber/v2"
 	"github.com/valyala/fasthttp/fasthttpadaptor"
 	"github.com/wasilak/go-hello-world/utils"
@@ -13,43 +17,54 @@ func (s *Server) mainRoute(c *fiber.Ctx) error {
 	// Convert fasthttp.Request to http.Request
 	req := new(http.Request)
 	fasthttpadaptor.ConvertRequest(c.Context(), req, false)
-	response := s.SetMainResponse(c.Context(), req)
+	response := s.SetMainResponse(c.UserContext(), req)
 
-	if err := c.JSON(response); err != nil {
-		// Use the new standardized error types
-		appErr := utils.WrapError(err, utils.RuntimeError, "failed to encode main response in fiber")
-		appErr.AddContext("path", c.Path())
-		appErr.LogError(c.Context())
-		// Return the original error to be handled by fiber
-		return err
-	}
-	return nil
+	return s.render(c, req, response)
 }
 
 func (s *Server) healthRoute(c *fiber.Ctx) error {
-	response := common.HealthResponse{Status: "ok"}
+	// Convert fasthttp.Request to http.Request
+	req := new(http.Request)
+	fasthttpadaptor.ConvertRequest(c.Context(), req, false)
 
-	if err := c.JSON(response); err != nil {
-		// Use the new standardized error types
-		appErr := utils.WrapError(err, utils.RuntimeError, "failed to encode health response in fiber")
-		appErr.AddContext("path", c.Path())
-		appErr.LogError(c.Context())
-		// Return the original error to be handled by fiber
-		return err
+	return s.render(c, req, common.HealthResponse{Status: "ok"})
+}
+
+// render writes the response in the format negotiated for the converted request
+func (s *Server) render(c *fiber.Ctx, req *http.Request, response any) error {
+	body, contentType, err := common.RenderResponse(req, response)
+	if err != nil {
+		appErr, _ := utils.AsAppError(err)
+		appErr.AddContext(utils.ContextPath, c.Path())
+		appErr.LogError(c.UserContext())
+		// Return the error to be handled by fiber
+		return appErr
 	}
-	return nil
+
+	c.Vary(fiber.HeaderAccept)
+	c.Set(fiber.HeaderContentType, contentType)
+	return c.Status(http.StatusOK).Send(body)
 }
 
 func (s *Server) loggerRoute(c *fiber.Ctx) error {
 	levelParam := c.Query("level")
-	response := s.SetLogLevelResponse(c.Context(), levelParam)
+	response, appErr := s.SetLogLevelResponse(c.UserContext(), common.LogLevelRequest{
+		Logger: c.Query("logger"),
+		Level:  levelParam,
+		TTL:    c.Query("ttl"),
+	})
+	if appErr != nil {
+		appErr.AddContext(utils.ContextPath, c.Path())
+		appErr.LogError(c.UserContext())
+		return appErr
+	}
 
 	if err := c.JSON(response); err != nil {
 		// Use the new standardized error types
 		appErr := utils.WrapError(err, utils.RuntimeError, "failed to encode logger response in fiber")
-		appErr.AddContext("path", c.Path())
-		appErr.AddContext("log_level", levelParam)
-		appErr.LogError(c.Context())
+		appErr.AddContext(utils.ContextPath, c.Path())
+		appErr.AddContext(utils.ContextLogLevel, levelParam)
+		appErr.LogError(c.UserContext())
 		// Return the original error to be handled by fiber
 		return err
 	}
@@ -58,17 +73,85 @@ func (s *Server) loggerRoute(c *fiber.Ctx) error {
 
 func (s *Server) switchRoute(c *fiber.Ctx) error {
 	nameParam := c.Query("name")
-	response := s.SetFrameworkResponse(c.Context(), nameParam)
+	response, appErr := s.SetFrameworkResponse(c.UserContext(), nameParam)
+	if appErr != nil {
+		appErr.AddContext(utils.ContextPath, c.Path())
+		appErr.LogError(c.UserContext())
+		return appErr
+	}
 
 	c.Set("Content-Type", "application/json")
 	if err := c.JSON(response); err != nil {
 		// Use the new standardized error types
 		appErr := utils.WrapError(err, utils.RuntimeError, "failed to encode switch response in fiber")
-		appErr.AddContext("path", c.Path())
-		appErr.AddContext("framework", nameParam)
-		appErr.LogError(c.Context())
+		appErr.AddContext(utils.ContextPath, c.Path())
+		appErr.AddContext(utils.ContextFramework, nameParam)
+		appErr.LogError(c.UserContext())
+		// Return the original error to be handled by fiber
+		return err
+	}
+	return nil
+}
+
+func (s *Server) chainRoute(c *fiber.Ctx) error {
+	query, _ := url.ParseQuery(string(c.Request().URI().QueryString()))
+	request, appErr := s.FrameworkOptions.Chain.ParseChainRequest(query, c.Get(common.ChainDepthHeader))
+	if appErr != nil {
+		appErr.AddContext(utils.ContextPath, c.Path())
+		appErr.LogError(c.UserContext())
+		return appErr
+	}
+
+	response := s.SetChainResponse(c.UserContext(), request)
+
+	if err := c.JSON(response); err != nil {
+		// Use the new standardized error types
+		appErr := utils.WrapError(err, utils.RuntimeError, "failed to encode chain response in fiber")
+		appErr.AddContext(utils.ContextPath, c.Path())
+		appErr.LogError(c.UserContext())
 		// Return the original error to be handled by fiber
 		return err
 	}
 	return nil
 }
+
+// proxyRoute adapts the common reverse proxy handler, passing the otelfiber span
+// context so the upstream call is traced as a child of the server span
+func (s *Server) proxyRoute(handler http.Handler) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		ctx := c.UserContext()
+		return adaptor.HTTPHandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			handler.ServeHTTP(w, r.WithContext(ctx))
+		})(c)
+	}
+}
+
+// dashboardRoute serves the dashboard page. The adaptor adds the headers of the handler to
+// the ones of the middleware, so the policy of the security headers is dropped when the
+// page sets its own, browsers would enforce both.
+func (s *Server) dashboardRoute() fiber.Handler {
+	handler := adaptor.HTTPHandler(s.Dashboard().Handler())
+
+	return func(c *fiber.Ctx) error {
+		if s.Dashboard().ContentSecurityPolicy(string(c.Request().Host())) != "" {
+			c.Response().Header.Del(fiber.HeaderContentSecurityPolicy)
+		}
+		return handler(c)
+	}
+}
+
+// dashboardEvents streams the dashboard state. The stream ends before the write timeout
+// of fasthttp, which cannot be lifted per request, and the browser reconnects.
+func (s *Server) dashboardEvents(c *fiber.Ctx) error {
+	header := http.Header{}
+	common.SetEventStreamHeaders(header)
+	for name := range header {
+		c.Set(name, header.Get(name))
+	}
+
+	dashboard := s.Dashboard()
+	c.Context().SetBodyStreamWriter(func(w *bufio.Writer) {
+		_ = dashboard.Stream(context.Background(), w, w.Flush, dashboard.StreamLifetime())
+	})
+	return nil
+}
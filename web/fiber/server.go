@@ -2,22 +2,20 @@ package fiber
 
 import (
 	"context"
-	"log/slog"
+	"math"
 	"net/http"
-	"os"
+	"time"
 
 	"github.com/arl/statsviz"
 	"github.com/gofiber/adaptor/v2"
 	"github.com/gofiber/fiber/v2"
-	"github.com/gofiber/fiber/v2/middleware/compress"
+	"github.com/gofiber/fiber/v2/middleware/recover"
 	"github.com/prometheus/client_golang/prometheus/collectors"
 	"github.com/wasilak/go-hello-world/utils"
 	"github.com/wasilak/go-hello-world/web/common"
 
 	"github.com/ansrivas/fiberprometheus/v2"
 	"github.com/gofiber/contrib/otelfiber/v2"
-
-	slogfiber "github.com/samber/slog-fiber"
 )
 
 type Server struct {
@@ -26,10 +24,27 @@ type Server struct {
 }
 
 func (s *Server) setup(ctx context.Context) {
+	server := s.FrameworkOptions.Server
+
+	// fiber applies its 4 MiB default to a zero body limit, which means no limit
+	bodyLimit := int(server.MaxBodyBytes)
+	if bodyLimit == 0 {
+		bodyLimit = math.MaxInt
+	}
 
 	// Initialize Fiber app
 	s.Server = fiber.New(fiber.Config{
 		DisableStartupMessage: true, // Disable the Fiber banner
+		ErrorHandler:          s.errorHandler,
+
+		// Server timeouts and limits, fasthttp has no read header timeout and bounds the
+		// headers with its read buffer
+		ReadTimeout:      server.ReadTimeout,
+		WriteTimeout:     server.WriteTimeout,
+		IdleTimeout:      server.IdleTimeout,
+		ReadBufferSize:   server.MaxHeaderBytes,
+		BodyLimit:        bodyLimit,
+		DisableKeepalive: server.DisableKeepAlive,
 	})
 
 	// Register Go runtime metrics only if not already registered
@@ -40,30 +55,96 @@ func (s *Server) setup(ctx context.Context) {
 	common.RegisterCollectorIfNotRegistered(goCollector)
 	common.RegisterCollectorIfNotRegistered(processCollector)
 
-	// Prometheus Middleware
+	// Prometheus metrics endpoint
 	prometheus := fiberprometheus.New(utils.GetAppName())
 	prometheus.RegisterAt(s.Server, "/metrics")
-	s.Server.Use(prometheus.Middleware)
+
+	// Middleware provided by fiber, applied in the configured order. The body limit is
+	// enforced by the server.
+	available := map[string][]fiber.Handler{
+		common.MiddlewareRecovery:    {recover.New()},
+		common.MiddlewarePrometheus:  {prometheus.Middleware},
+		common.MiddlewareRequestID:   {s.requestIDMiddleware()},
+		common.MiddlewareErrorScope:  {s.errorScopeMiddleware()},
+		common.MiddlewareAccessLog:   {s.accessLogMiddleware()},
+		common.MiddlewareRateLimit:   {s.rateLimitMiddleware()},
+		common.MiddlewareConcurrency: {s.concurrencyMiddleware()},
+		common.MiddlewareFault:       {s.faultMiddleware()},
+	}
+
+	// CORS headers and preflight responses, shared by all frameworks
+	if s.FrameworkOptions.CORS != nil {
+		available[common.MiddlewareCORS] = []fiber.Handler{s.corsMiddleware()}
+	}
+
+	// Security headers, shared by all frameworks
+	if s.FrameworkOptions.SecurityHeaders != nil {
+		available[common.MiddlewareSecurityHeaders] = []fiber.Handler{s.securityHeadersMiddleware()}
+	}
+
+	// Response compression, shared by all frameworks
+	if s.FrameworkOptions.Compression != nil {
+		available[common.MiddlewareCompression] = []fiber.Handler{s.compressionMiddleware()}
+	}
+
+	// OpenTelemetry HTTP server metrics
+	if s.FrameworkOptions.HTTPMetrics != nil {
+		available[common.MiddlewareHTTPMetrics] = []fiber.Handler{s.otelMetricsMiddleware()}
+	}
 
 	// OpenTelemetry Middleware
 	if s.FrameworkOptions.OtelEnabled {
-		s.Server.Use(otelfiber.Middleware())
+		tracing := s.FrameworkOptions.Tracing
+		available[common.MiddlewareOtel] = []fiber.Handler{
+			otelfiber.Middleware(
+				otelfiber.WithTracerProvider(tracing.TracerProvider),
+				otelfiber.WithPropagators(tracing.GetPropagators()),
+				otelfiber.WithSpanNameFormatter(func(c *fiber.Ctx) string {
+					return tracing.SpanName(c.Method(), c.Route().Path)
+				}),
+				otelfiber.WithNext(func(c *fiber.Ctx) bool {
+					return !tracing.Traced(c.Path())
+				}),
+			),
+			s.traceHeadersMiddleware(),
+		}
 	}
 
-	// Gzip Middleware
-	s.Server.Use(compress.New())
+	if s.FrameworkOptions.StatsvizEnabled {
+		available[common.MiddlewareStatsviz] = nil
+	}
 
-	// Custom Logging Middleware
-	s.Server.Use(slogfiber.New(slog.Default()))
+	chain, chained := common.BuildMiddleware(s.WebServer, available)
+	s.Server.Use(s.countRequestsMiddleware())
+	for _, handler := range chained {
+		s.Server.Use(handler)
+	}
 
 	// Define Routes
 	s.Server.Get("/", s.mainRoute)
 	s.Server.Get("/health", s.healthRoute)
+	s.Server.Get("/ready", adaptor.HTTPHandler(common.ReadinessHandler(s.FrameworkOptions.Runtime)))
+	s.Server.Get("/version", adaptor.HTTPHandler(common.VersionHandler(s.FrameworkOptions)))
+	// the adaptor buffers whole responses, so the event stream is written natively
+	s.Server.Get(common.DashboardEventsPath, s.dashboardEvents)
+	s.Server.Get(common.DashboardPath, s.dashboardRoute())
+	s.Server.Get(common.DashboardPath+"/*", s.dashboardRoute())
 	s.Server.Get("/logger", s.loggerRoute)
 	s.Server.Get("/framework", s.switchRoute)
+	s.Server.Get("/chain", s.chainRoute)
+	s.Server.Get("/middleware", adaptor.HTTPHandler(common.MiddlewareHandler(s.FrameworkOptions)))
+
+	// Reverse proxy routes
+	if s.FrameworkOptions.Proxy.Enabled() {
+		proxy := s.proxyRoute(common.NewProxyHandler(s.FrameworkOptions.Proxy, s.FrameworkOptions.Tracing, s.FrameworkOptions.Problems))
+		for _, route := range s.FrameworkOptions.Proxy.Routes {
+			s.Server.All(route.Prefix, proxy)
+			s.Server.All(route.Prefix+"/*", proxy)
+		}
+	}
 
 	// Optional Statviz
-	if s.FrameworkOptions.StatsvizEnabled {
+	if chain.Includes(common.MiddlewareStatsviz) {
 		mux := http.NewServeMux()
 
 		// Register statsviz handlerson the mux.
@@ -74,28 +155,34 @@ func (s *Server) setup(ctx context.Context) {
 		s.Server.Get("/debug/statsviz/*", adaptor.HTTPHandler(mux))
 	}
 
-	slog.DebugContext(ctx, "Starting server", "address", s.FrameworkOptions.ListenAddr)
+	utils.ComponentLogger(utils.ComponentServer).DebugContext(ctx, "Starting server", "address", s.FrameworkOptions.ListenAddr)
 }
 
-func (s *Server) Start(ctx context.Context) {
+func (s *Server) Start(ctx context.Context) error {
 	s.MU.Lock()
 	defer s.MU.Unlock()
 
 	if s.Running {
-		slog.DebugContext(ctx, "Web server is already running")
-		return
+		utils.ComponentLogger(utils.ComponentServer).DebugContext(ctx, "Web server is already running")
+		return nil
+	}
+
+	s.setup(ctx)
+
+	listener, err := s.Listen()
+	if err != nil {
+		return err
 	}
 
 	go func() {
-		s.setup(ctx)
-		slog.DebugContext(ctx, "Starting server", "address", s.FrameworkOptions.ListenAddr)
-		if err := s.Server.Listen(s.FrameworkOptions.ListenAddr); err != nil {
-			slog.ErrorContext(ctx, "Server exited with error", "error", err)
-			os.Exit(1)
+		utils.ComponentLogger(utils.ComponentServer).DebugContext(ctx, "Starting server", "address", s.FrameworkOptions.ListenAddr)
+		if err := s.Server.Listener(listener); err != nil {
+			s.Fail(err)
 		}
 	}()
 
 	s.Running = true
+	return nil
 }
 
 // Stop gracefully stops the web server.
@@ -104,18 +191,24 @@ func (s *Server) Stop(ctx context.Context) {
 	defer s.MU.Unlock()
 
 	if !s.Running {
-		slog.DebugContext(ctx, "Web server is not running")
+		utils.ComponentLogger(utils.ComponentServer).DebugContext(ctx, "Web server is not running")
 		return
 	}
 
-	slog.InfoContext(ctx, "Stopping web server")
-	// shutdownCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
-	// defer cancel()
+	utils.ComponentLogger(utils.ComponentServer).InfoContext(ctx, "Stopping web server")
+
+	// End the dashboard event streams, Shutdown waits for them
+	s.Dashboard().Close()
+
+	// fasthttp waits for keep-alive connections which never sent a request, the timeout
+	// keeps a framework switch from blocking on them
+	shutdownCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
 
-	if err := s.Server.Shutdown(); err != nil {
-		slog.ErrorContext(ctx, "Error stopping web server", "error", err)
+	if err := s.Server.ShutdownWithContext(shutdownCtx); err != nil {
+		utils.ComponentLogger(utils.ComponentServer).ErrorContext(ctx, "Error stopping web server", "error", err)
 	} else {
-		slog.InfoContext(ctx, "Web server stopped successfully")
+		utils.ComponentLogger(utils.ComponentServer).InfoContext(ctx, "Web server stopped successfully")
 	}
 
 	s.Running = false
@@ -0,0 +1,286 @@
+package gin
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/wasilak/go-hello-world/utils"
+	"github.com/wasilak/go-hello-world/web/common"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// otelMetricsMiddleware records the OpenTelemetry HTTP server metrics for gin
+func (s *Server) otelMetricsMiddleware() gin.HandlerFunc {
+	metrics := s.FrameworkOptions.HTTPMetrics
+
+	return func(c *gin.Context) {
+		done := metrics.Begin(c.Request.Context(), c.Request.Method, common.RequestScheme(c.Request), s.Framework)
+
+		c.Next()
+
+		done(c.FullPath(), c.Writer.Status(), c.Request.ContentLength, int64(max(c.Writer.Size(), 0)))
+	}
+}
+
+// traceHeadersMiddleware records the captured headers on the otelgin span
+func (s *Server) traceHeadersMiddleware() gin.HandlerFunc {
+	tracing := s.FrameworkOptions.Tracing
+
+	return func(c *gin.Context) {
+		span := trace.SpanFromContext(c.Request.Context())
+		tracing.RecordRequestHeaders(span, c.Request.Header)
+
+		c.Next()
+
+		tracing.RecordResponseHeaders(span, c.Writer.Header())
+	}
+}
+
+// problemMiddleware renders the last error attached with c.Error as RFC 9457 problem
+// details, unless the handler already wrote a response
+func (s *Server) problemMiddleware() gin.HandlerFunc {
+	problems := s.FrameworkOptions.Problems
+
+	return func(c *gin.Context) {
+		c.Next()
+
+		if len(c.Errors) == 0 || c.Writer.Written() {
+			return
+		}
+
+		abortWithProblem(c, problems.FromError(c.Request.Context(), c.Errors.Last().Err, c.Request.URL.Path))
+	}
+}
+
+// abortWithProblem writes the problem with the application/problem+json content type
+func abortWithProblem(c *gin.Context, problem common.Problem) {
+	c.Header("Content-Type", common.ProblemContentType)
+	c.AbortWithStatusJSON(problem.Status, problem)
+}
+
+// requestIDMiddleware resolves the request ID, stores it in the request context and
+// returns it in the response header
+func (s *Server) requestIDMiddleware() gin.HandlerFunc {
+	requestID := s.FrameworkOptions.RequestID
+	header := requestID.HeaderName()
+
+	return func(c *gin.Context) {
+		id := requestID.Resolve(c.GetHeader(header))
+		c.Request.Header.Set(header, id)
+		c.Header(header, id)
+		c.Request = c.Request.WithContext(requestID.Start(c.Request.Context(), id))
+		c.Next()
+	}
+}
+
+// errorScopeMiddleware labels errors logged during the request with the framework and route
+func (s *Server) errorScopeMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := utils.WithErrorScope(c.Request.Context(), utils.ErrorScope{Framework: s.Framework, Route: c.FullPath})
+		c.Request = c.Request.WithContext(ctx)
+		c.Next()
+	}
+}
+
+// faultMiddleware injects the faults configured through the admin API
+func (s *Server) faultMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := c.Request.Context()
+		if status := s.FrameworkOptions.Runtime.InjectFault(ctx, c.Request.URL.Path); status != 0 {
+			abortWithProblem(c, s.FrameworkOptions.Problems.FaultProblem(ctx, status, c.Request.URL.Path))
+			return
+		}
+		c.Next()
+	}
+}
+
+// bodyLimitMiddleware rejects requests declaring a body over the limit with a 413 problem
+// response and caps the body of the others
+func (s *Server) bodyLimitMiddleware() gin.HandlerFunc {
+	limit := s.FrameworkOptions.Server.MaxBodyBytes
+
+	return func(c *gin.Context) {
+		if limit == 0 {
+			c.Next()
+			return
+		}
+		if c.Request.ContentLength > limit {
+			abortWithProblem(c, s.FrameworkOptions.Problems.BodyTooLargeProblem(c.Request.Context(), c.Request.URL.Path))
+			return
+		}
+		c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, limit)
+		c.Next()
+	}
+}
+
+// rateLimitMiddleware applies the rate limits, rejected requests get a 429 problem response
+func (s *Server) rateLimitMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		decision := s.FrameworkOptions.RateLimit.Allow(common.RateLimitRequest{
+			Path:     c.Request.URL.Path,
+			ClientIP: common.ClientIP(c.Request.RemoteAddr),
+			Header:   c.GetHeader,
+		})
+		decision.SetHeaders(c.Header)
+
+		if !decision.Allowed {
+			abortWithProblem(c, s.FrameworkOptions.Problems.RateLimitProblem(c.Request.Context(), decision, c.Request.URL.Path))
+			return
+		}
+		c.Next()
+	}
+}
+
+// concurrencyMiddleware limits the requests served concurrently, shed requests get a 503
+// problem response
+func (s *Server) concurrencyMiddleware() gin.HandlerFunc {
+	limiter := s.FrameworkOptions.Concurrency
+
+	return func(c *gin.Context) {
+		release, reason := limiter.Acquire(c.Request.Context(), c.Request.URL.Path)
+		if release == nil {
+			limiter.Shed(s.Framework, reason)
+			c.Header("Retry-After", limiter.RetryAfterSeconds())
+			abortWithProblem(c, s.FrameworkOptions.Problems.OverloadProblem(c.Request.Context(), reason, c.Request.URL.Path))
+			return
+		}
+		defer release()
+
+		c.Next()
+	}
+}
+
+// accessLogMiddleware writes the common access log entries
+func (s *Server) accessLogMiddleware() gin.HandlerFunc {
+	accessLog := s.FrameworkOptions.AccessLog
+
+	return func(c *gin.Context) {
+		if accessLog.Excluded(c.Request.URL.Path) {
+			c.Next()
+			return
+		}
+
+		start := time.Now()
+
+		entry := common.AccessLogEntry{
+			Method:    c.Request.Method,
+			Path:      c.Request.URL.Path,
+			ClientIP:  common.ClientIP(c.Request.RemoteAddr),
+			UserAgent: c.Request.UserAgent(),
+			Headers:   c.Request.Header,
+		}
+		if limit := accessLog.RequestBodyLimit(); limit > 0 && c.Request.Body != nil {
+			entry.RequestBody = common.NewBodyCapture(limit)
+			c.Request.Body = entry.RequestBody.Tee(c.Request.Body)
+		}
+		if limit := accessLog.ResponseBodyLimit(); limit > 0 {
+			entry.ResponseBody = common.NewBodyCapture(limit)
+			c.Writer = &bodyCaptureWriter{ResponseWriter: c.Writer, body: entry.ResponseBody}
+		}
+
+		c.Next()
+
+		entry.Route = c.FullPath()
+		entry.Status = c.Writer.Status()
+		entry.Bytes = int64(max(c.Writer.Size(), 0))
+		entry.Latency = time.Since(start)
+		accessLog.Log(c.Request.Context(), entry)
+	}
+}
+
+// bodyCaptureWriter captures the response body for the access log
+type bodyCaptureWriter struct {
+	gin.ResponseWriter
+	body *common.BodyCapture
+}
+
+func (w *bodyCaptureWriter) Write(b []byte) (int, error) {
+	n, err := w.ResponseWriter.Write(b)
+	_, _ = w.body.Write(b[:n])
+	return n, err
+}
+
+func (w *bodyCaptureWriter) WriteString(s string) (int, error) {
+	n, err := w.ResponseWriter.WriteString(s)
+	_, _ = w.body.Write([]byte(s[:n]))
+	return n, err
+}
+
+// corsMiddleware sets the CORS headers and answers preflight requests with 204
+func (s *Server) corsMiddleware() gin.HandlerFunc {
+	policy := s.FrameworkOptions.CORS
+
+	return func(c *gin.Context) {
+		result := policy.Evaluate(c.Request.Method, c.GetHeader)
+		result.SetHeaders(c.Header, func(name string) { common.AddVary(c.Writer.Header(), name) })
+
+		if result.Preflight {
+			c.AbortWithStatus(http.StatusNoContent)
+			return
+		}
+		c.Next()
+	}
+}
+
+// securityHeadersMiddleware sets the security headers
+func (s *Server) securityHeadersMiddleware() gin.HandlerFunc {
+	headers := s.FrameworkOptions.SecurityHeaders
+
+	return func(c *gin.Context) {
+		headers.SetHeaders(c.Header)
+		c.Next()
+	}
+}
+
+// compressionMiddleware compresses the response with the negotiated encoding
+func (s *Server) compressionMiddleware() gin.HandlerFunc {
+	compressor := s.FrameworkOptions.Compression
+
+	return func(c *gin.Context) {
+		// upgraded connections, e.g. the statsviz websocket, are hijacked
+		if c.Request.Header.Get("Upgrade") != "" {
+			c.Next()
+			return
+		}
+
+		writer := c.Writer
+		compress := compressor.NewResponseWriter(writer, compressor.Negotiate(c.Request.Header.Get("Accept-Encoding")))
+		c.Writer = &compressWriter{ResponseWriter: writer, compress: compress}
+		defer func() {
+			// a panicking handler leaves the response to the recovery middleware
+			c.Writer = writer
+			if p := recover(); p != nil {
+				panic(p)
+			}
+		}()
+
+		c.Next()
+
+		_ = compress.Close()
+	}
+}
+
+// compressWriter writes the response through the common compressing writer, gin keeps
+// track of the status and of the compressed size
+type compressWriter struct {
+	gin.ResponseWriter
+	compress *common.CompressResponseWriter
+}
+
+func (w *compressWriter) WriteHeader(code int) {
+	w.ResponseWriter.WriteHeader(code)
+	w.compress.WriteHeader(code)
+}
+
+func (w *compressWriter) Write(b []byte) (int, error) {
+	return w.compress.Write(b)
+}
+
+func (w *compressWriter) WriteString(s string) (int, error) {
+	return w.compress.Write([]byte(s))
+}
+
+func (w *compressWriter) Flush() {
+	w.compress.Flush()
+}
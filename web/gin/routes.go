@@ -4,6 +4,7 @@ import (
 	"net/http"
 
 	"github.com/gin-gonic/gin"
+	"github.com/wasilak/go-hello-world/utils"
 	"github.com/wasilak/go-hello-world/web/common"
 )
 
@@ -11,19 +12,43 @@ func (s *Server) mainRoute(c *gin.Context) {
 	ctx := c.Request.Context()
 	response := s.SetMainResponse(ctx, c.Request)
 
-	// Gin automatically handles JSON marshaling errors internally
-	c.JSON(http.StatusOK, response)
+	s.render(c, response)
 }
 
 func (s *Server) healthRoute(c *gin.Context) {
-	// Gin automatically handles JSON marshaling errors internally
-	c.JSON(http.StatusOK, common.HealthResponse{Status: "ok"})
+	s.render(c, common.HealthResponse{Status: "ok"})
+}
+
+// render writes the response in the format negotiated for the request
+func (s *Server) render(c *gin.Context, response any) {
+	body, contentType, err := common.RenderResponse(c.Request, response)
+	if err != nil {
+		appErr, _ := utils.AsAppError(err)
+		appErr.AddContext(utils.ContextPath, c.FullPath())
+		appErr.LogError(c.Request.Context())
+		_ = c.Error(appErr)
+		c.Abort()
+		return
+	}
+
+	c.Writer.Header().Add("Vary", "Accept")
+	c.Data(http.StatusOK, contentType, body)
 }
 
 func (s *Server) loggerRoute(c *gin.Context) {
 	ctx := c.Request.Context()
-	levelParam := c.Query("level")
-	response := s.SetLogLevelResponse(ctx, levelParam)
+	response, appErr := s.SetLogLevelResponse(ctx, common.LogLevelRequest{
+		Logger: c.Query("logger"),
+		Level:  c.Query("level"),
+		TTL:    c.Query("ttl"),
+	})
+	if appErr != nil {
+		appErr.AddContext(utils.ContextPath, c.FullPath())
+		appErr.LogError(ctx)
+		_ = c.Error(appErr)
+		c.Abort()
+		return
+	}
 
 	// Gin automatically handles JSON marshaling errors internally
 	c.JSON(http.StatusOK, response)
@@ -32,8 +57,36 @@ func (s *Server) loggerRoute(c *gin.Context) {
 func (s *Server) switchRoute(c *gin.Context) {
 	ctx := c.Request.Context()
 	nameParam := c.Query("name")
-	response := s.SetFrameworkResponse(ctx, nameParam)
+	response, appErr := s.SetFrameworkResponse(ctx, nameParam)
+	if appErr != nil {
+		appErr.AddContext(utils.ContextPath, c.FullPath())
+		appErr.LogError(ctx)
+		_ = c.Error(appErr)
+		c.Abort()
+		return
+	}
 
 	// Gin automatically handles JSON marshaling errors internally
 	c.JSON(http.StatusOK, response)
 }
+
+func (s *Server) chainRoute(c *gin.Context) {
+	ctx := c.Request.Context()
+	request, appErr := s.FrameworkOptions.Chain.ParseChainRequest(c.Request.URL.Query(), c.GetHeader(common.ChainDepthHeader))
+	if appErr != nil {
+		appErr.AddContext(utils.ContextPath, c.FullPath())
+		appErr.LogError(ctx)
+		_ = c.Error(appErr)
+		c.Abort()
+		return
+	}
+
+	response := s.SetChainResponse(ctx, request)
+
+	// Gin automatically handles JSON marshaling errors internally
+	c.JSON(http.StatusOK, response)
+}
+
+func (s *Server) notFoundRoute(c *gin.Context) {
+	abortWithProblem(c, s.FrameworkOptions.Problems.StatusProblem(c.Request.Context(), http.StatusNotFound, "", c.Request.URL.Path))
+}
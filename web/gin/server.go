@@ -2,16 +2,12 @@ package gin
 
 import (
 	"context"
-	"log/slog"
 	"net/http"
-	"os"
 	"strings"
 
 	"github.com/arl/statsviz"
-	"github.com/gin-contrib/gzip"
 	"github.com/gin-gonic/gin"
 	"github.com/prometheus/client_golang/prometheus/collectors"
-	sloggin "github.com/samber/slog-gin"
 	"github.com/wasilak/go-hello-world/utils"
 	"github.com/wasilak/go-hello-world/web/common"
 	ginprometheus "github.com/zsais/go-gin-prometheus"
@@ -28,7 +24,7 @@ var p *ginprometheus.Prometheus
 type slogWriter struct{}
 
 func (sw slogWriter) Write(p []byte) (n int, err error) {
-	slog.Default().Info(string(p))
+	utils.ComponentLogger(utils.ComponentRouter).Info(string(p))
 	return len(p), nil
 }
 
@@ -43,26 +39,74 @@ func (s *Server) setup() {
 	common.RegisterCollectorIfNotRegistered(goCollector)
 	common.RegisterCollectorIfNotRegistered(processCollector)
 
-	// Create a Gin router
-	r := gin.Default()
+	// Create a Gin router, its logger and recovery are in the middleware chain
+	r := gin.New()
 
-	// Prometheus Middleware
+	// Prometheus metrics endpoint, registered before the middleware like the exporter does
 	if p == nil {
 		p = ginprometheus.NewPrometheus(strings.ReplaceAll(utils.GetAppName(), "-", "_"))
 	}
-	p.Use(r)
+	p.SetMetricsPath(r)
+
+	// Middleware provided by gin, applied in the configured order
+	available := map[string][]gin.HandlerFunc{
+		common.MiddlewareRecovery:    {gin.Recovery()},
+		common.MiddlewarePrometheus:  {p.HandlerFunc()},
+		common.MiddlewareRequestID:   {s.requestIDMiddleware()},
+		common.MiddlewareErrorScope:  {s.errorScopeMiddleware()},
+		common.MiddlewareAccessLog:   {s.accessLogMiddleware()},
+		common.MiddlewareRouterLog:   {gin.Logger()},
+		common.MiddlewareBodyLimit:   {s.bodyLimitMiddleware()},
+		common.MiddlewareRateLimit:   {s.rateLimitMiddleware()},
+		common.MiddlewareConcurrency: {s.concurrencyMiddleware()},
+		common.MiddlewareFault:       {s.faultMiddleware()},
+	}
+
+	// CORS headers and preflight responses, shared by all frameworks
+	if s.FrameworkOptions.CORS != nil {
+		available[common.MiddlewareCORS] = []gin.HandlerFunc{s.corsMiddleware()}
+	}
+
+	// Security headers, shared by all frameworks
+	if s.FrameworkOptions.SecurityHeaders != nil {
+		available[common.MiddlewareSecurityHeaders] = []gin.HandlerFunc{s.securityHeadersMiddleware()}
+	}
+
+	// Response compression, shared by all frameworks
+	if s.FrameworkOptions.Compression != nil {
+		available[common.MiddlewareCompression] = []gin.HandlerFunc{s.compressionMiddleware()}
+	}
+
+	// OpenTelemetry HTTP server metrics
+	if s.FrameworkOptions.HTTPMetrics != nil {
+		available[common.MiddlewareHTTPMetrics] = []gin.HandlerFunc{s.otelMetricsMiddleware()}
+	}
 
 	// OpenTelemetry Middleware
 	if s.FrameworkOptions.OtelEnabled {
-		r.Use(otelgin.Middleware(utils.GetAppName(), otelgin.WithTracerProvider(s.FrameworkOptions.TraceProvider)))
+		tracing := s.FrameworkOptions.Tracing
+		available[common.MiddlewareOtel] = []gin.HandlerFunc{
+			otelgin.Middleware(utils.GetAppName(),
+				otelgin.WithTracerProvider(tracing.TracerProvider),
+				otelgin.WithPropagators(tracing.GetPropagators()),
+				otelgin.WithSpanNameFormatter(func(c *gin.Context) string {
+					return tracing.SpanName(c.Request.Method, c.FullPath())
+				}),
+				otelgin.WithFilter(func(r *http.Request) bool {
+					return tracing.Traced(r.URL.Path)
+				}),
+			),
+			s.traceHeadersMiddleware(),
+		}
 	}
 
-	// Gzip Middleware
-	r.Use(gzip.Gzip(gzip.DefaultCompression))
+	if s.FrameworkOptions.StatsvizEnabled {
+		available[common.MiddlewareStatsviz] = nil
+	}
 
-	// Custom Logging Middleware
-	r.Use(sloggin.New(slog.Default()))
-	r.Use(gin.Recovery())
+	chain, chained := common.BuildMiddleware(s.WebServer, available)
+	r.Use(chained...)
+	r.Use(s.problemMiddleware())
 
 	// Debug Mode
 	if strings.EqualFold(s.FrameworkOptions.LogLevelConfig.Level().String(), "debug") {
@@ -72,12 +116,28 @@ func (s *Server) setup() {
 	// Define Routes
 	r.GET("/", s.mainRoute)
 	r.GET("/health", s.healthRoute)
+	r.GET("/ready", gin.WrapH(common.ReadinessHandler(s.FrameworkOptions.Runtime)))
+	r.GET("/version", gin.WrapH(common.VersionHandler(s.FrameworkOptions)))
+	r.GET(common.DashboardPath, gin.WrapH(s.Dashboard().Handler()))
+	r.GET(common.DashboardPath+"/*path", gin.WrapH(s.Dashboard().Handler()))
 	r.GET("/logger", s.loggerRoute)
 	r.GET("/framework", s.switchRoute)
+	r.GET("/chain", s.chainRoute)
+	r.GET("/middleware", gin.WrapH(common.MiddlewareHandler(s.FrameworkOptions)))
+	r.NoRoute(s.notFoundRoute)
+
+	// Reverse proxy routes
+	if s.FrameworkOptions.Proxy.Enabled() {
+		proxy := gin.WrapH(common.NewProxyHandler(s.FrameworkOptions.Proxy, s.FrameworkOptions.Tracing, s.FrameworkOptions.Problems))
+		for _, route := range s.FrameworkOptions.Proxy.Routes {
+			r.Any(route.Prefix, proxy)
+			r.Any(route.Prefix+"/*proxyPath", proxy)
+		}
+	}
 	// r.GET("/metrics", gin.WrapH(promhttp.Handler()))
 
 	// Optional Statviz
-	if s.FrameworkOptions.StatsvizEnabled {
+	if chain.Includes(common.MiddlewareStatsviz) {
 		// Create statsviz server.
 		srv, _ := statsviz.NewServer()
 
@@ -97,32 +157,39 @@ func (s *Server) setup() {
 
 	s.Server = &http.Server{
 		Addr:    s.FrameworkOptions.ListenAddr,
-		Handler: r,
+		Handler: common.CountRequests(s.FrameworkOptions.Runtime)(r),
 	}
+	s.FrameworkOptions.Server.Apply(s.Server)
 
 }
 
-func (s *Server) Start(ctx context.Context) {
+func (s *Server) Start(ctx context.Context) error {
 	s.MU.Lock()
 	defer s.MU.Unlock()
 
 	if s.Running {
-		slog.DebugContext(ctx, "Web server is already running")
-		return
+		utils.ComponentLogger(utils.ComponentServer).DebugContext(ctx, "Web server is already running")
+		return nil
+	}
+
+	if s.Server == nil {
+		s.setup()
+	}
+
+	listener, err := s.Listen()
+	if err != nil {
+		return err
 	}
 
 	go func() {
-		if s.Server == nil {
-			s.setup()
-		}
-		slog.DebugContext(ctx, "Starting server", "address", s.FrameworkOptions.ListenAddr)
-		if err := s.Server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-			slog.ErrorContext(ctx, "Server exited with error", "error", err)
-			os.Exit(1)
+		utils.ComponentLogger(utils.ComponentServer).DebugContext(ctx, "Starting server", "address", s.FrameworkOptions.ListenAddr)
+		if err := s.Server.Serve(listener); err != nil && err != http.ErrServerClosed {
+			s.Fail(err)
 		}
 	}()
 
 	s.Running = true
+	return nil
 }
 
 // Stop gracefully stops the web server.
@@ -131,16 +198,19 @@ func (s *Server) Stop(ctx context.Context) {
 	defer s.MU.Unlock()
 
 	if !s.Running {
-		slog.DebugContext(ctx, "Web server is not running")
+		utils.ComponentLogger(utils.ComponentServer).DebugContext(ctx, "Web server is not running")
 		return
 	}
 
-	slog.InfoContext(ctx, "Stopping web server")
+	utils.ComponentLogger(utils.ComponentServer).InfoContext(ctx, "Stopping web server")
+
+	// End the dashboard event streams, Shutdown waits for them
+	s.Dashboard().Close()
 
 	if err := s.Server.Shutdown(ctx); err != nil {
-		slog.ErrorContext(ctx, "Error stopping web server", "error", err)
+		utils.ComponentLogger(utils.ComponentServer).ErrorContext(ctx, "Error stopping web server", "error", err)
 	} else {
-		slog.InfoContext(ctx, "Web server stopped successfully")
+		utils.ComponentLogger(utils.ComponentServer).InfoContext(ctx, "Web server stopped successfully")
 	}
 
 	s.Running = false
@@ -47,6 +47,16 @@ func prometheusMiddleware(next http.Handler) http.Handler {
 	})
 }
 
+// routeTemplate returns the path template of the matched mux route
+func routeTemplate(r *http.Request) string {
+	route := mux.CurrentRoute(r)
+	if route == nil {
+		return ""
+	}
+	path, _ := route.GetPathTemplate()
+	return path
+}
+
 func init() {
 	initGeneralMetrics()
 }
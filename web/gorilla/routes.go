@@ -4,66 +4,51 @@ import (
 	"encoding/json"
 	"net/http"
 
-	"log/slog"
-
 	"github.com/wasilak/go-hello-world/utils"
 	"github.com/wasilak/go-hello-world/web/common"
 )
 
 func (s *Server) healthHandler(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
-	slog.DebugContext(ctx, "healthHandler called")
-	w.WriteHeader(http.StatusOK)
+	utils.ComponentLogger(utils.ComponentHandlers).DebugContext(ctx, "healthHandler called")
 	response := common.HealthResponse{Status: "ok"}
 
-	// Ensure context is used by making sure FrameworkOptions is accessible
-	if s.FrameworkOptions.Tracer != nil {
-		_, span := s.FrameworkOptions.Tracer.Start(ctx, "json encode response")
-		defer span.End()
-	}
-
-	err := json.NewEncoder(w).Encode(response)
-	if err != nil {
-		// Use the new standardized error types
-		appErr := utils.WrapError(err, utils.RuntimeError, "failed to encode health response")
-		appErr.AddContext("path", r.URL.Path)
-		appErr.LogError(ctx)
-		http.Error(w, "Internal server error", http.StatusInternalServerError)
-		return
-	}
+	common.WriteResponse(w, r, s.FrameworkOptions.Problems, http.StatusOK, response)
 }
 
 func (s *Server) rootHandler(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 	response := s.SetMainResponse(ctx, r)
 
-	w.Header().Set("Content-Type", "application/json")
-	if err := json.NewEncoder(w).Encode(response); err != nil {
-		// Use the new standardized error types
-		appErr := utils.WrapError(err, utils.RuntimeError, "failed to encode main response")
-		appErr.AddContext("path", r.URL.Path)
-		appErr.LogError(ctx)
-		http.Error(w, "Internal server error", http.StatusInternalServerError)
-		return
-	}
+	common.WriteResponse(w, r, s.FrameworkOptions.Problems, http.StatusOK, response)
 }
 
 func (s *Server) loggerHandler(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
-	// Extract the new log level parameter from the query
+	// Extract the logger, the new log level and its ttl from the query
 	newLogLevelParam := r.URL.Query().Get("level")
 
-	response := s.SetLogLevelResponse(ctx, newLogLevelParam)
+	response, appErr := s.SetLogLevelResponse(ctx, common.LogLevelRequest{
+		Logger: r.URL.Query().Get("logger"),
+		Level:  newLogLevelParam,
+		TTL:    r.URL.Query().Get("ttl"),
+	})
+	if appErr != nil {
+		appErr.AddContext(utils.ContextPath, r.URL.Path)
+		appErr.LogError(ctx)
+		s.FrameworkOptions.Problems.Write(w, r, appErr)
+		return
+	}
 
 	// Encode and write the response as JSON
 	w.Header().Set("Content-Type", "application/json")
 	if err := json.NewEncoder(w).Encode(response); err != nil {
 		// Use the new standardized error types
 		appErr := utils.WrapError(err, utils.RuntimeError, "failed to encode logger response")
-		appErr.AddContext("path", r.URL.Path)
-		appErr.AddContext("log_level", newLogLevelParam)
+		appErr.AddContext(utils.ContextPath, r.URL.Path)
+		appErr.AddContext(utils.ContextLogLevel, newLogLevelParam)
 		appErr.LogError(ctx)
-		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		s.FrameworkOptions.Problems.Write(w, r, appErr)
 		return
 	}
 }
@@ -73,17 +58,50 @@ func (s *Server) switchRoute(w http.ResponseWriter, r *http.Request) {
 	// Extract the new log level parameter from the query
 	newFrameworkParam := r.URL.Query().Get("name")
 
-	response := s.SetFrameworkResponse(ctx, newFrameworkParam)
+	response, appErr := s.SetFrameworkResponse(ctx, newFrameworkParam)
+	if appErr != nil {
+		appErr.AddContext(utils.ContextPath, r.URL.Path)
+		appErr.LogError(ctx)
+		s.FrameworkOptions.Problems.Write(w, r, appErr)
+		return
+	}
 
 	// Encode and write the response as JSON
 	w.Header().Set("Content-Type", "application/json")
 	if err := json.NewEncoder(w).Encode(response); err != nil {
 		// Use the new standardized error types
 		appErr := utils.WrapError(err, utils.RuntimeError, "failed to encode switch response")
-		appErr.AddContext("path", r.URL.Path)
-		appErr.AddContext("framework", newFrameworkParam)
+		appErr.AddContext(utils.ContextPath, r.URL.Path)
+		appErr.AddContext(utils.ContextFramework, newFrameworkParam)
+		appErr.LogError(ctx)
+		s.FrameworkOptions.Problems.Write(w, r, appErr)
+		return
+	}
+}
+
+func (s *Server) chainRoute(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	request, appErr := s.FrameworkOptions.Chain.ParseChainRequest(r.URL.Query(), r.Header.Get(common.ChainDepthHeader))
+	if appErr != nil {
+		appErr.AddContext(utils.ContextPath, r.URL.Path)
+		appErr.LogError(ctx)
+		s.FrameworkOptions.Problems.Write(w, r, appErr)
+		return
+	}
+
+	response := s.SetChainResponse(ctx, request)
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		// Use the new standardized error types
+		appErr := utils.WrapError(err, utils.RuntimeError, "failed to encode chain response")
+		appErr.AddContext(utils.ContextPath, r.URL.Path)
 		appErr.LogError(ctx)
-		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		s.FrameworkOptions.Problems.Write(w, r, appErr)
 		return
 	}
 }
+
+func (s *Server) notFoundHandler(w http.ResponseWriter, r *http.Request) {
+	common.WriteProblem(w, s.FrameworkOptions.Problems.StatusProblem(r.Context(), http.StatusNotFound, "", r.URL.Path))
+}
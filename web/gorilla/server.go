@@ -2,9 +2,8 @@ package gorilla
 
 import (
 	"context"
-	"log/slog"
 	"net/http"
-	"os"
+	"slices"
 	"sync"
 
 	"github.com/arl/statsviz"
@@ -25,63 +24,144 @@ type Server struct {
 func (s *Server) setup(ctx context.Context) {
 	router := mux.NewRouter()
 
-	// Prometheus middleware and metrics endpoint
-	router.Use(prometheusMiddleware)
+	// Middleware provided by gorilla, applied in the configured order
+	available := map[string][]mux.MiddlewareFunc{
+		common.MiddlewareRecovery:    {sloghttp.Recovery},
+		common.MiddlewarePrometheus:  {prometheusMiddleware},
+		common.MiddlewareRequestID:   {s.FrameworkOptions.RequestID.Middleware()},
+		common.MiddlewareErrorScope:  {common.ErrorScopeMiddleware(s.Framework, routeTemplate)},
+		common.MiddlewareAccessLog:   {s.FrameworkOptions.AccessLog.Middleware(routeTemplate)},
+		common.MiddlewareBodyLimit:   {s.FrameworkOptions.Server.BodyLimitMiddleware(s.FrameworkOptions.Problems)},
+		common.MiddlewareRateLimit:   {s.FrameworkOptions.RateLimit.Middleware(s.FrameworkOptions.Problems)},
+		common.MiddlewareConcurrency: {s.FrameworkOptions.Concurrency.Middleware(s.Framework, s.FrameworkOptions.Problems)},
+		common.MiddlewareFault:       {common.FaultMiddleware(s.FrameworkOptions.Runtime, s.FrameworkOptions.Problems)},
+	}
+
+	// OpenTelemetry Middleware
+	if s.FrameworkOptions.OtelEnabled {
+		tracing := s.FrameworkOptions.Tracing
+		available[common.MiddlewareOtel] = []mux.MiddlewareFunc{
+			otelmux.Middleware(utils.GetAppName(),
+				otelmux.WithTracerProvider(tracing.TracerProvider),
+				otelmux.WithPropagators(tracing.GetPropagators()),
+				otelmux.WithSpanNameFormatter(func(_ string, r *http.Request) string {
+					return tracing.SpanName(r.Method, routeTemplate(r))
+				}),
+				otelmux.WithFilter(func(r *http.Request) bool {
+					return tracing.Traced(r.URL.Path)
+				}),
+			),
+			tracing.HeaderCaptureMiddleware(),
+		}
+	}
+
+	// CORS headers and preflight responses, shared by all frameworks
+	if s.FrameworkOptions.CORS != nil {
+		available[common.MiddlewareCORS] = []mux.MiddlewareFunc{s.FrameworkOptions.CORS.Middleware()}
+	}
+
+	// Security headers, shared by all frameworks
+	if s.FrameworkOptions.SecurityHeaders != nil {
+		available[common.MiddlewareSecurityHeaders] = []mux.MiddlewareFunc{s.FrameworkOptions.SecurityHeaders.Middleware()}
+	}
+
+	// Response compression, shared by all frameworks
+	if s.FrameworkOptions.Compression != nil {
+		available[common.MiddlewareCompression] = []mux.MiddlewareFunc{s.FrameworkOptions.Compression.Middleware()}
+	}
+
+	// OpenTelemetry HTTP server metrics
+	if s.FrameworkOptions.HTTPMetrics != nil {
+		available[common.MiddlewareHTTPMetrics] = []mux.MiddlewareFunc{s.FrameworkOptions.HTTPMetrics.Middleware(s.Framework, routeTemplate)}
+	}
+
+	if s.FrameworkOptions.StatsvizEnabled {
+		available[common.MiddlewareStatsviz] = nil
+	}
+
+	chain, chained := common.BuildMiddleware(s.WebServer, available)
+	router.Use(chained...)
+
+	// Unmatched routes skip the router middleware, so CORS, the security headers, request
+	// IDs, the access log and the limits of the chain wrap the not found handler in order
+	notFound := http.Handler(http.HandlerFunc(s.notFoundHandler))
+	for _, name := range slices.Backward(chain.Active) {
+		switch name {
+		case common.MiddlewareCORS, common.MiddlewareSecurityHeaders, common.MiddlewareRequestID, common.MiddlewareAccessLog, common.MiddlewareBodyLimit, common.MiddlewareRateLimit, common.MiddlewareConcurrency:
+			notFound = available[name][0](notFound)
+		}
+	}
+
+	// Prometheus metrics endpoint
 	router.Path("/metrics").Handler(promhttp.Handler())
 
 	// Application-specific routes
 	router.HandleFunc("/", s.rootHandler)
 	router.HandleFunc("/health", s.healthHandler)
+	router.Handle("/ready", common.ReadinessHandler(s.FrameworkOptions.Runtime))
+	router.Handle("/version", common.VersionHandler(s.FrameworkOptions))
+	router.Handle(common.DashboardPath, s.Dashboard().Handler())
+	router.PathPrefix(common.DashboardPath + "/").Handler(s.Dashboard().Handler())
 	router.HandleFunc("/logger", s.loggerHandler)
 	router.HandleFunc("/framework", s.switchRoute)
+	router.HandleFunc("/chain", s.chainRoute)
+	router.Handle("/middleware", common.MiddlewareHandler(s.FrameworkOptions))
+	router.NotFoundHandler = notFound
+
+	// Reverse proxy routes
+	if s.FrameworkOptions.Proxy.Enabled() {
+		proxy := common.NewProxyHandler(s.FrameworkOptions.Proxy, s.FrameworkOptions.Tracing, s.FrameworkOptions.Problems)
+		for _, route := range s.FrameworkOptions.Proxy.Routes {
+			router.Path(route.Prefix).Handler(proxy)
+			router.PathPrefix(route.Prefix + "/").Handler(proxy)
+		}
+	}
 
-	if s.FrameworkOptions.StatsvizEnabled {
+	if chain.Includes(common.MiddlewareStatsviz) {
 		// Create statsviz server and register the handlers on the router
 		srv, _ := statsviz.NewServer()
 
-		slog.DebugContext(ctx, "Statsviz enabled", "address", "/debug/statsviz/")
+		utils.ComponentLogger(utils.ComponentServer).DebugContext(ctx, "Statsviz enabled", "address", "/debug/statsviz/")
 		router.Methods("GET").Path("/debug/statsviz/ws").Name("GET /debug/statsviz/ws").HandlerFunc(srv.Ws())
 		router.Methods("GET").PathPrefix("/debug/statsviz/").Name("GET /debug/statsviz/").Handler(srv.Index())
 	}
 
-	if s.FrameworkOptions.OtelEnabled {
-		router.Use(otelmux.Middleware(utils.GetAppName()))
-	}
-
-	// Wrap the router with sloghttp middleware
-	handler := sloghttp.Recovery(router)            // Recovery middleware
-	handler = sloghttp.New(slog.Default())(handler) // Logging middleware
-
 	s.Server = &http.Server{
 		Addr:    s.FrameworkOptions.ListenAddr,
-		Handler: handler,
+		Handler: common.CountRequests(s.FrameworkOptions.Runtime)(router),
 	}
+	s.FrameworkOptions.Server.Apply(s.Server)
 }
 
-func (s *Server) Start(ctx context.Context) {
+func (s *Server) Start(ctx context.Context) error {
 	s.MU.Lock()
 	defer s.MU.Unlock()
 
-	s.wg.Add(1)
-
 	if s.Running {
-		slog.DebugContext(ctx, "Web server is already running")
-		return
+		utils.ComponentLogger(utils.ComponentServer).DebugContext(ctx, "Web server is already running")
+		return nil
 	}
 
+	if s.Server == nil {
+		s.setup(ctx)
+	}
+
+	listener, err := s.Listen()
+	if err != nil {
+		return err
+	}
+
+	s.wg.Add(1)
 	go func() {
 		defer s.wg.Done()
-		if s.Server == nil {
-			s.setup(ctx)
-		}
-		slog.DebugContext(ctx, "Starting server", "address", s.FrameworkOptions.ListenAddr)
-		if err := s.Server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-			slog.ErrorContext(ctx, "Server exited with error", "error", err)
-			os.Exit(1)
+		utils.ComponentLogger(utils.ComponentServer).DebugContext(ctx, "Starting server", "address", s.FrameworkOptions.ListenAddr)
+		if err := s.Server.Serve(listener); err != nil && err != http.ErrServerClosed {
+			s.Fail(err)
 		}
 	}()
 
 	s.Running = true
+	return nil
 }
 
 // Stop gracefully stops the web server.
@@ -90,16 +170,19 @@ func (s *Server) Stop(ctx context.Context) {
 	defer s.MU.Unlock()
 
 	if !s.Running {
-		slog.DebugContext(ctx, "Web server is not running")
+		utils.ComponentLogger(utils.ComponentServer).DebugContext(ctx, "Web server is not running")
 		return
 	}
 
-	slog.InfoContext(ctx, "Stopping web server")
+	utils.ComponentLogger(utils.ComponentServer).InfoContext(ctx, "Stopping web server")
+
+	// End the dashboard event streams, Shutdown waits for them
+	s.Dashboard().Close()
 
 	if err := s.Server.Shutdown(ctx); err != nil {
-		slog.ErrorContext(ctx, "Error stopping web server", "error", err)
+		utils.ComponentLogger(utils.ComponentServer).ErrorContext(ctx, "Error stopping web server", "error", err)
 	} else {
-		slog.InfoContext(ctx, "Web server stopped successfully")
+		utils.ComponentLogger(utils.ComponentServer).InfoContext(ctx, "Web server stopped successfully")
 	}
 
 	s.Running = false
@@ -0,0 +1,47 @@
+package web
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/wasilak/go-hello-world/web/common"
+)
+
+func TestMiddlewareChainPerFramework(t *testing.T) {
+	for _, framework := range testFrameworks {
+		t.Run(framework, func(t *testing.T) {
+			registry := common.NewMiddlewareRegistry(common.MiddlewareConfig{
+				Order:    []string{common.MiddlewareFault, common.MiddlewareRecovery},
+				Disabled: []string{common.MiddlewareCompression, common.MiddlewareRequestID},
+			})
+			options := testOptions(t)
+			options.RequestID = common.RequestIDConfig{Header: common.DefaultRequestIDHeader, Format: common.RequestIDFormatUUIDv7}
+			options.Middleware = registry
+			baseURL := startTestServer(t, context.Background(), framework, options)
+
+			req, err := http.NewRequest(http.MethodGet, baseURL+"/middleware", nil)
+			require.NoError(t, err)
+			req.Header.Set("Accept-Encoding", "gzip")
+			resp, err := http.DefaultTransport.RoundTrip(req)
+			require.NoError(t, err)
+			defer resp.Body.Close()
+			require.Equal(t, http.StatusOK, resp.StatusCode)
+
+			// disabled middleware are not applied
+			assert.Empty(t, resp.Header.Get("Content-Encoding"))
+			assert.Empty(t, resp.Header.Get(common.DefaultRequestIDHeader))
+
+			var chain common.MiddlewareChain
+			require.NoError(t, json.NewDecoder(resp.Body).Decode(&chain))
+			assert.Equal(t, framework, chain.Framework)
+			assert.Equal(t, []string{common.MiddlewareFault, common.MiddlewareRecovery}, chain.Active[:2])
+			assert.Equal(t, []string{common.MiddlewareCompression, common.MiddlewareRequestID}, chain.Disabled)
+			assert.Contains(t, chain.Unavailable, common.MiddlewareOtel)
+			assert.Equal(t, registry.Chain(), chain)
+		})
+	}
+}
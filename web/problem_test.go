@@ -0,0 +1,89 @@
+package web
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/wasilak/go-hello-world/web/common"
+)
+
+func TestProblemResponsesPerFramework(t *testing.T) {
+	for _, framework := range testFrameworks {
+		t.Run(framework, func(t *testing.T) {
+			options := testOptions(t)
+			options.Problems = common.ProblemConfig{ContextAllowlist: []string{"mode"}}
+			baseURL := startTestServer(t, context.Background(), framework, options)
+
+			t.Run("Validation error", func(t *testing.T) {
+				problem := getProblem(t, baseURL+"/chain?mode=random")
+				assert.Equal(t, http.StatusBadRequest, problem.Status)
+				assert.Equal(t, common.DefaultProblemTypeURIPrefix+"validation", problem.Type)
+				assert.Equal(t, "validation.chain_mode", problem.Code)
+				assert.Equal(t, "invalid chain mode", problem.Detail)
+				assert.Equal(t, "/chain", problem.Instance)
+				assert.Equal(t, "random", problem.Context["mode"])
+				assert.Equal(t, "[REDACTED]", problem.Context["path"])
+			})
+
+			t.Run("Chain depth", func(t *testing.T) {
+				req, err := http.NewRequest(http.MethodGet, baseURL+"/chain", nil)
+				require.NoError(t, err)
+				req.Header.Set(common.ChainDepthHeader, strconv.Itoa(common.DefaultChainMaxDepth+1))
+				resp, err := http.DefaultClient.Do(req)
+				require.NoError(t, err)
+				defer resp.Body.Close()
+
+				var problem common.Problem
+				require.NoError(t, json.NewDecoder(resp.Body).Decode(&problem))
+				assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+				assert.Equal(t, "validation.chain_depth", problem.Code)
+			})
+
+			t.Run("Chain hop not allowed", func(t *testing.T) {
+				problem := getProblem(t, baseURL+"/chain?hop=http://169.254.169.254/latest/meta-data/")
+				assert.Equal(t, http.StatusBadRequest, problem.Status)
+				assert.Equal(t, "validation.chain_hop_not_allowed", problem.Code)
+			})
+
+			t.Run("Invalid log level", func(t *testing.T) {
+				problem := getProblem(t, baseURL+"/logger?level=verbose")
+				assert.Equal(t, http.StatusBadRequest, problem.Status)
+				assert.Equal(t, "validation.log_level", problem.Code)
+
+				problem = getProblem(t, baseURL+"/logger?logger=database&level=debug")
+				assert.Equal(t, "validation.logger", problem.Code)
+
+				problem = getProblem(t, baseURL+"/logger?level=debug&ttl=soon")
+				assert.Equal(t, "validation.log_level_ttl", problem.Code)
+			})
+
+			t.Run("Unknown route", func(t *testing.T) {
+				problem := getProblem(t, baseURL+"/does-not-exist")
+				assert.Equal(t, http.StatusNotFound, problem.Status)
+				assert.Equal(t, "about:blank", problem.Type)
+				assert.Equal(t, "Not Found", problem.Title)
+			})
+		})
+	}
+}
+
+// getProblem requests url and decodes the problem+json response
+func getProblem(t *testing.T, url string) common.Problem {
+	t.Helper()
+
+	resp, err := http.Get(url)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	assert.Equal(t, common.ProblemContentType, resp.Header.Get("Content-Type"))
+
+	var problem common.Problem
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&problem))
+	assert.Equal(t, resp.StatusCode, problem.Status)
+	return problem
+}
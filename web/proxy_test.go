@@ -0,0 +1,68 @@
+package web
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/wasilak/go-hello-world/web/common"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestProxyPerFramework(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, r.URL.Path)
+	}))
+	defer upstream.Close()
+
+	routes, err := common.ParseProxyRoutes([]string{"/api=" + upstream.URL})
+	require.NoError(t, err)
+
+	for _, framework := range testFrameworks {
+		t.Run(framework, func(t *testing.T) {
+			ctx := context.Background()
+			exporter := tracetest.NewInMemoryExporter()
+			provider := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+			tracing := common.TracingConfig{
+				TracerProvider: provider,
+				Propagators:    propagation.TraceContext{},
+				FilteredPaths:  []string{"/health"},
+			}
+
+			options := testOptions(t)
+			options.OtelEnabled = true
+			options.Tracer = provider.Tracer("test")
+			options.Tracing = tracing
+			options.Proxy = common.ProxyConfig{Routes: routes, StripPrefix: true}
+			baseURL := startTestServer(t, ctx, framework, options)
+
+			resp, err := http.Get(baseURL + "/api/users/1")
+			require.NoError(t, err)
+			body, _ := io.ReadAll(resp.Body)
+			resp.Body.Close()
+
+			assert.Equal(t, http.StatusOK, resp.StatusCode)
+			assert.Equal(t, "/users/1", string(body))
+
+			require.Eventually(t, func() bool {
+				return len(exporter.GetSpans()) == 2
+			}, 2*time.Second, 10*time.Millisecond, "Expected a server span and the proxy client span")
+
+			spans := map[trace.SpanKind]tracetest.SpanStub{}
+			for _, span := range exporter.GetSpans() {
+				spans[span.SpanKind] = span
+			}
+
+			assert.Equal(t, "proxy GET "+upstream.Listener.Addr().String(), spans[trace.SpanKindClient].Name)
+			assert.Equal(t, spans[trace.SpanKindServer].SpanContext.SpanID(), spans[trace.SpanKindClient].Parent.SpanID())
+		})
+	}
+}
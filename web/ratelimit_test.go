@@ -0,0 +1,50 @@
+package web
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/wasilak/go-hello-world/web/common"
+)
+
+func TestRateLimitPerFramework(t *testing.T) {
+	for _, framework := range testFrameworks {
+		t.Run(framework, func(t *testing.T) {
+			options := testOptions(t)
+			options.RateLimit = common.NewRateLimiter(common.RateLimitConfig{
+				Routes:       []common.RouteRateLimit{{Path: "/logger", RateLimit: common.RateLimit{Rate: 0.001, Burst: 1}}},
+				ExcludePaths: []string{"/health"},
+			})
+			baseURL := startTestServer(t, context.Background(), framework, options)
+
+			resp, err := http.Get(baseURL + "/logger")
+			require.NoError(t, err)
+			resp.Body.Close()
+			assert.NotEqual(t, http.StatusTooManyRequests, resp.StatusCode)
+			assert.Equal(t, "1", resp.Header.Get("RateLimit-Limit"))
+			assert.Equal(t, "0", resp.Header.Get("RateLimit-Remaining"))
+
+			resp, err = http.Get(baseURL + "/logger")
+			require.NoError(t, err)
+			defer resp.Body.Close()
+
+			assert.Equal(t, http.StatusTooManyRequests, resp.StatusCode)
+			assert.NotEmpty(t, resp.Header.Get("Retry-After"))
+			assert.Equal(t, common.ProblemContentType, resp.Header.Get("Content-Type"))
+
+			var problem common.Problem
+			require.NoError(t, json.NewDecoder(resp.Body).Decode(&problem))
+			assert.Equal(t, "rate_limit.exceeded", problem.Code)
+
+			resp, err = http.Get(baseURL + "/")
+			require.NoError(t, err)
+			resp.Body.Close()
+			assert.Equal(t, http.StatusOK, resp.StatusCode, "Expected paths without a limit to pass")
+			assert.Empty(t, resp.Header.Get("RateLimit-Limit"))
+		})
+	}
+}
@@ -0,0 +1,63 @@
+package web
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/wasilak/go-hello-world/web/common"
+)
+
+func TestContentNegotiationPerFramework(t *testing.T) {
+	for _, framework := range testFrameworks {
+		t.Run(framework, func(t *testing.T) {
+			options := testOptions(t)
+			baseURL := startTestServer(t, context.Background(), framework, options)
+
+			get := func(path, accept string) (*http.Response, string) {
+				req, err := http.NewRequest(http.MethodGet, baseURL+path, nil)
+				require.NoError(t, err)
+				if accept != "" {
+					req.Header.Set("Accept", accept)
+				}
+
+				resp, err := http.DefaultClient.Do(req)
+				require.NoError(t, err)
+				defer resp.Body.Close()
+
+				body, err := io.ReadAll(resp.Body)
+				require.NoError(t, err)
+				return resp, string(body)
+			}
+
+			resp, body := get("/", "")
+			assert.Equal(t, "application/json", resp.Header.Get("Content-Type"))
+			assert.Contains(t, body, `"framework":"`+framework+`"`)
+
+			resp, body = get("/", "application/yaml")
+			assert.Equal(t, "application/yaml", resp.Header.Get("Content-Type"))
+			assert.Contains(t, body, "framework: "+framework+"\n")
+			assert.Contains(t, strings.Split(strings.ReplaceAll(strings.Join(resp.Header.Values("Vary"), ","), " ", ""), ","), "Accept")
+
+			resp, body = get("/health", "text/plain")
+			assert.Equal(t, "text/plain; charset=utf-8", resp.Header.Get("Content-Type"))
+			assert.Equal(t, "status: ok\n", body)
+
+			resp, body = get("/?format=html", "application/json")
+			assert.Equal(t, "text/html; charset=utf-8", resp.Header.Get("Content-Type"))
+			assert.Contains(t, body, " on "+framework+"</h1>")
+
+			resp, body = get("/version?format=pretty", "")
+			assert.Equal(t, http.StatusOK, resp.StatusCode)
+			assert.Contains(t, body, "{\n  \"version\": ")
+
+			resp, _ = get("/health?format=xml", "")
+			assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+			assert.Equal(t, common.ProblemContentType, resp.Header.Get("Content-Type"))
+		})
+	}
+}
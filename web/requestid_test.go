@@ -0,0 +1,52 @@
+package web
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/wasilak/go-hello-world/utils"
+	"github.com/wasilak/go-hello-world/web/common"
+)
+
+func TestRequestIDPerFramework(t *testing.T) {
+	for _, framework := range testFrameworks {
+		t.Run(framework, func(t *testing.T) {
+			options := testOptions(t)
+			options.RequestID = common.RequestIDConfig{Header: "X-Correlation-ID", Format: common.RequestIDFormatULID}
+			baseURL := startTestServer(t, context.Background(), framework, options)
+
+			t.Run("Generated", func(t *testing.T) {
+				resp, err := http.Get(baseURL + "/")
+				require.NoError(t, err)
+				defer resp.Body.Close()
+
+				id := resp.Header.Get("X-Correlation-ID")
+				assert.Len(t, id, 26, "Expected a generated ULID")
+
+				var body common.APIResponse
+				require.NoError(t, json.NewDecoder(resp.Body).Decode(&body))
+				assert.Equal(t, id, body.RequestID)
+			})
+
+			t.Run("Incoming", func(t *testing.T) {
+				req, err := http.NewRequest(http.MethodGet, baseURL+"/chain?mode=random", nil)
+				require.NoError(t, err)
+				req.Header.Set("X-Correlation-ID", "client-42")
+
+				resp, err := http.DefaultClient.Do(req)
+				require.NoError(t, err)
+				resp.Body.Close()
+
+				assert.Equal(t, "client-42", resp.Header.Get("X-Correlation-ID"))
+
+				records := utils.RecentErrors().Snapshot(1)
+				require.Len(t, records, 1)
+				assert.Equal(t, "client-42", records[0].Error.Context()[utils.RequestIDKey])
+			})
+		})
+	}
+}
@@ -0,0 +1,64 @@
+package web
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/wasilak/go-hello-world/web/common"
+)
+
+func TestRuntimeControlPerFramework(t *testing.T) {
+	for _, framework := range testFrameworks {
+		t.Run(framework, func(t *testing.T) {
+			runtime := common.NewRuntimeState()
+			logLevel := new(slog.LevelVar)
+
+			options := testOptions(t)
+			options.LogLevelConfig = logLevel
+			options.Admin = common.AdminConfig{ListenAddr: "127.0.0.1:0"}
+			options.Runtime = runtime
+			baseURL := startTestServer(t, context.Background(), framework, options)
+
+			status := func(path string) int {
+				resp, err := http.Get(baseURL + path)
+				require.NoError(t, err)
+				resp.Body.Close()
+				return resp.StatusCode
+			}
+
+			t.Run("Readiness", func(t *testing.T) {
+				assert.Equal(t, http.StatusOK, status("/ready"))
+				runtime.SetReady(false)
+				assert.Equal(t, http.StatusServiceUnavailable, status("/ready"))
+				runtime.SetReady(true)
+			})
+
+			t.Run("Fault injection", func(t *testing.T) {
+				runtime.SetFaults(&common.FaultConfig{ErrorRate: 1, StatusCode: http.StatusTeapot, Paths: []string{"/logger"}})
+				defer runtime.SetFaults(nil)
+
+				assert.Equal(t, http.StatusTeapot, status("/logger"))
+				assert.Equal(t, http.StatusOK, status("/health"))
+			})
+
+			t.Run("Public routes are read-only", func(t *testing.T) {
+				assert.Equal(t, http.StatusOK, status("/logger"))
+				assert.Equal(t, http.StatusOK, status("/framework"))
+
+				problem := getProblem(t, baseURL+"/logger?level=debug")
+				assert.Equal(t, http.StatusForbidden, problem.Status)
+				assert.Equal(t, "forbidden.admin_only", problem.Code)
+				assert.Contains(t, problem.Detail, "/admin/log-level")
+				assert.Equal(t, slog.LevelInfo, logLevel.Level())
+
+				problem = getProblem(t, baseURL+"/framework?name=gin")
+				assert.Equal(t, http.StatusForbidden, problem.Status)
+				assert.Contains(t, problem.Detail, "/admin/framework")
+			})
+		})
+	}
+}
@@ -0,0 +1,86 @@
+package web
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"io"
+	"net/http"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/wasilak/go-hello-world/web/common"
+)
+
+func TestSecurityHeadersPerFramework(t *testing.T) {
+	config, appErr := common.ParseSecurityHeadersConfig(common.SecurityHeadersAPI, []string{"Permissions-Policy=", "X-Frame-Options=DENY"})
+	require.Nil(t, appErr)
+	expected := config.Header()
+
+	for _, framework := range testFrameworks {
+		t.Run(framework, func(t *testing.T) {
+			options := testOptions(t)
+			options.SecurityHeaders = common.NewSecurityHeaders(config)
+			baseURL := startTestServer(t, context.Background(), framework, options)
+
+			// error responses carry the headers too
+			for path, status := range map[string]int{"/health": http.StatusOK, "/missing": http.StatusNotFound} {
+				resp, err := http.Get(baseURL + path)
+				require.NoError(t, err)
+				resp.Body.Close()
+				require.Equal(t, status, resp.StatusCode, path)
+
+				for name := range expected {
+					assert.Equal(t, expected.Get(name), resp.Header.Get(name), "%s %s", path, name)
+				}
+				assert.Empty(t, resp.Header.Get("Permissions-Policy"))
+			}
+		})
+	}
+}
+
+// TestSecurityHeadersStrictPagesPerFramework checks that the strict policy lets the HTML
+// format and the dashboard work
+func TestSecurityHeadersStrictPagesPerFramework(t *testing.T) {
+	for _, framework := range testFrameworks {
+		t.Run(framework, func(t *testing.T) {
+			options := testOptions(t)
+			options.SecurityHeaders = common.NewSecurityHeaders(common.SecurityHeadersConfig{Preset: common.SecurityHeadersStrict})
+			options.Admin = common.AdminConfig{ListenAddr: "0.0.0.0:9090", Token: "s3cret"}
+			baseURL := startTestServer(t, context.Background(), framework, options)
+
+			get := func(path string) (*http.Response, string) {
+				resp, err := http.Get(baseURL + path)
+				require.NoError(t, err)
+				defer resp.Body.Close()
+				body, err := io.ReadAll(resp.Body)
+				require.NoError(t, err)
+				require.Equal(t, http.StatusOK, resp.StatusCode, path)
+				return resp, string(body)
+			}
+
+			t.Run("HTML format", func(t *testing.T) {
+				resp, body := get("/?format=html")
+				style := regexp.MustCompile(`(?s)<style>(.*)</style>`).FindStringSubmatch(body)
+				require.Len(t, style, 2)
+				sum := sha256.Sum256([]byte(style[1]))
+				assert.Contains(t, resp.Header.Get("Content-Security-Policy"), "style-src 'self' 'sha256-"+base64.StdEncoding.EncodeToString(sum[:])+"'")
+			})
+
+			t.Run("Dashboard", func(t *testing.T) {
+				resp, body := get("/ui/")
+				assert.NotContains(t, body, "<style")
+				assert.NotContains(t, body, "<script>", "Expected scripts to be served from the dashboard origin")
+
+				require.Len(t, resp.Header.Values("Content-Security-Policy"), 1, "Expected a single policy, browsers enforce each of them")
+				policy := resp.Header.Get("Content-Security-Policy")
+				assert.Contains(t, policy, "default-src 'self'")
+				host := strings.TrimPrefix(baseURL, "http://")
+				assert.Contains(t, policy, "connect-src 'self' http://"+strings.Split(host, ":")[0]+":9090")
+			})
+		})
+	}
+}
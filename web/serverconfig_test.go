@@ -0,0 +1,75 @@
+package web
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/wasilak/go-hello-world/web/common"
+)
+
+func TestServerLimitsPerFramework(t *testing.T) {
+	for _, framework := range testFrameworks {
+		t.Run(framework, func(t *testing.T) {
+			options := testOptions(t)
+			options.Server = common.ServerConfig{
+				ReadTimeout:      5 * time.Second,
+				WriteTimeout:     5 * time.Second,
+				MaxBodyBytes:     16,
+				DisableKeepAlive: true,
+			}
+			baseURL := startTestServer(t, context.Background(), framework, options)
+
+			resp, err := http.Get(baseURL + "/")
+			require.NoError(t, err)
+			resp.Body.Close()
+			assert.Equal(t, http.StatusOK, resp.StatusCode)
+			assert.True(t, resp.Close, "Expected the connection to be closed without keep-alive")
+
+			resp, err = http.Post(baseURL+"/", "text/plain", strings.NewReader(strings.Repeat("x", 64)))
+			require.NoError(t, err)
+			resp.Body.Close()
+			assert.Equal(t, http.StatusRequestEntityTooLarge, resp.StatusCode)
+			assert.Equal(t, common.ProblemContentType, resp.Header.Get("Content-Type"))
+		})
+	}
+}
+
+// TestServerUnlimitedBodyPerFramework checks that a zero body limit accepts bodies over the
+// 4 MiB fiber applies by default
+func TestServerUnlimitedBodyPerFramework(t *testing.T) {
+	// the proxy forwards the body, the upstream returns its size
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n, _ := io.Copy(io.Discard, r.Body)
+		_, _ = io.WriteString(w, strconv.FormatInt(n, 10))
+	}))
+	defer upstream.Close()
+
+	routes, err := common.ParseProxyRoutes([]string{"/upload=" + upstream.URL})
+	require.NoError(t, err)
+
+	size := 5 << 20
+	for _, framework := range testFrameworks {
+		t.Run(framework, func(t *testing.T) {
+			options := testOptions(t)
+			options.Server = common.ServerConfig{MaxBodyBytes: 0}
+			options.Proxy = common.ProxyConfig{Routes: routes, Timeout: 10 * time.Second}
+			baseURL := startTestServer(t, context.Background(), framework, options)
+
+			resp, err := http.Post(baseURL+"/upload", "text/plain", strings.NewReader(strings.Repeat("x", size)))
+			require.NoError(t, err)
+			body, _ := io.ReadAll(resp.Body)
+			resp.Body.Close()
+
+			assert.Equal(t, http.StatusOK, resp.StatusCode)
+			assert.Equal(t, strconv.Itoa(size), string(body))
+		})
+	}
+}
@@ -0,0 +1,245 @@
+package web
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/wasilak/go-hello-world/utils"
+	"github.com/wasilak/go-hello-world/web/common"
+	"golang.org/x/text/cases"
+	"golang.org/x/text/language"
+)
+
+var (
+	// ServerStartFailuresCounterVec counts the failed web server start attempts by framework
+	ServerStartFailuresCounterVec = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "web_server_start_failures_total",
+			Help: "Total number of failed web server start attempts by framework",
+		},
+		[]string{"framework"},
+	)
+
+	// ServerFailuresCounterVec counts the running web servers which exited with an error
+	ServerFailuresCounterVec = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "web_server_failures_total",
+			Help: "Total number of running web servers which exited with an error by framework",
+		},
+		[]string{"framework"},
+	)
+
+	// ServerRollbacksCounterVec counts the failed framework switches rolled back to the previous framework
+	ServerRollbacksCounterVec = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "web_server_rollbacks_total",
+			Help: "Total number of failed framework switches rolled back to the previous framework",
+		},
+		[]string{"from", "to"},
+	)
+)
+
+// SupervisorConfig configures how failed web server starts are retried
+type SupervisorConfig struct {
+	// Retries is the number of start attempts after the first one failed
+	Retries int
+
+	// Backoff is the delay before the first retry, doubled after every attempt up to MaxBackoff
+	Backoff    time.Duration
+	MaxBackoff time.Duration
+}
+
+// Validate checks the supervisor configuration
+func (c SupervisorConfig) Validate() *utils.AppError {
+	if c.Retries < 0 {
+		return utils.NewAppError(utils.ValidationError, "server start retries must not be negative", nil).WithCode("validation.supervisor_retries")
+	}
+	if c.Retries > 0 && (c.Backoff <= 0 || c.MaxBackoff < c.Backoff) {
+		return utils.NewAppError(utils.ValidationError, "server start retries need a positive backoff not above the maximum backoff", nil).
+			WithCode("validation.supervisor_backoff")
+	}
+	return nil
+}
+
+// Supervisor runs the web server of the selected framework. It retries failed starts with
+// backoff, rolls back to the previous framework when a switch fails and restarts servers
+// exiting with an error. Failures it cannot recover from are logged as framework errors
+// and mark the instance not ready until a server is running again.
+type Supervisor struct {
+	options common.FrameworkOptions
+	config  SupervisorConfig
+
+	// newServer creates the server of a framework, nil when it is unknown
+	newServer func(ws *common.WebServer) common.WebServerInterface
+
+	server    common.WebServerInterface
+	framework string
+
+	// notReady records that the supervisor, not an operator, marked the instance not ready
+	notReady bool
+}
+
+// NewSupervisor creates the supervisor of the web servers
+func NewSupervisor(options common.FrameworkOptions, config SupervisorConfig) *Supervisor {
+	return &Supervisor{
+		options:   options,
+		config:    config,
+		newServer: newServer,
+	}
+}
+
+// Run serves the framework switches requested on common.FrameworkChannel and restarts
+// failed servers until ctx is canceled. Switch must not be called concurrently with Run.
+func (s *Supervisor) Run(ctx context.Context) {
+	for {
+		var failed <-chan error
+		if s.server != nil {
+			failed = s.server.Errors()
+		}
+
+		select {
+		case <-ctx.Done():
+			// Context cancellation received, stop the server and exit
+			slog.DebugContext(ctx, "Shutting down server before exiting")
+			s.stop(ctx)
+			return
+
+		case req := <-common.FrameworkChannel:
+			err := s.Switch(ctx, req.Framework)
+			if req.Result != nil {
+				req.Result <- err
+			}
+
+		case err := <-failed:
+			framework := s.framework
+			ServerFailuresCounterVec.WithLabelValues(framework).Inc()
+			s.report(ctx, framework, err)
+
+			s.stop(ctx)
+			if err := s.start(ctx, framework); err != nil {
+				s.fail(ctx, framework, err)
+			}
+		}
+	}
+}
+
+// Switch stops the running server and starts framework, rolling back to the previous
+// framework when it cannot be started. It returns the error of the failed start.
+func (s *Supervisor) Switch(ctx context.Context, framework string) error {
+	caser := cases.Title(language.English)
+	previous := s.framework
+
+	// Stop the currently running server, the new one binds the same address
+	if s.server != nil {
+		slog.DebugContext(ctx, "Stopping server", "type", caser.String(previous))
+		s.stop(ctx)
+	}
+
+	slog.DebugContext(ctx, "Starting server", "type", caser.String(framework))
+	slog.DebugContext(ctx, "Features supported", "loggergo", true, "statsviz", true, "tracing", true)
+
+	err := s.start(ctx, framework)
+	if err == nil {
+		return nil
+	}
+	if previous == "" || previous == framework {
+		s.fail(ctx, framework, err)
+		return err
+	}
+
+	s.report(ctx, framework, err)
+	ServerRollbacksCounterVec.WithLabelValues(framework, previous).Inc()
+	slog.WarnContext(ctx, "Rolling back to the previous framework", "framework", framework, "previous", previous)
+
+	if rollbackErr := s.start(ctx, previous); rollbackErr != nil {
+		s.fail(ctx, previous, rollbackErr)
+	}
+	return err
+}
+
+// start starts framework, retrying with backoff while the error is retryable
+func (s *Supervisor) start(ctx context.Context, framework string) error {
+	backoff := s.config.Backoff
+
+	for attempt := 0; ; attempt++ {
+		err := s.startOnce(ctx, framework)
+		if err == nil {
+			s.recovered()
+			return nil
+		}
+
+		ServerStartFailuresCounterVec.WithLabelValues(framework).Inc()
+		if attempt >= s.config.Retries || !utils.IsRetryable(err) {
+			return err
+		}
+
+		slog.WarnContext(ctx, "Retrying server start", "framework", framework, "attempt", attempt+1, "backoff", backoff, "error", err)
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(backoff):
+		}
+		backoff = min(2*backoff, s.config.MaxBackoff)
+	}
+}
+
+// startOnce creates and starts the server of framework
+func (s *Supervisor) startOnce(ctx context.Context, framework string) error {
+	server := s.newServer(&common.WebServer{Framework: framework, FrameworkOptions: s.options})
+	if server == nil {
+		return utils.NewAppError(utils.FrameworkError, "no valid web framework selected", nil).
+			WithCode("framework.unknown").
+			AddContext(utils.ContextFramework, framework)
+	}
+
+	if err := server.Start(ctx); err != nil {
+		return err
+	}
+
+	s.server = server
+	s.framework = framework
+	s.options.Runtime.SetFramework(framework)
+	return nil
+}
+
+// stop stops the running server, if any
+func (s *Supervisor) stop(ctx context.Context) {
+	if s.server != nil {
+		s.server.Stop(ctx)
+		s.server = nil
+	}
+}
+
+// report logs err as a framework error of framework
+func (s *Supervisor) report(ctx context.Context, framework string, err error) {
+	appErr, ok := utils.AsAppError(err)
+	if !ok {
+		appErr = utils.WrapError(err, utils.FrameworkError, "web server failed").AddContext(utils.ContextFramework, framework)
+	}
+	appErr.LogError(utils.WithErrorScope(ctx, utils.ErrorScope{Framework: framework}))
+}
+
+// fail reports a failure the supervisor could not recover from, no server is running
+// anymore so the instance is marked not ready
+func (s *Supervisor) fail(ctx context.Context, framework string, err error) {
+	s.report(ctx, framework, err)
+	s.server = nil
+	s.framework = ""
+	s.options.Runtime.SetFramework("")
+
+	if s.options.Runtime.Ready() {
+		s.options.Runtime.SetReady(false)
+		s.notReady = true
+	}
+}
+
+// recovered marks the instance ready again when the supervisor marked it not ready
+func (s *Supervisor) recovered() {
+	if s.notReady {
+		s.options.Runtime.SetReady(true)
+		s.notReady = false
+	}
+}
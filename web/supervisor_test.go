@@ -0,0 +1,167 @@
+package web
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/wasilak/go-hello-world/utils"
+	"github.com/wasilak/go-hello-world/web/common"
+)
+
+// fakeServer fails to start while its framework has failures left
+type fakeServer struct {
+	framework string
+	failures  map[string]int
+	errs      chan error
+	stopped   bool
+}
+
+func (s *fakeServer) Start(ctx context.Context) error {
+	if s.failures[s.framework] > 0 {
+		s.failures[s.framework]--
+		return utils.NewAppError(utils.FrameworkError, "failed to listen", nil).WithCode("framework.listen").WithRetryable(true)
+	}
+	return nil
+}
+
+func (s *fakeServer) Stop(ctx context.Context) {
+	s.stopped = true
+}
+
+func (s *fakeServer) Errors() <-chan error {
+	return s.errs
+}
+
+func newFakeSupervisor(config SupervisorConfig, failures map[string]int) (*Supervisor, *[]*fakeServer) {
+	servers := &[]*fakeServer{}
+	supervisor := NewSupervisor(common.FrameworkOptions{Runtime: common.NewRuntimeState()}, config)
+	supervisor.newServer = func(ws *common.WebServer) common.WebServerInterface {
+		if newServer(ws) == nil {
+			return nil
+		}
+		server := &fakeServer{framework: ws.Framework, failures: failures, errs: make(chan error, 1)}
+		*servers = append(*servers, server)
+		return server
+	}
+	return supervisor, servers
+}
+
+func TestSupervisorConfigValidate(t *testing.T) {
+	assert.Nil(t, SupervisorConfig{}.Validate())
+	assert.Nil(t, SupervisorConfig{Retries: 3, Backoff: time.Millisecond, MaxBackoff: time.Second}.Validate())
+	assert.NotNil(t, SupervisorConfig{Retries: -1}.Validate())
+	assert.NotNil(t, SupervisorConfig{Retries: 3}.Validate())
+	assert.NotNil(t, SupervisorConfig{Retries: 3, Backoff: time.Second, MaxBackoff: time.Millisecond}.Validate())
+}
+
+func TestSupervisorRetriesWithBackoff(t *testing.T) {
+	ctx := context.Background()
+	supervisor, servers := newFakeSupervisor(SupervisorConfig{Retries: 2, Backoff: time.Millisecond, MaxBackoff: time.Millisecond}, map[string]int{"gin": 2})
+
+	failures := ServerStartFailuresCounterVec.WithLabelValues("gin")
+	before := testutil.ToFloat64(failures)
+
+	require.NoError(t, supervisor.Switch(ctx, "gin"))
+	assert.Len(t, *servers, 3)
+	assert.Equal(t, before+2, testutil.ToFloat64(failures))
+	assert.Equal(t, "gin", supervisor.options.Runtime.Framework())
+	assert.True(t, supervisor.options.Runtime.Ready())
+}
+
+func TestSupervisorRollsBackFailedSwitch(t *testing.T) {
+	ctx := context.Background()
+	supervisor, servers := newFakeSupervisor(SupervisorConfig{}, map[string]int{"fiber": 1})
+	require.NoError(t, supervisor.Switch(ctx, "chi"))
+
+	rollbacks := ServerRollbacksCounterVec.WithLabelValues("fiber", "chi")
+	before := testutil.ToFloat64(rollbacks)
+
+	err := supervisor.Switch(ctx, "fiber")
+	require.Error(t, err)
+	assert.True(t, utils.IsFrameworkError(err))
+	assert.Equal(t, before+1, testutil.ToFloat64(rollbacks))
+
+	assert.True(t, (*servers)[0].stopped, "Expected the previous server to be stopped before the switch")
+	assert.Equal(t, "chi", supervisor.options.Runtime.Framework())
+	assert.True(t, supervisor.options.Runtime.Ready(), "Expected the rolled back instance to stay ready")
+
+	err = supervisor.Switch(ctx, "django")
+	assert.True(t, errors.Is(err, utils.ErrFramework))
+	assert.Equal(t, "chi", supervisor.options.Runtime.Framework())
+}
+
+func TestSupervisorRunReturnsSwitchResult(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	common.FrameworkChannel = make(chan common.FrameworkSwitch)
+	supervisor, _ := newFakeSupervisor(SupervisorConfig{}, map[string]int{"fiber": 1})
+	go supervisor.Run(ctx)
+
+	require.NoError(t, common.SwitchFramework(ctx, "chi"))
+	assert.Equal(t, "chi", supervisor.options.Runtime.Framework())
+
+	err := common.SwitchFramework(ctx, "fiber")
+	require.Error(t, err)
+	assert.True(t, utils.IsFrameworkError(err))
+	assert.Equal(t, "chi", supervisor.options.Runtime.Framework())
+}
+
+func TestSupervisorRestartsFailedServer(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	failures := map[string]int{}
+	supervisor, servers := newFakeSupervisor(SupervisorConfig{}, failures)
+	require.NoError(t, supervisor.Switch(ctx, "echo"))
+
+	done := make(chan struct{})
+	go func() {
+		supervisor.Run(ctx)
+		close(done)
+	}()
+
+	// the restart fails, the instance stays not ready until a switch succeeds
+	failures["echo"] = 1
+	(*servers)[0].errs <- errors.New("accept failed")
+
+	require.Eventually(t, func() bool {
+		return !supervisor.options.Runtime.Ready()
+	}, time.Second, time.Millisecond)
+	cancel()
+	<-done
+
+	assert.True(t, (*servers)[0].stopped)
+	assert.Empty(t, supervisor.options.Runtime.Framework())
+
+	require.NoError(t, supervisor.Switch(context.Background(), "echo"))
+	assert.True(t, supervisor.options.Runtime.Ready())
+}
+
+func TestSupervisorListenFailure(t *testing.T) {
+	ctx := context.Background()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer listener.Close()
+
+	options := testOptions(t)
+	options.ListenAddr = listener.Addr().String()
+	supervisor := NewSupervisor(options, SupervisorConfig{Retries: 1, Backoff: time.Millisecond, MaxBackoff: time.Millisecond})
+
+	for _, framework := range testFrameworks {
+		err := supervisor.Switch(ctx, framework)
+		require.Error(t, err, framework)
+
+		appErr, ok := utils.AsAppError(err)
+		require.True(t, ok)
+		assert.Equal(t, utils.ErrorCode("framework.listen"), appErr.Code)
+		assert.False(t, supervisor.options.Runtime.Ready())
+	}
+}
@@ -0,0 +1,138 @@
+package web
+
+import (
+	"context"
+	"log/slog"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/wasilak/go-hello-world/web/common"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/noop"
+)
+
+const incomingTraceParent = "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+
+// freeListenAddr returns a local address that is free at the time of the call
+func freeListenAddr(t *testing.T) string {
+	t.Helper()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer l.Close()
+	return l.Addr().String()
+}
+
+// testFrameworks are the frameworks every per-framework test runs against
+var testFrameworks = []string{"gorilla", "chi", "gin", "echo", "fiber"}
+
+// testOptions returns the options every test server needs, tests set the fields they exercise
+func testOptions(t *testing.T) common.FrameworkOptions {
+	t.Helper()
+	return common.FrameworkOptions{
+		Tracer:         noop.NewTracerProvider().Tracer("test"),
+		LogLevelConfig: new(slog.LevelVar),
+		Runtime:        common.NewRuntimeState(),
+	}
+}
+
+// startTestServer starts the framework on a free port and waits until it answers
+func startTestServer(t *testing.T, ctx context.Context, framework string, options common.FrameworkOptions) string {
+	t.Helper()
+
+	options.ListenAddr = freeListenAddr(t)
+	server := newServer(&common.WebServer{Framework: framework, FrameworkOptions: options})
+	require.NotNil(t, server)
+
+	require.NoError(t, server.Start(ctx))
+	t.Cleanup(func() { server.Stop(ctx) })
+
+	baseURL := "http://" + options.ListenAddr
+	require.Eventually(t, func() bool {
+		resp, err := http.Get(baseURL + "/health")
+		if err != nil {
+			return false
+		}
+		resp.Body.Close()
+		return resp.StatusCode == http.StatusOK
+	}, 5*time.Second, 20*time.Millisecond)
+
+	return baseURL
+}
+
+func TestTracingSpanTreePerFramework(t *testing.T) {
+	for _, framework := range testFrameworks {
+		t.Run(framework, func(t *testing.T) {
+			ctx := context.Background()
+			exporter := tracetest.NewInMemoryExporter()
+			provider := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+
+			options := testOptions(t)
+			options.OtelEnabled = true
+			options.Tracer = provider.Tracer("test")
+			options.Tracing = common.TracingConfig{
+				TracerProvider:          provider,
+				Propagators:             propagation.TraceContext{},
+				FilteredPaths:           []string{"/health"},
+				CapturedRequestHeaders:  []string{"X-Test-Header"},
+				CapturedResponseHeaders: []string{"Content-Type"},
+			}
+			baseURL := startTestServer(t, ctx, framework, options)
+
+			t.Run("Filtered path", func(t *testing.T) {
+				assert.Empty(t, exporter.GetSpans(), "Expected no spans for the filtered health route")
+			})
+
+			req, err := http.NewRequest(http.MethodGet, baseURL+"/", nil)
+			require.NoError(t, err)
+			req.Header.Set("traceparent", incomingTraceParent)
+			req.Header.Set("X-Test-Header", "hello")
+
+			resp, err := http.DefaultClient.Do(req)
+			require.NoError(t, err)
+			resp.Body.Close()
+
+			require.Eventually(t, func() bool {
+				return len(exporter.GetSpans()) == 2
+			}, 2*time.Second, 10*time.Millisecond, "Expected a server span and its response child span")
+
+			var serverSpan, childSpan tracetest.SpanStub
+			for _, span := range exporter.GetSpans() {
+				if span.SpanKind == trace.SpanKindServer {
+					serverSpan = span
+				} else {
+					childSpan = span
+				}
+			}
+
+			t.Run("Server span", func(t *testing.T) {
+				assert.Equal(t, "GET /", serverSpan.Name)
+				assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", serverSpan.SpanContext.TraceID().String())
+				assert.Equal(t, "00f067aa0ba902b7", serverSpan.Parent.SpanID().String())
+				assert.True(t, serverSpan.Parent.IsRemote())
+			})
+
+			t.Run("Child span", func(t *testing.T) {
+				assert.Equal(t, "response", childSpan.Name)
+				assert.Equal(t, serverSpan.SpanContext.SpanID(), childSpan.Parent.SpanID())
+			})
+
+			t.Run("Captured headers", func(t *testing.T) {
+				attrs := attribute.NewSet(serverSpan.Attributes...)
+				requestHeader, ok := attrs.Value("http.request.header.x_test_header")
+				assert.True(t, ok)
+				assert.Equal(t, []string{"hello"}, requestHeader.AsStringSlice())
+
+				_, ok = attrs.Value("http.response.header.content_type")
+				assert.True(t, ok)
+			})
+		})
+	}
+}
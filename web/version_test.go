@@ -0,0 +1,45 @@
+package web
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/wasilak/go-hello-world/utils"
+	"github.com/wasilak/go-hello-world/web/common"
+)
+
+func TestVersionPerFramework(t *testing.T) {
+	for _, framework := range testFrameworks {
+		t.Run(framework, func(t *testing.T) {
+			options := testOptions(t)
+			options.Instance = &common.InstanceInfo{PodName: "hello-0"}
+			baseURL := startTestServer(t, context.Background(), framework, options)
+
+			resp, err := http.Get(baseURL + "/version")
+			require.NoError(t, err)
+			defer resp.Body.Close()
+			assert.Equal(t, http.StatusOK, resp.StatusCode)
+			assert.Equal(t, "application/json", resp.Header.Get("Content-Type"))
+
+			var version common.VersionResponse
+			require.NoError(t, json.NewDecoder(resp.Body).Decode(&version))
+			assert.Equal(t, utils.GetBuildInfo().Version, version.Version)
+			assert.Equal(t, runtime.Version(), version.GoVersion)
+			assert.True(t, version.Features["instance_info"])
+			assert.False(t, version.Features["admin"])
+
+			resp, err = http.Get(baseURL + "/")
+			require.NoError(t, err)
+			defer resp.Body.Close()
+
+			var response common.APIResponse
+			require.NoError(t, json.NewDecoder(resp.Body).Decode(&response))
+			assert.Equal(t, utils.GetBuildInfo().Version, response.Version)
+		})
+	}
+}